@@ -2,10 +2,11 @@ package accounts
 
 import (
 	"github.com/jmoiron/sqlx"
-	minio "github.com/minio/minio-go"
 	"github.com/unrolled/render"
 
+	"github.com/jteppinette/peragrin-api/geocoder"
 	"github.com/jteppinette/peragrin-api/mail"
+	"github.com/jteppinette/peragrin-api/store"
 )
 
 var (
@@ -15,13 +16,15 @@ var (
 // Config defines a single instance of the accounts package.
 type Config struct {
 	DBClient    *sqlx.DB
-	StoreClient *minio.Client
+	StoreClient store.Store
 	MailClient  *mail.Config
 	TokenSecret string
 	AppDomain   string
+
+	GeocoderClient geocoder.Geocoder
 }
 
 // Init generates an accounts.Config instance.
-func Init(dbClient *sqlx.DB, storeClient *minio.Client, mailClient *mail.Config, tokenSecret, appDomain string) *Config {
-	return &Config{dbClient, storeClient, mailClient, tokenSecret, appDomain}
+func Init(dbClient *sqlx.DB, storeClient store.Store, mailClient *mail.Config, tokenSecret, appDomain string, geocoderClient geocoder.Geocoder) *Config {
+	return &Config{dbClient, storeClient, mailClient, tokenSecret, appDomain, geocoderClient}
 }
@@ -8,5 +8,8 @@ var (
 
 	errAccountNotFound = errors.New("account not found")
 
+	errAuthenticationRequired = errors.New("authentication required")
+	errAccountForbidden       = errors.New("accounts can only be accessed by themselves")
+
 	errCreateOrganization = errors.New("create organization")
 )
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
 	"github.com/jteppinette/peragrin-api/models"
 	"github.com/jteppinette/peragrin-api/service"
@@ -99,7 +100,7 @@ func (c *Config) CreateOrganizationHandler(r *http.Request) *service.Response {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
 
-	if err := organization.CreateWithAccount(id, c.DBClient); err != nil {
+	if err := organization.CreateWithAccount(id, c.GeocoderClient, c.DBClient); err != nil {
 		return service.NewResponse(errors.Wrap(err, errCreateOrganization.Error()), http.StatusBadRequest, nil)
 	}
 	return service.NewResponse(nil, http.StatusCreated, organization)
@@ -240,3 +241,74 @@ func (c *Config) ListMembershipsByCommunityHandler(r *http.Request) *service.Res
 
 	return service.NewResponse(nil, http.StatusOK, result)
 }
+
+// CheckInHandler records a visit of the account at the organization whose check-in code it
+// scanned. The visit is of the account's active membership in one of the organization's
+// communities, or of the provided membership.
+func (c *Config) CheckInHandler(r *http.Request) *service.Response {
+	accountID, response := authorizeSelf(r)
+	if response != nil {
+		return response
+	}
+
+	body := struct {
+		Code         string `json:"code"`
+		MembershipID int    `json:"membershipID"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	visit, err := models.CheckInMember(accountID, body.Code, body.MembershipID, c.TokenSecret, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusCreated, visit)
+}
+
+// ListVisitsHandler returns the account's visit history with the newest visits first.
+func (c *Config) ListVisitsHandler(r *http.Request) *service.Response {
+	accountID, response := authorizeSelf(r)
+	if response != nil {
+		return response
+	}
+
+	visits, err := models.GetVisitsByAccount(accountID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, visits)
+}
+
+// ListMembershipChangesHandler returns the history of the account's membership tier changes
+// and transfers with the newest changes first.
+func (c *Config) ListMembershipChangesHandler(r *http.Request) *service.Response {
+	accountID, response := authorizeSelf(r)
+	if response != nil {
+		return response
+	}
+
+	changes, err := models.GetMembershipChangesByAccount(accountID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, changes)
+}
+
+// authorizeSelf returns the requested account id if it is the authenticated account or
+// the authenticated account is a super user.
+func authorizeSelf(r *http.Request) (int, *service.Response) {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return 0, service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	accountID, err := strconv.Atoi(mux.Vars(r)["accountID"])
+	if err != nil {
+		return 0, service.NewResponse(errors.Wrap(err, errAccountIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+	if account.ID != accountID && !account.IsSuper {
+		return 0, service.NewResponse(errAccountForbidden, http.StatusForbidden, nil)
+	}
+	return accountID, nil
+}
@@ -154,6 +154,18 @@ func (c *Config) RegisterHandler(r *http.Request) *service.Response {
 	return service.NewResponse(nil, http.StatusOK, nil)
 }
 
+// OptionalMiddleware authenticates the incoming request like RequiredMiddleware when
+// it has an Authorization header. Requests without one are handled anonymously.
+func (c *Config) OptionalMiddleware(h service.Handler) service.Handler {
+	required := c.RequiredMiddleware(h)
+	return func(r *http.Request) *service.Response {
+		if r.Header.Get("Authorization") == "" {
+			return h(r)
+		}
+		return required(r)
+	}
+}
+
 // RequiredMiddleware attempts to authenticate the incoming request using
 // Basic and JWT authentication strategies. If successful, an "account" key will be
 // added to the request context. Otherwise, an HTTP Unauthorized will be
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+
+	"github.com/jteppinette/peragrin-api/geocoder"
+)
+
+// newGeocoder returns the configured geocoder. Online providers are rate limited and
+// their results are cached in the database.
+func newGeocoder(dbClient *sqlx.DB) (geocoder.Geocoder, error) {
+	var g geocoder.Geocoder
+	switch backend := viper.GetString("GEOCODER_BACKEND"); backend {
+	case "locationiq":
+		g = geocoder.NewLocationIQ(viper.GetString("LOCATIONIQ_API_KEY"), viper.GetDuration("GEOCODER_TIMEOUT"))
+	case "nominatim":
+		g = geocoder.NewNominatim(viper.GetString("GEOCODER_URL"), "", "nominatim", viper.GetDuration("GEOCODER_TIMEOUT"))
+	case "gazetteer":
+		return geocoder.NewGazetteer(viper.GetString("GEOCODER_GAZETTEER"))
+	default:
+		return nil, fmt.Errorf("unsupported geocoder backend: %s", backend)
+	}
+
+	rate := viper.GetInt("GEOCODER_RATE")
+	if rate <= 0 {
+		return nil, fmt.Errorf("geocoder rate must be positive: %d", rate)
+	}
+	return geocoder.NewCache(geocoder.NewLimiter(g, rate), dbClient, viper.GetDuration("GEOCODER_CACHE_TTL")), nil
+}
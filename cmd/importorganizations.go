@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/jteppinette/peragrin-api/db"
+	"github.com/jteppinette/peragrin-api/models"
+)
+
+func importOrganizations(communityID int, path string, dryRun bool) {
+	dbClient, err := db.Client(viper.GetString("DB_HOST"), viper.GetString("DB_USER"), viper.GetString("DB_PASSWORD"), viper.GetString("DB_NAME"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	geocoderClient, err := newGeocoder(dbClient)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	community, err := models.GetCommunityByID(communityID, dbClient)
+	if err != nil {
+		log.WithFields(log.Fields{"communityID": communityID, "error": err.Error()}).Fatal(errors.New("community lookup"))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var result *models.OrganizationImport
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		result, err = models.ParseOrganizationsCSV(f)
+	case ".geojson", ".json":
+		result, err = models.ParseOrganizationsGeoJSON(f)
+	default:
+		log.WithFields(log.Fields{"path": path}).Fatal(errors.New("import format not supported"))
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"path": path, "error": err.Error()}).Fatal(errors.New("parse import"))
+	}
+
+	log.WithFields(log.Fields{"path": path, "rows": len(result.Rows)}).Info("parsed import")
+
+	result.DryRun = dryRun
+	result.Validate(community)
+	result.Geocode(geocoderClient, community)
+
+	for _, row := range result.Rows {
+		fields := log.Fields{"row": row.Row, "name": row.Organization.Name, "lon": row.Organization.Lon, "lat": row.Organization.Lat, "geocoded": row.Geocoded}
+		if len(row.Errors) > 0 {
+			fields["errors"] = strings.Join(row.Errors, "; ")
+			log.WithFields(fields).Error("invalid row")
+		} else {
+			log.WithFields(fields).Info("valid row")
+		}
+	}
+
+	if !result.Valid {
+		log.Fatal(errors.New("import invalid - no organizations were created"))
+	}
+
+	if err := result.CreateWithCommunity(communityID, dbClient); err != nil {
+		log.WithFields(log.Fields{"communityID": communityID, "error": err.Error()}).Fatal(errors.New("create organizations"))
+	}
+
+	if dryRun {
+		log.WithFields(log.Fields{"rows": len(result.Rows)}).Info("dry run completed - no organizations were created")
+		return
+	}
+	log.WithFields(log.Fields{"communityID": communityID, "created": result.Created}).Info("completed successfully")
+}
+
+// ImportOrganizations creates the organizations in the provided csv or GeoJSON file and
+// joins them to the provided community in a single transaction.
+var ImportOrganizations *cobra.Command
+
+func init() {
+	var dryRun bool
+
+	ImportOrganizations = &cobra.Command{
+		Use:  "importorganizations <community-id> <path>",
+		Args: cobra.ExactArgs(2),
+		Run: func(_ *cobra.Command, args []string) {
+			communityID, err := strconv.Atoi(args[0])
+			if err != nil {
+				log.Fatal(errors.Wrap(err, "community id required"))
+			}
+			importOrganizations(communityID, args[1], dryRun)
+		},
+	}
+
+	ImportOrganizations.Flags().BoolVarP(&dryRun, "dry-run", "", false, "validate the import without creating any organizations")
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/jteppinette/peragrin-api/payment"
+)
+
+// newPaymentGateway returns the configured payment gateway.
+func newPaymentGateway() (payment.Gateway, error) {
+	switch backend := viper.GetString("PAYMENT_BACKEND"); backend {
+	case "stripe":
+		return payment.NewStripe(viper.GetString("STRIPE_API_KEY"), viper.GetString("PAYMENT_WEBHOOK_SECRET"), viper.GetDuration("PAYMENT_TIMEOUT")), nil
+	case "fake":
+		return payment.NewFake(viper.GetString("PAYMENT_WEBHOOK_SECRET")), nil
+	default:
+		return nil, fmt.Errorf("unsupported payment backend: %s", backend)
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/jteppinette/peragrin-api/db"
+	"github.com/jteppinette/peragrin-api/mail"
+	"github.com/jteppinette/peragrin-api/reminders"
+)
+
+func sendReminders() {
+	log.SetFormatter(&log.JSONFormatter{})
+
+	dbClient, err := db.Client(viper.GetString("DB_HOST"), viper.GetString("DB_USER"), viper.GetString("DB_PASSWORD"), viper.GetString("DB_NAME"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mailClient := mail.New(viper.GetString("MAIL_FROM"), viper.GetString("MAIL_HOST"), viper.GetInt("MAIL_PORT"), viper.GetString("MAIL_PASSWORD"), viper.GetString("MAIL_USER"))
+
+	sent, err := reminders.Init(dbClient, mailClient, viper.GetString("APP_DOMAIN")).Send(time.Now())
+	if err != nil {
+		log.WithFields(log.Fields{"sent": sent}).Fatal(err)
+	}
+	log.WithFields(log.Fields{"sent": sent}).Info("membership reminders sent")
+}
+
+// SendReminders is a cobra command that emails every membership reminder that is due. It
+// is an alternative to the reminders that the server sends every REMINDER_INTERVAL, e.g.
+// for running from cron.
+var SendReminders *cobra.Command
+
+func init() {
+	SendReminders = &cobra.Command{
+		Use: "sendreminders",
+		Run: func(_ *cobra.Command, args []string) {
+			sendReminders()
+		},
+	}
+}
@@ -7,7 +7,6 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
-	minio "github.com/minio/minio-go"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -19,8 +18,11 @@ import (
 	"github.com/jteppinette/peragrin-api/mail"
 	"github.com/jteppinette/peragrin-api/memberships"
 	"github.com/jteppinette/peragrin-api/organizations"
+	"github.com/jteppinette/peragrin-api/payments"
 	"github.com/jteppinette/peragrin-api/promotions"
+	"github.com/jteppinette/peragrin-api/reminders"
 	"github.com/jteppinette/peragrin-api/service"
+	"github.com/jteppinette/peragrin-api/store"
 )
 
 func serve() {
@@ -31,7 +33,35 @@ func serve() {
 		log.Fatal(err)
 	}
 
-	storeClient, err := minio.New(viper.GetString("STORE_ENDPOINT"), viper.GetString("STORE_ACCESS_KEY"), viper.GetString("STORE_SECRET_KEY"), viper.GetBool("STORE_SECURE"))
+	var storeClient store.Store
+	var localStoreClient *store.Local
+	switch backend := viper.GetString("STORE_BACKEND"); backend {
+	case "minio":
+		storeClient, err = store.NewMinio(viper.GetString("STORE_ENDPOINT"), viper.GetString("STORE_ACCESS_KEY"), viper.GetString("STORE_SECRET_KEY"), viper.GetBool("STORE_SECURE"), viper.GetString("STORE_BUCKET"))
+	case "local":
+		localStoreClient, err = store.NewLocal(viper.GetString("STORE_DIRECTORY"), viper.GetString("STORE_URL"), viper.GetString("TOKEN_SECRET"))
+		storeClient = localStoreClient
+	default:
+		err = fmt.Errorf("unsupported store backend: %s", backend)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	geocoderClient, err := newGeocoder(dbClient)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	paymentGateway, err := newPaymentGateway()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if viper.GetString("PAYMENT_WEBHOOK_SECRET") == "" {
+		log.Warn("PAYMENT_WEBHOOK_SECRET is not set, payment webhooks are rejected")
+	}
+
+	appleWallet, googleWallet, err := newWallets()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -39,13 +69,18 @@ func serve() {
 	mailClient := mail.New(viper.GetString("MAIL_FROM"), viper.GetString("MAIL_HOST"), viper.GetInt("MAIL_PORT"), viper.GetString("MAIL_PASSWORD"), viper.GetString("MAIL_USER"))
 
 	auth := auth.Init(dbClient, mailClient, viper.GetString("TOKEN_SECRET"), viper.GetString("APP_DOMAIN"))
-	accounts := accounts.Init(dbClient, storeClient, mailClient, viper.GetString("TOKEN_SECRET"), viper.GetString("APP_DOMAIN"))
-	organizations := organizations.Init(dbClient, storeClient, mailClient, viper.GetString("TOKEN_SECRET"), viper.GetString("APP_DOMAIN"))
-	geo := geo.Init(viper.GetString("LOCATIONIQ_API_KEY"))
-	communities := communities.Init(dbClient, storeClient, mailClient, viper.GetString("TOKEN_SECRET"), viper.GetString("APP_DOMAIN"))
-	memberships := memberships.Init(dbClient, mailClient, viper.GetString("TOKEN_SECRET"), viper.GetString("APP_DOMAIN"))
+	accounts := accounts.Init(dbClient, storeClient, mailClient, viper.GetString("TOKEN_SECRET"), viper.GetString("APP_DOMAIN"), geocoderClient)
+	organizations := organizations.Init(dbClient, storeClient, mailClient, viper.GetString("TOKEN_SECRET"), viper.GetString("APP_DOMAIN"), geocoderClient)
+	geo := geo.Init(geocoderClient)
+	communities := communities.Init(dbClient, storeClient, mailClient, viper.GetString("TOKEN_SECRET"), viper.GetString("APP_DOMAIN"), geocoderClient, viper.GetFloat64("WALKING_SPEED"))
+	memberships := memberships.Init(dbClient, mailClient, viper.GetString("TOKEN_SECRET"), viper.GetString("APP_DOMAIN"), paymentGateway, viper.GetString("PAYMENT_CURRENCY"), appleWallet, googleWallet)
+	payments := payments.Init(dbClient, mailClient, viper.GetString("TOKEN_SECRET"), viper.GetString("APP_DOMAIN"), paymentGateway)
 	promotions := promotions.Init(dbClient)
 
+	if interval := viper.GetDuration("REMINDER_INTERVAL"); interval > 0 {
+		go reminders.Init(dbClient, mailClient, viper.GetString("APP_DOMAIN")).Run(interval, nil)
+	}
+
 	r := mux.NewRouter()
 	r.Handle("/auth/login", service.Handler(auth.LoginHandler)).Methods(http.MethodPost)
 	r.Handle("/auth/register", service.Handler(auth.RegisterHandler)).Methods(http.MethodPost)
@@ -54,6 +89,8 @@ func serve() {
 	r.Handle("/auth/activate", auth.RequiredMiddleware(auth.ActivateHandler)).Methods(http.MethodPost)
 
 	r.Handle("/geo", auth.RequiredMiddleware(geo.LookupHandler)).Methods(http.MethodPost)
+	r.Handle("/geo/reverse", auth.RequiredMiddleware(geo.ReverseHandler)).Methods(http.MethodPost)
+	r.Handle("/geo/autocomplete", auth.RequiredMiddleware(geo.AutocompleteHandler)).Methods(http.MethodGet)
 
 	r.Handle("/accounts", auth.RequiredMiddleware(accounts.ListHandler)).Methods(http.MethodGet)
 	r.Handle("/accounts/{accountID:[0-9]+}", auth.RequiredMiddleware(accounts.UpdateAccountHandler)).Methods(http.MethodPut)
@@ -64,28 +101,61 @@ func serve() {
 	r.Handle("/accounts/{accountID:[0-9]+}/promotions/{promotionID:[0-9]+}", auth.RequiredMiddleware(accounts.ListPromotionRedemptionsHandler)).Methods(http.MethodGet)
 	r.Handle("/accounts/{accountID:[0-9]+}/promotions", auth.RequiredMiddleware(accounts.ListRedemptionsHandler)).Methods(http.MethodGet)
 	r.Handle("/accounts/{accountID:[0-9]+}/memberships", auth.RequiredMiddleware(accounts.ListMembershipsByCommunityHandler)).Methods(http.MethodGet)
+	r.Handle("/accounts/{accountID:[0-9]+}/check-ins", auth.RequiredMiddleware(accounts.CheckInHandler)).Methods(http.MethodPost)
+	r.Handle("/accounts/{accountID:[0-9]+}/visits", auth.RequiredMiddleware(accounts.ListVisitsHandler)).Methods(http.MethodGet)
+	r.Handle("/accounts/{accountID:[0-9]+}/membership-changes", auth.RequiredMiddleware(accounts.ListMembershipChangesHandler)).Methods(http.MethodGet)
 
 	r.Handle("/communities", service.Handler(communities.ListHandler)).Methods(http.MethodGet)
 	r.Handle("/communities", auth.RequiredMiddleware(communities.CreateHandler)).Methods(http.MethodPost)
+	r.Handle("/communities/locate", service.Handler(communities.LocateHandler)).Methods(http.MethodGet)
+	r.Handle("/communities/by-slug/{slug}", service.Handler(communities.GetBySlugHandler)).Methods(http.MethodGet)
 	r.Handle("/communities/{communityID:[0-9]+}", service.Handler(communities.GetHandler)).Methods(http.MethodGet)
 	r.Handle("/communities/{communityID:[0-9]+}", auth.RequiredMiddleware(communities.DeleteHandler)).Methods(http.MethodDelete)
 	r.Handle("/communities/{communityID:[0-9]+}", auth.RequiredMiddleware(communities.UpdateHandler)).Methods(http.MethodPut)
+	r.Handle("/communities/{communityID:[0-9]+}/logo", auth.RequiredMiddleware(communities.UploadLogoHandler)).Methods(http.MethodPost)
 	r.Handle("/communities/{communityID:[0-9]+}/organizations", service.Handler(communities.ListOrganizationsHandler)).Methods(http.MethodGet)
+	r.Handle("/communities/{communityID:[0-9]+}/organizations/clusters", service.Handler(communities.ListOrganizationClustersHandler)).Methods(http.MethodGet)
+	r.Handle("/communities/{communityID:[0-9]+}/organizations/reviews", auth.RequiredMiddleware(communities.ListOrganizationReviewsHandler)).Methods(http.MethodGet)
+	r.Handle("/communities/{communityID:[0-9]+}/organizations/reviews/{organizationID:[0-9]+}/resolve", auth.RequiredMiddleware(communities.ResolveOrganizationReviewHandler)).Methods(http.MethodPost)
+	r.Handle("/communities/{communityID:[0-9]+}/organizations", auth.RequiredMiddleware(communities.ImportOrganizationsHandler)).Methods(http.MethodPost).Headers("X-Action", "import")
 	r.Handle("/communities/{communityID:[0-9]+}/organizations", auth.RequiredMiddleware(communities.CreateOrganizationHandler)).Methods(http.MethodPost)
+	r.Handle("/communities/{communityID:[0-9]+}/join-requests", auth.RequiredMiddleware(communities.ListJoinRequestsHandler)).Methods(http.MethodGet)
+	r.Handle("/communities/{communityID:[0-9]+}/join-requests/{organizationID:[0-9]+}/approve", auth.RequiredMiddleware(communities.ApproveJoinRequestHandler)).Methods(http.MethodPost)
+	r.Handle("/communities/{communityID:[0-9]+}/join-requests/{organizationID:[0-9]+}/reject", auth.RequiredMiddleware(communities.RejectJoinRequestHandler)).Methods(http.MethodPost)
 	r.Handle("/communities/{communityID:[0-9]+}/posts", auth.RequiredMiddleware(communities.ListPostsHandler))
-	r.Handle("/communities/{communityID:[0-9]+}/geo-json-overlays", service.Handler(communities.ListGeoJSONOverlaysHandler))
+	r.Handle("/communities/{communityID:[0-9]+}/geo-json-overlays", service.Handler(communities.ListGeoJSONOverlaysHandler)).Methods(http.MethodGet)
+	r.Handle("/communities/{communityID:[0-9]+}/geo-json-overlays", auth.RequiredMiddleware(communities.CreateGeoJSONOverlayHandler)).Methods(http.MethodPost)
+	r.Handle("/communities/{communityID:[0-9]+}/geo-json-overlays/order", auth.RequiredMiddleware(communities.ReorderGeoJSONOverlaysHandler)).Methods(http.MethodPut)
+	r.Handle("/communities/{communityID:[0-9]+}/geo-json-overlays/{overlayID:[0-9]+}", auth.RequiredMiddleware(communities.UpdateGeoJSONOverlayHandler)).Methods(http.MethodPut)
+	r.Handle("/communities/{communityID:[0-9]+}/geo-json-overlays/{overlayID:[0-9]+}", auth.RequiredMiddleware(communities.DeleteGeoJSONOverlayHandler)).Methods(http.MethodDelete)
+	r.HandleFunc("/communities/{communityID:[0-9]+}/tiles/{z:[0-9]+}/{x:[0-9]+}/{y:[0-9]+}.mvt", communities.TileHandler).Methods(http.MethodGet)
 	r.Handle("/communities/{communityID:[0-9]+}/memberships", auth.RequiredMiddleware(communities.ListMembershipsHandler)).Methods(http.MethodGet)
 	r.Handle("/communities/{communityID:[0-9]+}/memberships", auth.RequiredMiddleware(communities.CreateMembershipHandler)).Methods(http.MethodPost)
+	r.Handle("/communities/{communityID:[0-9]+}/reminder-templates", auth.RequiredMiddleware(communities.ListReminderTemplatesHandler)).Methods(http.MethodGet)
+	r.Handle("/communities/{communityID:[0-9]+}/reminder-templates/{kind}", auth.RequiredMiddleware(communities.UpdateReminderTemplateHandler)).Methods(http.MethodPut)
+	r.Handle("/communities/{communityID:[0-9]+}/reminder-templates/{kind}", auth.RequiredMiddleware(communities.DeleteReminderTemplateHandler)).Methods(http.MethodDelete)
+	r.Handle("/communities/{communityID:[0-9]+}/payments", auth.RequiredMiddleware(communities.ListPaymentsHandler)).Methods(http.MethodGet)
+	r.Handle("/communities/{communityID:[0-9]+}/traffic", auth.RequiredMiddleware(communities.TrafficHandler)).Methods(http.MethodGet)
 	r.Handle("/communities/{communityID:[0-9]+}/accounts", auth.RequiredMiddleware(communities.BulkAddAccountsHandler)).Methods(http.MethodPost).Headers("X-Action", "bulk")
 
 	r.Handle("/memberships/{membershipID:[0-9]+}", auth.RequiredMiddleware(memberships.GetHandler)).Methods(http.MethodGet)
 	r.Handle("/memberships/{membershipID:[0-9]+}", auth.RequiredMiddleware(memberships.UpdateHandler)).Methods(http.MethodPut)
 	r.Handle("/memberships/{membershipID:[0-9]+}", auth.RequiredMiddleware(memberships.DeleteHandler)).Methods(http.MethodDelete)
+	r.Handle("/memberships/{membershipID:[0-9]+}/checkout", service.Handler(memberships.CheckoutHandler)).Methods(http.MethodPost)
 	r.Handle("/memberships/{membershipID:[0-9]+}/accounts", auth.RequiredMiddleware(memberships.ListAccountsHandler)).Methods(http.MethodGet)
 	r.Handle("/memberships/{membershipID:[0-9]+}/accounts", auth.RequiredMiddleware(memberships.BulkAddAccountsHandler)).Methods(http.MethodPost).Headers("X-Action", "bulk")
 	r.Handle("/memberships/{membershipID:[0-9]+}/accounts", auth.RequiredMiddleware(memberships.AddAccountHandler)).Methods(http.MethodPost)
 	r.Handle("/memberships/{membershipID:[0-9]+}/accounts/{accountID:[0-9]+}", auth.RequiredMiddleware(memberships.RemoveAccountHandler)).Methods(http.MethodDelete)
 	r.Handle("/memberships/{membershipID:[0-9]+}/accounts/{accountID:[0-9]+}", auth.RequiredMiddleware(memberships.UpdateAccountHandler)).Methods(http.MethodPut)
+	r.Handle("/memberships/{membershipID:[0-9]+}/accounts/{accountID:[0-9]+}/renew", auth.RequiredMiddleware(memberships.RenewAccountHandler)).Methods(http.MethodPost)
+	r.Handle("/memberships/{membershipID:[0-9]+}/accounts/{accountID:[0-9]+}/change", auth.RequiredMiddleware(memberships.ChangeAccountHandler)).Methods(http.MethodPost)
+	r.Handle("/memberships/{membershipID:[0-9]+}/accounts/{accountID:[0-9]+}/transfer", auth.RequiredMiddleware(memberships.TransferAccountHandler)).Methods(http.MethodPost)
+	r.Handle("/memberships/{membershipID:[0-9]+}/accounts/{accountID:[0-9]+}/seats", auth.RequiredMiddleware(memberships.ListSeatsHandler)).Methods(http.MethodGet)
+	r.Handle("/memberships/{membershipID:[0-9]+}/accounts/{accountID:[0-9]+}/seats", auth.RequiredMiddleware(memberships.AddSeatHandler)).Methods(http.MethodPost)
+	r.Handle("/memberships/{membershipID:[0-9]+}/accounts/{accountID:[0-9]+}/seats/{dependentID:[0-9]+}", auth.RequiredMiddleware(memberships.RemoveSeatHandler)).Methods(http.MethodDelete)
+	r.Handle("/memberships/{membershipID:[0-9]+}/accounts/{accountID:[0-9]+}/seats/{dependentID:[0-9]+}/accept", auth.RequiredMiddleware(memberships.AcceptSeatHandler)).Methods(http.MethodPost)
+	r.Handle("/memberships/{membershipID:[0-9]+}/accounts/{accountID:[0-9]+}/card/{format}", auth.RequiredMiddleware(memberships.CardHandler)).Methods(http.MethodGet)
+	r.Handle("/memberships/verify", auth.RequiredMiddleware(memberships.VerifyHandler)).Methods(http.MethodPost)
 
 	r.Handle("/organizations/{organizationID:[0-9]+}", auth.RequiredMiddleware(organizations.UpdateHandler)).Methods(http.MethodPut)
 	r.Handle("/organizations/{organizationID:[0-9]+}", auth.RequiredMiddleware(organizations.GetHandler)).Methods(http.MethodGet)
@@ -95,24 +165,46 @@ func serve() {
 	r.Handle("/organizations/{organizationID:[0-9]+}/communities/{communityID:[0-9]+}", auth.RequiredMiddleware(organizations.RemoveCommunityHandler)).Methods(http.MethodDelete)
 	r.Handle("/organizations/{organizationID:[0-9]+}/posts", auth.RequiredMiddleware(organizations.CreatePostHandler))
 	r.Handle("/organizations/{organizationID:[0-9]+}/hours", service.Handler(organizations.ListHoursHandler)).Methods(http.MethodGet)
-	r.Handle("/organizations/{organizationID:[0-9]+}/promotions", service.Handler(organizations.ListPromotionsHandler)).Methods(http.MethodGet)
+	r.Handle("/organizations/{organizationID:[0-9]+}/promotions", auth.OptionalMiddleware(organizations.ListPromotionsHandler)).Methods(http.MethodGet)
 	r.Handle("/organizations/{organizationID:[0-9]+}/promotions", service.Handler(organizations.CreatePromotionHandler)).Methods(http.MethodPost)
 	r.Handle("/organizations/{organizationID:[0-9]+}/accounts", auth.RequiredMiddleware(organizations.ListAccountsHandler)).Methods(http.MethodGet)
 	r.Handle("/organizations/{organizationID:[0-9]+}/accounts", auth.RequiredMiddleware(organizations.AddAccountHandler)).Methods(http.MethodPost)
 	r.Handle("/organizations/{organizationID:[0-9]+}/accounts/{accountID:[0-9]+}", auth.RequiredMiddleware(organizations.RemoveAccountHandler)).Methods(http.MethodDelete)
+	r.Handle("/organizations/{organizationID:[0-9]+}/claims", auth.RequiredMiddleware(organizations.ListClaimsHandler)).Methods(http.MethodGet)
+	r.Handle("/organizations/{organizationID:[0-9]+}/claims", auth.RequiredMiddleware(organizations.CreateClaimHandler)).Methods(http.MethodPost)
+	r.Handle("/organizations/{organizationID:[0-9]+}/claims/{claimID:[0-9]+}/verify", auth.RequiredMiddleware(organizations.VerifyClaimHandler)).Methods(http.MethodPost)
+	r.Handle("/organizations/{organizationID:[0-9]+}/claims/{claimID:[0-9]+}/approve", auth.RequiredMiddleware(organizations.ApproveClaimHandler)).Methods(http.MethodPost)
+	r.Handle("/organizations/{organizationID:[0-9]+}/claims/{claimID:[0-9]+}/reject", auth.RequiredMiddleware(organizations.RejectClaimHandler)).Methods(http.MethodPost)
 	r.Handle("/organizations/{organizationID:[0-9]+}/logo", auth.RequiredMiddleware(organizations.UploadLogoHandler)).Methods(http.MethodPost)
+	r.Handle("/organizations/{organizationID:[0-9]+}/check-ins", auth.RequiredMiddleware(organizations.CheckInHandler)).Methods(http.MethodPost)
+	r.Handle("/organizations/{organizationID:[0-9]+}/check-in-code/{format}", auth.RequiredMiddleware(organizations.CheckInCodeHandler)).Methods(http.MethodGet)
+	r.Handle("/organizations/{organizationID:[0-9]+}/traffic", auth.RequiredMiddleware(organizations.TrafficHandler)).Methods(http.MethodGet)
+
+	if localStoreClient != nil {
+		r.HandleFunc("/store/{key:.+}", localStoreClient.DownloadHandler).Methods(http.MethodGet)
+	}
+
+	r.Handle("/payments/webhook", service.Handler(payments.WebhookHandler)).Methods(http.MethodPost)
+	r.Handle("/payments/{paymentID:[0-9]+}/refund", auth.RequiredMiddleware(payments.RefundHandler)).Methods(http.MethodPost)
 
 	r.Handle("/promotions/{promotionID:[0-9]+}/redeem", auth.RequiredMiddleware(promotions.RedeemHandler)).Methods(http.MethodPost)
 	r.Handle("/promotions/{promotionID:[0-9]+}", auth.RequiredMiddleware(promotions.UpdateHandler)).Methods(http.MethodPut)
 	r.Handle("/promotions/{promotionID:[0-9]+}", auth.RequiredMiddleware(promotions.DeleteHandler)).Methods(http.MethodDelete)
 
+	// Streamed responses are routed around the timeout handler, because it buffers the entire response.
+	// Every other handler is limited by the timeout handler, so the server's write timeout only
+	// needs to leave room for the longest export.
+	root := mux.NewRouter()
+	root.HandleFunc("/communities/{communityID:[0-9]+}/organizations/export.{format:csv|geojson|kml}", communities.ExportOrganizationsHandler).Methods(http.MethodGet)
+	root.PathPrefix("/").Handler(http.TimeoutHandler(r, 15*time.Second, ""))
+
 	log.Infof("initializing server: %s", viper.GetString("PORT"))
 
 	server := &http.Server{
 		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		WriteTimeout: viper.GetDuration("EXPORT_TIMEOUT"),
 		Addr:         fmt.Sprintf(":%s", viper.GetString("PORT")),
-		Handler:      http.TimeoutHandler(r, 15*time.Second, ""),
+		Handler:      root,
 	}
 	server.SetKeepAlivesEnabled(false)
 	server.ListenAndServe()
@@ -131,4 +223,7 @@ func init() {
 
 	Serve.PersistentFlags().StringP("port", "", "8000", "port that the api will listen on")
 	viper.BindPFlag("PORT", Serve.PersistentFlags().Lookup("port"))
+
+	Serve.PersistentFlags().DurationP("export-timeout", "", 10*time.Minute, "how long organization exports can take to be written")
+	viper.BindPFlag("EXPORT_TIMEOUT", Serve.PersistentFlags().Lookup("export-timeout"))
 }
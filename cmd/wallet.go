@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/spf13/viper"
+
+	"github.com/jteppinette/peragrin-api/wallet"
+)
+
+// newWallets returns the configured wallets. The apple wallet is nil when its certificate
+// is not configured, and the google wallet does not have a key when its credentials are not.
+func newWallets() (*wallet.Apple, *wallet.Google, error) {
+	var apple *wallet.Apple
+	if file := viper.GetString("APPLE_PASS_CERTIFICATE"); file != "" {
+		var err error
+		if apple, err = wallet.LoadApple(viper.GetString("APPLE_PASS_TYPE_ID"), viper.GetString("APPLE_TEAM_ID"), file, viper.GetString("APPLE_WWDR_CERTIFICATE")); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	google := &wallet.Google{IssuerID: viper.GetString("GOOGLE_WALLET_ISSUER_ID")}
+	if file := viper.GetString("GOOGLE_WALLET_CREDENTIALS"); file != "" {
+		var err error
+		if google, err = wallet.LoadGoogle(google.IssuerID, file); err != nil {
+			return nil, nil, err
+		}
+	}
+	return apple, google, nil
+}
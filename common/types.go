@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/jmoiron/sqlx/types"
 	"github.com/lib/pq"
 )
 
@@ -16,6 +17,11 @@ type JSONNullTime struct {
 	pq.NullTime
 }
 
+// NewJSONNullTime returns a valid JSONNullTime for the provided time.
+func NewJSONNullTime(t time.Time) JSONNullTime {
+	return JSONNullTime{pq.NullTime{Time: t, Valid: true}}
+}
+
 // MarshalJSON satisifies the json.Marshaler interface. This
 // allows the wrapped time field to be directly returned during
 // encoding.
@@ -47,3 +53,37 @@ func (v *JSONNullTime) UnmarshalJSON(data []byte) error {
 var _ sql.Scanner = &JSONNullTime{}
 var _ json.Marshaler = JSONNullTime{}
 var _ json.Unmarshaler = &JSONNullTime{}
+
+// JSONNullText represents a json document that may be null.
+// JSONNullText implements the sql.Scanner interface so it can be
+// used as a scan destination, similar to sql.NullString. It also
+// implements the json.Marshaler and json.Unmarshaler json interfaces.
+type JSONNullText struct {
+	types.NullJSONText
+}
+
+// MarshalJSON satisifies the json.Marshaler interface. This
+// allows the wrapped document to be directly returned during
+// encoding.
+func (v JSONNullText) MarshalJSON() ([]byte, error) {
+	if v.Valid {
+		return v.JSONText.MarshalJSON()
+	}
+	return json.Marshal(nil)
+}
+
+// UnmarshalJSON satisifies the json.Unmarshaler interface. This
+// allows the wrapped document to be directly set during decoding.
+func (v *JSONNullText) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		v.Valid = false
+		v.JSONText = nil
+		return nil
+	}
+	v.Valid = true
+	return v.JSONText.UnmarshalJSON(data)
+}
+
+var _ sql.Scanner = &JSONNullText{}
+var _ json.Marshaler = JSONNullText{}
+var _ json.Unmarshaler = &JSONNullText{}
@@ -0,0 +1,81 @@
+package communities
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jteppinette/peragrin-api/geometry"
+	"github.com/jteppinette/peragrin-api/models"
+)
+
+const (
+	// clusterSize is the width, in pixels, of the grid cells that organizations are clustered by.
+	clusterSize = 64
+	// maxClusterZoom is the zoom at which organizations are no longer clustered.
+	maxClusterZoom = 17
+	// uncategorized is used in category breakdowns for organizations without a category.
+	uncategorized = "Uncategorized"
+)
+
+// cluster is a group of nearby organizations. Clusters of a single organization include
+// the organization itself so that it can be rendered as an individual marker.
+type cluster struct {
+	Lon          float64              `json:"lon"`
+	Lat          float64              `json:"lat"`
+	Count        int                  `json:"count"`
+	Categories   map[string]int       `json:"categories"`
+	Bounds       geometry.Bounds      `json:"bounds"`
+	Organization *models.Organization `json:"organization,omitempty"`
+}
+
+// clusterOrganizations groups organizations that share a grid cell at the provided zoom.
+// The position of a cluster is the average position of its organizations. At and beyond
+// maxClusterZoom, every organization is returned as its own cluster.
+func clusterOrganizations(organizations models.Organizations, zoom int) []cluster {
+	type cell struct{ x, y int }
+
+	cells := map[cell][]int{}
+	keys := []cell{}
+	for i, o := range organizations {
+		k := cell{-1, i}
+		if zoom < maxClusterZoom {
+			x, y := geometry.Point{o.Lon, o.Lat}.Mercator(zoom)
+			k = cell{int(math.Floor(x / clusterSize)), int(math.Floor(y / clusterSize))}
+		}
+		if _, ok := cells[k]; !ok {
+			keys = append(keys, k)
+		}
+		cells[k] = append(cells[k], i)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].y != keys[j].y {
+			return keys[i].y < keys[j].y
+		}
+		return keys[i].x < keys[j].x
+	})
+
+	clusters := make([]cluster, 0, len(keys))
+	for _, k := range keys {
+		c := cluster{Categories: map[string]int{}, Bounds: geometry.EmptyBounds()}
+		for _, i := range cells[k] {
+			o := organizations[i]
+			c.Lon += o.Lon
+			c.Lat += o.Lat
+			c.Count++
+			category := o.Category
+			if category == "" {
+				category = uncategorized
+			}
+			c.Categories[category]++
+			c.Bounds = c.Bounds.Extend(geometry.Point{o.Lon, o.Lat})
+		}
+		c.Lon /= float64(c.Count)
+		c.Lat /= float64(c.Count)
+		if c.Count == 1 {
+			o := organizations[cells[k][0]]
+			c.Organization = &o
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters
+}
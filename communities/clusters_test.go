@@ -0,0 +1,31 @@
+package communities
+
+import (
+	"testing"
+
+	"github.com/jteppinette/peragrin-api/models"
+)
+
+func TestClusterOrganizations(t *testing.T) {
+	organizations := models.Organizations{
+		{ID: 1, Lon: -77.4360, Lat: 37.5407, Category: models.Restaurant},
+		{ID: 2, Lon: -77.4361, Lat: 37.5408, Category: models.Restaurant},
+		{ID: 3, Lon: -77.4362, Lat: 37.5409, Category: models.Entertainment},
+		{ID: 4, Lon: -76.2859, Lat: 36.8508},
+	}
+
+	clusters := clusterOrganizations(organizations, 10)
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %+v", clusters)
+	}
+	if c := clusters[0]; c.Count != 3 || c.Categories[models.Restaurant] != 2 || c.Categories[models.Entertainment] != 1 || c.Organization != nil {
+		t.Errorf("unexpected cluster: %+v", c)
+	}
+	if c := clusters[1]; c.Count != 1 || c.Categories[uncategorized] != 1 || c.Organization == nil || c.Organization.ID != 4 {
+		t.Errorf("unexpected cluster: %+v", c)
+	}
+
+	if clusters := clusterOrganizations(organizations, maxClusterZoom); len(clusters) != len(organizations) {
+		t.Errorf("expected every organization to be returned individually, got %+v", clusters)
+	}
+}
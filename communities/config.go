@@ -2,23 +2,35 @@ package communities
 
 import (
 	"github.com/jmoiron/sqlx"
-	minio "github.com/minio/minio-go"
 
+	"github.com/jteppinette/peragrin-api/geocoder"
 	"github.com/jteppinette/peragrin-api/mail"
+	"github.com/jteppinette/peragrin-api/mvt"
+	"github.com/jteppinette/peragrin-api/store"
 )
 
 // Config represents the configuration objects necessary to
 // use the objects in this package.
 type Config struct {
 	DBClient    *sqlx.DB
-	StoreClient *minio.Client
+	StoreClient store.Store
 	MailClient  *mail.Config
 	TokenSecret string
 	AppDomain   string
+
+	GeocoderClient geocoder.Geocoder
+
+	// WalkingSpeed is the speed, in meters per second, used to estimate walking times.
+	WalkingSpeed float64
+
+	TileCache *mvt.Cache
 }
 
 // Init returns a configuration struct that can be used to initialize
 // the objects in this package.
-func Init(dbClient *sqlx.DB, storeClient *minio.Client, mailClient *mail.Config, tokenSecret, appDomain string) *Config {
-	return &Config{dbClient, storeClient, mailClient, tokenSecret, appDomain}
+func Init(dbClient *sqlx.DB, storeClient store.Store, mailClient *mail.Config, tokenSecret, appDomain string, geocoderClient geocoder.Geocoder, walkingSpeed float64) *Config {
+	if walkingSpeed <= 0 {
+		walkingSpeed = defaultWalkingSpeed
+	}
+	return &Config{dbClient, storeClient, mailClient, tokenSecret, appDomain, geocoderClient, walkingSpeed, mvt.NewCache(tileCacheSize, tileCacheTTL)}
 }
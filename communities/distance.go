@@ -0,0 +1,67 @@
+package communities
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/jteppinette/peragrin-api/geometry"
+	"github.com/jteppinette/peragrin-api/models"
+)
+
+const (
+	// defaultWalkingSpeed is the walking speed, in meters per second, used when one is not configured.
+	defaultWalkingSpeed = 1.4
+	// minWalkingSpeed and maxWalkingSpeed bound the walking speeds that members may request.
+	minWalkingSpeed = 0.3
+	maxWalkingSpeed = 3
+	// walkingDetour accounts for streets and paths being longer than the great circle distance.
+	walkingDetour = 1.3
+)
+
+// parseFinite parses a float query parameter. Unlike strconv.ParseFloat, NaN and infinities
+// are rejected, because they compare false with every bound.
+func parseFinite(v string) (float64, error) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, errNotFinite
+	}
+	return f, nil
+}
+
+// locatedOrganization is an organization along with its distance and bearing, in degrees
+// clockwise from north, from a member's location. WalkingTime is an estimate in seconds.
+type locatedOrganization struct {
+	models.Organization
+	Distance    float64 `json:"distance"`
+	Bearing     float64 `json:"bearing"`
+	WalkingTime int     `json:"walkingTime"`
+}
+
+// locateOrganizations measures the distance from origin to every organization and estimates
+// the time it takes to walk there at the provided speed. Organizations further than maxDistance
+// meters are removed unless maxDistance is zero. When byDistance is true, the nearest organizations
+// are first; otherwise the original order is kept.
+func locateOrganizations(organizations models.Organizations, origin geometry.Point, speed, maxDistance float64, byDistance bool) []locatedOrganization {
+	located := []locatedOrganization{}
+	for _, o := range organizations {
+		p := geometry.Point{o.Lon, o.Lat}
+		distance := geometry.Distance(origin, p)
+		if maxDistance > 0 && distance > maxDistance {
+			continue
+		}
+		located = append(located, locatedOrganization{
+			Organization: o,
+			Distance:     math.Floor(distance + 0.5),
+			Bearing:      math.Floor(geometry.Bearing(origin, p)*10+0.5) / 10,
+			WalkingTime:  int(math.Ceil(distance * walkingDetour / speed)),
+		})
+	}
+	if byDistance {
+		sort.SliceStable(located, func(i, j int) bool { return located[i].Distance < located[j].Distance })
+	}
+	return located
+}
@@ -0,0 +1,44 @@
+package communities
+
+import (
+	"testing"
+
+	"github.com/jteppinette/peragrin-api/geometry"
+	"github.com/jteppinette/peragrin-api/models"
+)
+
+func TestLocateOrganizations(t *testing.T) {
+	organizations := models.Organizations{
+		{ID: 1, Lon: 0, Lat: 0.02},
+		{ID: 2, Lon: 0.01, Lat: 0},
+		{ID: 3, Lon: 0, Lat: -1},
+	}
+	origin := geometry.Point{0, 0}
+
+	located := locateOrganizations(organizations, origin, 1.4, 0, false)
+	if len(located) != 3 || located[0].ID != 1 || located[1].ID != 2 || located[2].ID != 3 {
+		t.Fatalf("expected original order, got %+v", located)
+	}
+	if o := located[1]; o.Distance != 1112 || o.Bearing != 90 || o.WalkingTime != 1033 {
+		t.Errorf("unexpected located organization: %+v", o)
+	}
+
+	located = locateOrganizations(organizations, origin, 1.4, 5000, true)
+	if len(located) != 2 || located[0].ID != 2 || located[1].ID != 1 {
+		t.Errorf("expected nearest organizations within 5km, got %+v", located)
+	}
+	if o := located[1]; o.Bearing != 0 || o.Distance != 2224 {
+		t.Errorf("unexpected located organization: %+v", o)
+	}
+}
+
+func TestParseFinite(t *testing.T) {
+	if v, err := parseFinite("1.5"); err != nil || v != 1.5 {
+		t.Errorf("expected 1.5, got %v, %v", v, err)
+	}
+	for _, v := range []string{"NaN", "nan", "Inf", "-Inf", "+Infinity", "1e400", "walk"} {
+		if _, err := parseFinite(v); err == nil {
+			t.Errorf("expected %q to be rejected", v)
+		}
+	}
+}
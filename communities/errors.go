@@ -2,14 +2,42 @@ package communities
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
-	errCommunityIDRequired = errors.New("community id required")
-	errCreateOrganization  = errors.New("create organization")
+	errCommunityIDRequired    = errors.New("community id required")
+	errCommunityNotFound      = errors.New("community not found")
+	errUploadLogo             = errors.New("upload logo")
+	errOrganizationIDRequired = errors.New("organization id required")
+	errOrganizationNotFound   = errors.New("organization not found")
+	errCreateOrganization     = errors.New("create organization")
 
 	errAuthenticationRequired = errors.New("authentication required")
-	errSuperUserRequired = errors.New("super user required")
+	errSuperUserRequired      = errors.New("super user required")
+	errAdministratorRequired  = errors.New("community administrator required")
 
 	errAccountActivationEmail = errors.New("account activation email")
+
+	errJoinRequestNotFound     = errors.New("join request not found")
+	errJoinRequestNotification = errors.New("join request notification")
+	errReasonRequired          = errors.New("reason required")
+
+	errImportFormatNotSupported = errors.New("import format not supported")
+	errImportTooManyGeocodes    = fmt.Errorf("import may geocode at most %d rows - provide lon and lat or use the import command", maxImportGeocodes)
+	errExportOrganizations      = errors.New("export organizations")
+
+	errLocationRequired             = errors.New("lon and lat required")
+	errOrganizationsOutsideBoundary = errors.New("organizations outside community boundary")
+	errSortNotSupported             = errors.New("sort must be distance")
+	errMaxDistance                  = errors.New("maxDistance must be a positive number of meters")
+	errWalkingSpeed                 = errors.New("speed must be between 0.3 and 3 meters per second")
+	errNotFinite                    = errors.New("number must be finite")
+
+	errOverlayIDRequired = errors.New("overlay id required")
+	errOverlayNotFound   = errors.New("overlay not found")
+	errOverlayTooLarge   = errors.New("overlay too large")
+	errInvalidZoom       = errors.New("zoom must be an integer between 0 and 22")
+
+	errTile = errors.New("tile")
 )
@@ -0,0 +1,151 @@
+package communities
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jteppinette/peragrin-api/models"
+)
+
+// organizationEncoder writes a stream of organizations in a single export format.
+type organizationEncoder interface {
+	Begin(community models.Community) error
+	Encode(organization models.Organization) error
+	End() error
+}
+
+// exportFormats maps the supported export formats to their content type and encoder.
+var exportFormats = map[string]struct {
+	ContentType string
+	New         func(io.Writer) organizationEncoder
+}{
+	"csv":     {"text/csv", func(w io.Writer) organizationEncoder { return &csvEncoder{w: csv.NewWriter(w)} }},
+	"geojson": {"application/geo+json", func(w io.Writer) organizationEncoder { return &geoJSONEncoder{w: w} }},
+	"kml":     {"application/vnd.google-earth.kml+xml", func(w io.Writer) organizationEncoder { return &kmlEncoder{w: w, e: xml.NewEncoder(w)} }},
+}
+
+// csvEncoder writes organizations using the same columns that are accepted by imports.
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) Begin(community models.Community) error {
+	return e.w.Write([]string{"name", "street", "city", "state", "country", "zip", "lon", "lat", "email", "phone", "website", "category", "hours"})
+}
+
+func (e *csvEncoder) Encode(o models.Organization) error {
+	return e.w.Write([]string{
+		o.Name, o.Street, o.City, o.State, o.Country, o.Zip,
+		strconv.FormatFloat(o.Lon, 'f', -1, 64), strconv.FormatFloat(o.Lat, 'f', -1, 64),
+		o.Email, o.Phone, o.Website, o.Category, o.Hours.String(),
+	})
+}
+
+func (e *csvEncoder) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// geoJSONEncoder writes organizations as Point features of a FeatureCollection.
+type geoJSONEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *geoJSONEncoder) Begin(community models.Community) error {
+	_, err := io.WriteString(e.w, `{"type":"FeatureCollection","features":[`)
+	return err
+}
+
+func (e *geoJSONEncoder) Encode(o models.Organization) error {
+	b, err := json.Marshal(struct {
+		Type       string              `json:"type"`
+		Geometry   interface{}         `json:"geometry"`
+		Properties models.Organization `json:"properties"`
+	}{"Feature", struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	}{"Point", [2]float64{o.Lon, o.Lat}}, o})
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *geoJSONEncoder) End() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+// kmlEncoder writes organizations as Placemarks of a KML Document.
+type kmlEncoder struct {
+	w io.Writer
+	e *xml.Encoder
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPlacemark struct {
+	XMLName      xml.Name  `xml:"Placemark"`
+	ID           string    `xml:"id,attr"`
+	Name         string    `xml:"name"`
+	Description  string    `xml:"description"`
+	ExtendedData []kmlData `xml:"ExtendedData>Data"`
+	Coordinates  string    `xml:"Point>coordinates"`
+}
+
+func (e *kmlEncoder) Begin(community models.Community) error {
+	if _, err := io.WriteString(e.w, xml.Header+`<kml xmlns="http://www.opengis.net/kml/2.2"><Document>`); err != nil {
+		return err
+	}
+	return e.e.EncodeElement(community.Name, xml.StartElement{Name: xml.Name{Local: "name"}})
+}
+
+func (e *kmlEncoder) Encode(o models.Organization) error {
+	address := strings.Join(nonEmpty(o.Street, o.City, o.State, o.Zip, o.Country), ", ")
+	return e.e.Encode(kmlPlacemark{
+		ID:          fmt.Sprintf("organization-%d", o.ID),
+		Name:        o.Name,
+		Description: strings.Join(nonEmpty(o.Category, address, o.Hours.String()), "\n"),
+		ExtendedData: []kmlData{
+			{"category", o.Category},
+			{"street", o.Street}, {"city", o.City}, {"state", o.State}, {"country", o.Country}, {"zip", o.Zip},
+			{"email", o.Email}, {"phone", o.Phone}, {"website", o.Website},
+			{"hours", o.Hours.String()},
+		},
+		Coordinates: fmt.Sprintf("%s,%s", strconv.FormatFloat(o.Lon, 'f', -1, 64), strconv.FormatFloat(o.Lat, 'f', -1, 64)),
+	})
+}
+
+func (e *kmlEncoder) End() error {
+	if err := e.e.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(e.w, "</Document></kml>\n")
+	return err
+}
+
+func nonEmpty(values ...string) []string {
+	result := []string{}
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
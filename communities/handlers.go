@@ -1,17 +1,25 @@
 package communities
 
 import (
+	"crypto/sha1"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 
+	"github.com/jteppinette/peragrin-api/geometry"
 	"github.com/jteppinette/peragrin-api/models"
+	"github.com/jteppinette/peragrin-api/mvt"
 	"github.com/jteppinette/peragrin-api/service"
 )
 
@@ -22,6 +30,34 @@ func (c *Config) ListHandler(r *http.Request) *service.Response {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
 
+	if err := communities.SetPresignedLogoLinks(c.StoreClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	return service.NewResponse(nil, http.StatusOK, communities)
+}
+
+// LocateHandler returns a response with all communities whose boundary contains
+// the location provided by the lon and lat query parameters.
+func (c *Config) LocateHandler(r *http.Request) *service.Response {
+	values := r.URL.Query()
+
+	lon, err := strconv.ParseFloat(values.Get("lon"), 64)
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errLocationRequired.Error()), http.StatusBadRequest, map[string]string{"msg": errLocationRequired.Error()})
+	}
+	lat, err := strconv.ParseFloat(values.Get("lat"), 64)
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errLocationRequired.Error()), http.StatusBadRequest, map[string]string{"msg": errLocationRequired.Error()})
+	}
+	if err := (geometry.Point{lon, lat}).Validate(); err != nil {
+		return service.NewResponse(errors.Wrap(err, errLocationRequired.Error()), http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+
+	communities, err := models.GetCommunitiesByLocation(lon, lat, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
 	return service.NewResponse(nil, http.StatusOK, communities)
 }
 
@@ -37,17 +73,116 @@ func (c *Config) GetHandler(r *http.Request) *service.Response {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
 
+	if err := community.SetPresignedLogoLink(c.StoreClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	return service.NewResponse(nil, http.StatusOK, community)
+}
+
+// GetBySlugHandler returns a response with the community that has the requested slug. Slugs
+// that the community used before are permanently redirected to its current slug.
+func (c *Config) GetBySlugHandler(r *http.Request) *service.Response {
+	community, moved, err := models.GetCommunityBySlug(mux.Vars(r)["slug"], c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if community == nil {
+		return service.NewResponse(errCommunityNotFound, http.StatusNotFound, map[string]string{"msg": errCommunityNotFound.Error()})
+	}
+
+	if err := community.SetPresignedLogoLink(c.StoreClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	if moved {
+		resp := service.NewResponse(nil, http.StatusMovedPermanently, community)
+		resp.Header = http.Header{"Location": {"/communities/by-slug/" + url.PathEscape(community.Slug)}}
+		return resp
+	}
+	return service.NewResponse(nil, http.StatusOK, community)
+}
+
+// UploadLogoHandler uploads a new logo to the store and sets the community's logo field.
+// This requires that the requesting account administers the community.
+func (c *Config) UploadLogoHandler(r *http.Request) *service.Response {
+	communityID, resp := c.authorizeAdministrator(r)
+	if resp != nil {
+		return resp
+	}
+
+	file, header, err := r.FormFile("logo")
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	defer file.Close()
+
+	community, err := models.GetCommunityByID(communityID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	if err := community.UploadLogo(header.Filename, file, c.StoreClient, c.DBClient); err != nil {
+		log.WithFields(log.Fields{
+			"communityID": communityID,
+			"filename":    header.Filename,
+			"error":       err.Error(),
+			"id":          r.Header.Get("X-Request-ID"),
+		}).Info(errUploadLogo.Error())
+		return service.NewResponse(errUploadLogo, http.StatusBadRequest, nil)
+	}
+
+	if err := community.SetPresignedLogoLink(c.StoreClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
 	return service.NewResponse(nil, http.StatusOK, community)
 }
 
 // ListOrganizationsHandler returns a response with all organizations
-// in a given community.
+// in a given community. When the member's location is provided with the lon and lat query
+// parameters, each organization includes its distance in meters, bearing, and walking time in
+// seconds from that location. Organizations may then be sorted with sort=distance, and limited
+// to those within maxDistance meters. The speed query parameter overrides the configured
+// walking speed in meters per second.
 func (c *Config) ListOrganizationsHandler(r *http.Request) *service.Response {
+	values := r.URL.Query()
+
 	communityID, err := strconv.Atoi(mux.Vars(r)["communityID"])
 	if err != nil {
 		return service.NewResponse(errors.Wrap(err, errCommunityIDRequired.Error()), http.StatusBadRequest, nil)
 	}
 
+	var origin *geometry.Point
+	if values.Get("lon") != "" || values.Get("lat") != "" {
+		lon, lonErr := strconv.ParseFloat(values.Get("lon"), 64)
+		lat, latErr := strconv.ParseFloat(values.Get("lat"), 64)
+		p := geometry.Point{lon, lat}
+		if lonErr != nil || latErr != nil || p.Validate() != nil {
+			return service.NewResponse(errLocationRequired, http.StatusBadRequest, map[string]string{"msg": errLocationRequired.Error()})
+		}
+		origin = &p
+	}
+
+	sortBy := values.Get("sort")
+	if sortBy != "" && sortBy != "distance" {
+		return service.NewResponse(errSortNotSupported, http.StatusBadRequest, map[string]string{"msg": errSortNotSupported.Error()})
+	}
+	var maxDistance float64
+	if v := values.Get("maxDistance"); v != "" {
+		if maxDistance, err = parseFinite(v); err != nil || maxDistance <= 0 {
+			return service.NewResponse(errMaxDistance, http.StatusBadRequest, map[string]string{"msg": errMaxDistance.Error()})
+		}
+	}
+	speed := c.WalkingSpeed
+	if v := values.Get("speed"); v != "" {
+		if speed, err = parseFinite(v); err != nil || speed < minWalkingSpeed || speed > maxWalkingSpeed {
+			return service.NewResponse(errWalkingSpeed, http.StatusBadRequest, map[string]string{"msg": errWalkingSpeed.Error()})
+		}
+	}
+	if origin == nil && (sortBy != "" || maxDistance > 0) {
+		return service.NewResponse(errLocationRequired, http.StatusBadRequest, map[string]string{"msg": errLocationRequired.Error()})
+	}
+
 	organizations, err := models.GetOrganizationsByCommunity(communityID, c.DBClient)
 	if err != nil {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
@@ -57,9 +192,43 @@ func (c *Config) ListOrganizationsHandler(r *http.Request) *service.Response {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
 
+	if origin != nil {
+		return service.NewResponse(nil, http.StatusOK, locateOrganizations(organizations, *origin, speed, maxDistance, sortBy == "distance"))
+	}
 	return service.NewResponse(nil, http.StatusOK, organizations)
 }
 
+// ListOrganizationClustersHandler returns a response with the given community's organizations
+// grouped into clusters for the zoom query parameter. Each cluster includes its organization
+// count and category breakdown, and clusters of a single organization include the organization.
+// When the bbox query parameter is provided, only organizations within the bounds are clustered.
+func (c *Config) ListOrganizationClustersHandler(r *http.Request) *service.Response {
+	communityID, err := strconv.Atoi(mux.Vars(r)["communityID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errCommunityIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	zoom, err := strconv.Atoi(r.URL.Query().Get("zoom"))
+	if err != nil || zoom < 0 || zoom > maxZoom {
+		return service.NewResponse(errInvalidZoom, http.StatusBadRequest, map[string]string{"msg": errInvalidZoom.Error()})
+	}
+
+	var organizations models.Organizations
+	if bbox := r.URL.Query().Get("bbox"); bbox != "" {
+		b, err := geometry.ParseBounds(bbox)
+		if err != nil {
+			return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+		}
+		organizations, err = models.GetOrganizationsByBounds(communityID, b, c.DBClient)
+	} else {
+		organizations, err = models.GetOrganizationsByCommunity(communityID, c.DBClient)
+	}
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, clusterOrganizations(organizations, zoom))
+}
+
 // CreateOrganizationHandler creates a new organization that is automatically
 // joined with the creating community.
 func (c *Config) CreateOrganizationHandler(r *http.Request) *service.Response {
@@ -81,7 +250,7 @@ func (c *Config) CreateOrganizationHandler(r *http.Request) *service.Response {
 		organization.IsAdministrator = nil
 	}
 
-	if err := organization.CreateWithCommunity(communityID, c.DBClient); err != nil {
+	if err := organization.CreateWithCommunity(communityID, c.GeocoderClient, c.DBClient); err != nil {
 		return service.NewResponse(errors.Wrap(err, errCreateOrganization.Error()), http.StatusBadRequest, nil)
 	}
 
@@ -117,24 +286,160 @@ func (c *Config) CreateMembershipHandler(r *http.Request) *service.Response {
 	}
 
 	if err := membership.Create(communityID, c.DBClient); err != nil {
-		return service.NewResponse(err, http.StatusBadRequest, nil)
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
 	}
 	return service.NewResponse(nil, http.StatusCreated, membership)
 }
 
+// ListPaymentsHandler returns a response with the community's payments ledger.
+func (c *Config) ListPaymentsHandler(r *http.Request) *service.Response {
+	communityID, response := c.authorizeAdministrator(r)
+	if response != nil {
+		return response
+	}
+
+	payments, err := models.GetPaymentsByCommunity(communityID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, payments)
+}
+
+// TrafficHandler returns a report of the visits to the community's organizations. The from,
+// to and interval query parameters select the period and how it is divided, and dates are
+// in the community's time zone.
+func (c *Config) TrafficHandler(r *http.Request) *service.Response {
+	communityID, response := c.authorizeAdministrator(r)
+	if response != nil {
+		return response
+	}
+
+	community, err := models.GetCommunityByID(communityID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	query := r.URL.Query()
+	report, err := models.NewTrafficReport(query.Get("from"), query.Get("to"), query.Get("interval"), community.Location(), time.Now())
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	if err := report.LoadCommunity(communityID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, report)
+}
+
+// ListReminderTemplatesHandler returns a response with the community's template for every
+// kind of membership reminder.
+func (c *Config) ListReminderTemplatesHandler(r *http.Request) *service.Response {
+	communityID, response := c.authorizeAdministrator(r)
+	if response != nil {
+		return response
+	}
+
+	templates, err := models.GetReminderTemplatesByCommunity(communityID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, templates)
+}
+
+// UpdateReminderTemplateHandler customizes the community's template for a kind of membership reminder.
+func (c *Config) UpdateReminderTemplateHandler(r *http.Request) *service.Response {
+	communityID, response := c.authorizeAdministrator(r)
+	if response != nil {
+		return response
+	}
+
+	t := models.ReminderTemplate{}
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	t.CommunityID, t.Kind = communityID, mux.Vars(r)["kind"]
+
+	if err := t.Save(c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusOK, t)
+}
+
+// DeleteReminderTemplateHandler resets the community's template for a kind of membership
+// reminder to the default.
+func (c *Config) DeleteReminderTemplateHandler(r *http.Request) *service.Response {
+	communityID, response := c.authorizeAdministrator(r)
+	if response != nil {
+		return response
+	}
+
+	if err := models.DeleteReminderTemplate(communityID, mux.Vars(r)["kind"], c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusNoContent, nil)
+}
+
 // ListGeoJSONOverlaysHandler returns a response with all geo JSON overlays
-// in a given community.
+// in a given community. When the zoom query parameter is provided, lines and polygons
+// are simplified to the detail visible at that zoom. When the bbox query parameter is
+// provided, only overlays within the bounds are returned and their geometry is clipped.
+// Responses carry an ETag so that clients can revalidate without downloading the overlays.
 func (c *Config) ListGeoJSONOverlaysHandler(r *http.Request) *service.Response {
 	communityID, err := strconv.Atoi(mux.Vars(r)["communityID"])
 	if err != nil {
 		return service.NewResponse(errors.Wrap(err, errCommunityIDRequired.Error()), http.StatusBadRequest, nil)
 	}
 
-	geoJSONOverlays, err := models.GetGeoJSONOverlaysByCommunity(communityID, c.DBClient)
+	tolerance := 0.0
+	zoom := r.URL.Query().Get("zoom")
+	if zoom != "" {
+		z, err := strconv.Atoi(zoom)
+		if err != nil || z < 0 || z > maxZoom {
+			return service.NewResponse(errInvalidZoom, http.StatusBadRequest, map[string]string{"msg": errInvalidZoom.Error()})
+		}
+		tolerance = geometry.ToleranceForZoom(z)
+	}
+
+	var clip *geometry.Bounds
+	bbox := r.URL.Query().Get("bbox")
+	if bbox != "" {
+		b, err := geometry.ParseBounds(bbox)
+		if err != nil {
+			return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+		}
+		clip = &b
+	}
+
+	version, err := models.GetGeoJSONOverlaysVersion(communityID, c.DBClient)
 	if err != nil {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
-	return service.NewResponse(nil, http.StatusOK, geoJSONOverlays)
+	header := http.Header{}
+	header.Set("ETag", fmt.Sprintf(`"%x"`, sha1.Sum([]byte(version+"|"+zoom+"|"+bbox))))
+	header.Set("Cache-Control", "no-cache")
+	if match := r.Header.Get("If-None-Match"); match != "" && match == header.Get("ETag") {
+		return &service.Response{Code: http.StatusNotModified, Header: header}
+	}
+
+	var geoJSONOverlays []models.GeoJSONOverlay
+	if clip != nil {
+		geoJSONOverlays, err = models.GetGeoJSONOverlaysByBounds(communityID, *clip, c.DBClient)
+	} else {
+		geoJSONOverlays, err = models.GetGeoJSONOverlaysByCommunity(communityID, c.DBClient)
+	}
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	if tolerance > 0 || clip != nil {
+		for i, o := range geoJSONOverlays {
+			data, err := geometry.TransformGeoJSON(o.Data, tolerance, clip)
+			if err != nil {
+				return service.NewResponse(err, http.StatusBadRequest, nil)
+			}
+			geoJSONOverlays[i].Data = data
+		}
+	}
+	return &service.Response{Code: http.StatusOK, Data: geoJSONOverlays, Header: header}
 }
 
 // ListPostsHandler returns a response with all posts
@@ -215,20 +520,48 @@ func (c *Config) BulkAddAccountsHandler(r *http.Request) *service.Response {
 	return service.NewResponse(nil, http.StatusOK, nil)
 }
 
-// UpdateHandler updates an community.
+// UpdateHandler updates an community. A boundary that would exclude existing member organizations
+// is rejected along with the list of those organizations, unless the force query parameter is true,
+// in which case the excluded organizations are flagged for review. Fields that are omitted
+// from the request keep their current values. This requires that the requesting account
+// administers the community, because a changed slug redirects the community's previous
+// public URL to the new one.
 func (c *Config) UpdateHandler(r *http.Request) *service.Response {
-	id, err := strconv.Atoi(mux.Vars(r)["communityID"])
-	if err != nil {
-		return service.NewResponse(errors.Wrap(err, errCommunityIDRequired.Error()), http.StatusBadRequest, nil)
+	id, resp := c.authorizeAdministrator(r)
+	if resp != nil {
+		return resp
 	}
 
-	community := models.Community{}
+	community, err := models.GetCommunityByID(id, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
 	if err := json.NewDecoder(r.Body).Decode(&community); err != nil {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
 	community.ID = id
 
+	if force, _ := strconv.ParseBool(r.URL.Query().Get("force")); !force && community.Boundary.Valid {
+		organizations, err := models.GetOrganizationsByCommunity(id, c.DBClient)
+		if err != nil {
+			return service.NewResponse(err, http.StatusBadRequest, nil)
+		}
+		outside, err := community.OrganizationsOutside(organizations)
+		if err != nil {
+			return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+		}
+		if len(outside) > 0 {
+			return service.NewResponse(errOrganizationsOutsideBoundary, http.StatusUnprocessableEntity, struct {
+				Msg           string               `json:"msg"`
+				Organizations models.Organizations `json:"organizations"`
+			}{errOrganizationsOutsideBoundary.Error(), outside})
+		}
+	}
+
 	if err := community.Update(c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	if err := community.FlagOrganizationsOutside(c.DBClient); err != nil {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
 	return service.NewResponse(nil, http.StatusOK, community)
@@ -251,7 +584,7 @@ func (c *Config) CreateHandler(r *http.Request) *service.Response {
 	}
 
 	if err := community.Create(c.DBClient); err != nil {
-		return service.NewResponse(err, http.StatusBadRequest, nil)
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
 	}
 
 	return service.NewResponse(nil, http.StatusCreated, community)
@@ -275,3 +608,478 @@ func (c *Config) DeleteHandler(r *http.Request) *service.Response {
 	}
 	return service.NewResponse(nil, http.StatusNoContent, nil)
 }
+
+// ListJoinRequestsHandler returns a response with all pending requests from organizations
+// to join the given community. This requires that the requesting account administers the community.
+func (c *Config) ListJoinRequestsHandler(r *http.Request) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	communityID, err := strconv.Atoi(mux.Vars(r)["communityID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errCommunityIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	if ok, err := account.IsCommunityAdministrator(communityID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return service.NewResponse(errAdministratorRequired, http.StatusForbidden, nil)
+	}
+
+	requests, err := models.GetPendingCommunityOrganizations(communityID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, requests)
+}
+
+// ApproveJoinRequestHandler makes the requesting organization a member of the given community.
+// This requires that the requesting account administers the community.
+func (c *Config) ApproveJoinRequestHandler(r *http.Request) *service.Response {
+	return c.resolveJoinRequest(r, func(co *models.CommunityOrganization) error {
+		return co.Approve(c.DBClient)
+	})
+}
+
+// RejectJoinRequestHandler declines an organization's request to join the given community. The
+// provided reason is shared with the organization's operators. This requires that the requesting
+// account administers the community.
+func (c *Config) RejectJoinRequestHandler(r *http.Request) *service.Response {
+	form := struct {
+		Reason string `json:"reason"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if form.Reason == "" {
+		return service.NewResponse(errReasonRequired, http.StatusBadRequest, map[string]string{"msg": errReasonRequired.Error()})
+	}
+
+	return c.resolveJoinRequest(r, func(co *models.CommunityOrganization) error {
+		return co.Reject(form.Reason, c.DBClient)
+	})
+}
+
+func (c *Config) resolveJoinRequest(r *http.Request, resolve func(*models.CommunityOrganization) error) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	communityID, err := strconv.Atoi(mux.Vars(r)["communityID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errCommunityIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+	organizationID, err := strconv.Atoi(mux.Vars(r)["organizationID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errOrganizationIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	if ok, err := account.IsCommunityAdministrator(communityID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return service.NewResponse(errAdministratorRequired, http.StatusForbidden, nil)
+	}
+
+	co, err := models.GetCommunityOrganization(communityID, organizationID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if co == nil {
+		return service.NewResponse(errJoinRequestNotFound, http.StatusNotFound, nil)
+	}
+
+	if err := resolve(co); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+
+	community, err := models.GetCommunityByID(communityID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	operators, err := models.GetAccountsByOrganization(organizationID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	subject := fmt.Sprintf("%s Join Request %s", community.Name, strings.Title(co.Status))
+	body := fmt.Sprintf("Your request to join %s has been %s.", community.Name, co.Status)
+	if co.Reason != "" {
+		body = fmt.Sprintf("%s\r\n\r\nReason: %s", body, co.Reason)
+	}
+
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				log.WithFields(log.Fields{"error": err, "id": r.Header.Get("X-Request-ID")}).Info(errJoinRequestNotification.Error())
+			}
+		}()
+		for _, operator := range operators {
+			if err := c.MailClient.Send([]string{operator.Email}, subject, body); err != nil {
+				log.WithFields(log.Fields{
+					"email": operator.Email, "error": err.Error(), "id": r.Header.Get("X-Request-ID"),
+				}).Info(errJoinRequestNotification.Error())
+			}
+		}
+	}()
+
+	return service.NewResponse(nil, http.StatusOK, co)
+}
+
+// ListOrganizationReviewsHandler returns a response with the given community's organizations that
+// have been flagged for review, either because they could not be located or because they are
+// outside of the community's boundary. This requires that the requesting account administers
+// the community.
+func (c *Config) ListOrganizationReviewsHandler(r *http.Request) *service.Response {
+	communityID, resp := c.authorizeAdministrator(r)
+	if resp != nil {
+		return resp
+	}
+
+	organizations, err := models.GetOrganizationsForReview(communityID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, organizations)
+}
+
+// ResolveOrganizationReviewHandler clears the review flag of one of the given community's
+// organizations. This requires that the requesting account administers the community.
+func (c *Config) ResolveOrganizationReviewHandler(r *http.Request) *service.Response {
+	communityID, resp := c.authorizeAdministrator(r)
+	if resp != nil {
+		return resp
+	}
+
+	organizationID, err := strconv.Atoi(mux.Vars(r)["organizationID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errOrganizationIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	co, err := models.GetCommunityOrganization(communityID, organizationID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if co == nil || co.Status != models.JoinStatusApproved {
+		return service.NewResponse(errOrganizationNotFound, http.StatusNotFound, nil)
+	}
+
+	organization := models.Organization{ID: organizationID}
+	if err := organization.ResolveReview(c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, organization)
+}
+
+// maxImportGeocodes limits the rows an import request may geocode. Geocoding is rate limited,
+// so larger imports would outlive the request timeout while still creating organizations.
+const maxImportGeocodes = 10
+
+// ImportOrganizationsHandler creates many organizations from a csv or GeoJSON FeatureCollection
+// request body and joins them to the given community in a single atomic action. The format is
+// determined by the request's Content-Type. Rows without coordinates are geocoded, up to
+// maxImportGeocodes per request. If any row is invalid, then nothing is created and the per-row
+// error report is returned. When the dryRun query parameter is true, the report is returned
+// without creating anything. This requires that the requesting account administers the community.
+func (c *Config) ImportOrganizationsHandler(r *http.Request) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	communityID, err := strconv.Atoi(mux.Vars(r)["communityID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errCommunityIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	if ok, err := account.IsCommunityAdministrator(communityID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return service.NewResponse(errAdministratorRequired, http.StatusForbidden, nil)
+	}
+
+	var result *models.OrganizationImport
+	switch contentType := strings.Split(r.Header.Get("Content-Type"), ";")[0]; contentType {
+	case "text/csv":
+		result, err = models.ParseOrganizationsCSV(r.Body)
+	case "application/geo+json", "application/json":
+		result, err = models.ParseOrganizationsGeoJSON(r.Body)
+	default:
+		return service.NewResponse(errImportFormatNotSupported, http.StatusUnsupportedMediaType, map[string]string{"msg": errImportFormatNotSupported.Error()})
+	}
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+
+	community, err := models.GetCommunityByID(communityID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	result.DryRun, _ = strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	result.Validate(community)
+	if !result.Valid {
+		return service.NewResponse(nil, http.StatusUnprocessableEntity, result)
+	}
+	if result.PendingGeocodes() > maxImportGeocodes {
+		return service.NewResponse(errImportTooManyGeocodes, http.StatusRequestEntityTooLarge, map[string]string{"msg": errImportTooManyGeocodes.Error()})
+	}
+	result.Geocode(c.GeocoderClient, community)
+	if !result.Valid {
+		return service.NewResponse(nil, http.StatusUnprocessableEntity, result)
+	}
+
+	if err := result.CreateWithCommunity(communityID, c.DBClient); err != nil {
+		return service.NewResponse(errors.Wrap(err, errCreateOrganization.Error()), http.StatusBadRequest, nil)
+	}
+
+	if result.DryRun {
+		return service.NewResponse(nil, http.StatusOK, result)
+	}
+	return service.NewResponse(nil, http.StatusCreated, result)
+}
+
+// ExportOrganizationsHandler streams every organization in the given community, including their
+// hours and categories, as a csv, GeoJSON FeatureCollection, or KML document. This is a raw http
+// handler, so that large communities are written as they are read instead of being buffered.
+func (c *Config) ExportOrganizationsHandler(w http.ResponseWriter, r *http.Request) {
+	fields := log.Fields{"method": r.Method, "url": r.URL.String(), "id": r.Header.Get("X-Request-ID")}
+
+	communityID, err := strconv.Atoi(mux.Vars(r)["communityID"])
+	if err != nil {
+		log.WithFields(fields).WithField("error", errCommunityIDRequired.Error()).Info(errExportOrganizations.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	format, ok := exportFormats[mux.Vars(r)["format"]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	community, err := models.GetCommunityByID(communityID, c.DBClient)
+	if err != nil {
+		log.WithFields(fields).WithField("error", err.Error()).Info(errExportOrganizations.Error())
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="community-%d-organizations.%s"`, community.ID, mux.Vars(r)["format"]))
+
+	flusher, _ := w.(http.Flusher)
+	encoder := format.New(w)
+
+	// Once the body has been started, errors can only be logged, because the status code has been written.
+	var count int
+	err = encoder.Begin(community)
+	if err == nil {
+		err = models.EachOrganizationByCommunity(communityID, c.DBClient, func(organization models.Organization) error {
+			if err := encoder.Encode(organization); err != nil {
+				return err
+			}
+			if count++; flusher != nil && count%100 == 0 {
+				flusher.Flush()
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = encoder.End()
+	}
+	if err != nil {
+		log.WithFields(fields).WithField("error", err.Error()).Error(errExportOrganizations.Error())
+		return
+	}
+
+	log.WithFields(fields).WithField("count", count).Info("access log")
+}
+
+// maxZoom is the deepest web map zoom level that overlays may be requested at.
+const maxZoom = 22
+
+// maxOverlayRequestSize bounds overlay request bodies, leaving room for the name and encoding.
+const maxOverlayRequestSize = models.MaxGeoJSONOverlayDataSize + models.MaxGeoJSONOverlayStyleSize + 1<<10
+
+// CreateGeoJSONOverlayHandler adds a new geo JSON overlay to the end of the given community's
+// overlays. This requires that the requesting account administers the community.
+func (c *Config) CreateGeoJSONOverlayHandler(r *http.Request) *service.Response {
+	communityID, response := c.authorizeAdministrator(r)
+	if response != nil {
+		return response
+	}
+
+	overlay := models.GeoJSONOverlay{}
+	if response := decodeOverlay(r, &overlay); response != nil {
+		return response
+	}
+	if err := overlay.Create(communityID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusCreated, overlay)
+}
+
+// UpdateGeoJSONOverlayHandler replaces the name, data, and style of a geo JSON overlay in the
+// given community. This requires that the requesting account administers the community.
+func (c *Config) UpdateGeoJSONOverlayHandler(r *http.Request) *service.Response {
+	communityID, response := c.authorizeAdministrator(r)
+	if response != nil {
+		return response
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["overlayID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errOverlayIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	overlay := models.GeoJSONOverlay{}
+	if response := decodeOverlay(r, &overlay); response != nil {
+		return response
+	}
+	overlay.ID, overlay.CommunityID = id, communityID
+
+	if existing, err := models.GetGeoJSONOverlayByID(communityID, id, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if existing == nil {
+		return service.NewResponse(errOverlayNotFound, http.StatusNotFound, nil)
+	}
+
+	if err := overlay.Update(c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusOK, overlay)
+}
+
+// DeleteGeoJSONOverlayHandler removes a geo JSON overlay from the given community.
+// This requires that the requesting account administers the community.
+func (c *Config) DeleteGeoJSONOverlayHandler(r *http.Request) *service.Response {
+	communityID, response := c.authorizeAdministrator(r)
+	if response != nil {
+		return response
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["overlayID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errOverlayIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	overlay := models.GeoJSONOverlay{ID: id, CommunityID: communityID}
+	if err := overlay.Delete(c.DBClient); err == models.ErrOverlayNotFound {
+		return service.NewResponse(err, http.StatusNotFound, map[string]string{"msg": err.Error()})
+	} else if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusNoContent, nil)
+}
+
+// ReorderGeoJSONOverlaysHandler sets the display order of the given community's geo JSON
+// overlays. The request body is the list of every overlay id in the desired order.
+// This requires that the requesting account administers the community.
+func (c *Config) ReorderGeoJSONOverlaysHandler(r *http.Request) *service.Response {
+	communityID, response := c.authorizeAdministrator(r)
+	if response != nil {
+		return response
+	}
+
+	ids := []int{}
+	if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	overlays, err := models.ReorderGeoJSONOverlays(communityID, ids, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusOK, overlays)
+}
+
+// authorizeAdministrator returns the community id from the request path, or a response
+// if the requesting account does not administer that community.
+func (c *Config) authorizeAdministrator(r *http.Request) (int, *service.Response) {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return 0, service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	communityID, err := strconv.Atoi(mux.Vars(r)["communityID"])
+	if err != nil {
+		return 0, service.NewResponse(errors.Wrap(err, errCommunityIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	if ok, err := account.IsCommunityAdministrator(communityID, c.DBClient); err != nil {
+		return 0, service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return 0, service.NewResponse(errAdministratorRequired, http.StatusForbidden, nil)
+	}
+	return communityID, nil
+}
+
+// decodeOverlay reads a size limited overlay from the request body.
+func decodeOverlay(r *http.Request, overlay *models.GeoJSONOverlay) *service.Response {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxOverlayRequestSize+1))
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if len(body) > maxOverlayRequestSize {
+		return service.NewResponse(errOverlayTooLarge, http.StatusRequestEntityTooLarge, map[string]string{"msg": errOverlayTooLarge.Error()})
+	}
+	if err := json.Unmarshal(body, overlay); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return nil
+}
+
+// TileHandler writes a Mapbox Vector Tile containing the given community's organizations
+// and geo JSON overlay features. Tiles are cached in memory for a short time, and cached
+// tiles are discarded as soon as the community's overlays change. This is a raw http
+// handler, because the response is a protocol buffer instead of JSON.
+func (c *Config) TileHandler(w http.ResponseWriter, r *http.Request) {
+	fields := log.Fields{"method": r.Method, "url": r.URL.String(), "id": r.Header.Get("X-Request-ID")}
+	vars := mux.Vars(r)
+
+	communityID, err := strconv.Atoi(vars["communityID"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	z, _ := strconv.Atoi(vars["z"])
+	x, _ := strconv.Atoi(vars["x"])
+	y, _ := strconv.Atoi(vars["y"])
+	tile, err := mvt.NewTile(z, x, y)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	community, err := models.GetCommunityByID(communityID, c.DBClient)
+	if err != nil {
+		log.WithFields(fields).WithField("error", err.Error()).Error(errTile.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	version, err := models.GetGeoJSONOverlaysVersion(communityID, c.DBClient)
+	if err != nil {
+		log.WithFields(fields).WithField("error", err.Error()).Error(errTile.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	key := fmt.Sprintf("%d/%s/%s/%d/%d/%d", communityID, version, community.Timezone, z, x, y)
+	data, ok := c.TileCache.Get(key)
+	if !ok {
+		if data, err = c.buildTile(&community, tile, time.Now()); err != nil {
+			log.WithFields(fields).WithField("error", err.Error()).Error(errTile.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		c.TileCache.Set(key, data)
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.mapbox-vector-tile")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(tileCacheTTL.Seconds())))
+	w.Write(data)
+}
@@ -0,0 +1,61 @@
+package communities
+
+import (
+	"time"
+
+	"github.com/jteppinette/peragrin-api/geometry"
+	"github.com/jteppinette/peragrin-api/models"
+	"github.com/jteppinette/peragrin-api/mvt"
+)
+
+const (
+	// tileCacheSize is the number of encoded tiles that are kept in memory.
+	tileCacheSize = 4096
+	// tileCacheTTL bounds how long open-now properties and organization changes may be stale.
+	tileCacheTTL = time.Minute
+)
+
+// buildTile encodes the organizations and geo JSON overlays of a community that are
+// within the tile. Organizations are points with their name, category, and whether
+// they are open now in the community's time zone. Overlay features keep their properties
+// along with the overlay's id and name.
+func (c *Config) buildTile(community *models.Community, tile *mvt.Tile, now time.Time) ([]byte, error) {
+	bounds := tile.Bounds()
+	communityID := community.ID
+	now = now.In(community.Location())
+
+	organizations, err := models.GetOrganizationsByBounds(communityID, bounds, c.DBClient)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(organizations))
+	for i, o := range organizations {
+		ids[i] = o.ID
+	}
+	hours, err := models.GetHoursByOrganizations(ids, c.DBClient)
+	if err != nil {
+		return nil, err
+	}
+	layer := tile.Layer("organizations")
+	for _, o := range organizations {
+		layer.AddPoint(uint64(o.ID), geometry.Point{o.Lon, o.Lat}, map[string]interface{}{
+			"id":       o.ID,
+			"name":     o.Name,
+			"category": o.Category,
+			"open":     hours[o.ID].IsOpen(now),
+		})
+	}
+
+	overlays, err := models.GetGeoJSONOverlaysByBounds(communityID, bounds, c.DBClient)
+	if err != nil {
+		return nil, err
+	}
+	layer = tile.Layer("overlays")
+	for _, o := range overlays {
+		if err := layer.AddGeoJSON(o.Data, map[string]interface{}{"overlayID": o.ID, "overlayName": o.Name}); err != nil {
+			return nil, err
+		}
+	}
+
+	return tile.Marshal(), nil
+}
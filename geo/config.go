@@ -1,13 +1,17 @@
 package geo
 
+import (
+	"github.com/jteppinette/peragrin-api/geocoder"
+)
+
 // Config represents the configuration objects necessary to
 // use the objects in this package.
 type Config struct {
-	LocationIQAPIKey string
+	GeocoderClient geocoder.Geocoder
 }
 
 // Init returns a configuration struct that can be used to initialize
 // the objects in this package.
-func Init(locationIQAPIKey string) *Config {
-	return &Config{locationIQAPIKey}
+func Init(geocoderClient geocoder.Geocoder) *Config {
+	return &Config{geocoderClient}
 }
@@ -3,5 +3,10 @@ package geo
 import "errors"
 
 var (
-	errGeocode = errors.New("geocode")
+	errGeocode      = errors.New("geocode")
+	errQueryMissing = errors.New("q required")
+	errLocation     = errors.New("lon and lat out of range")
+	errLimit        = errors.New("limit must be between 1 and 20")
+	errUnavailable  = errors.New("geocoding provider unavailable")
+	errTimeout      = errors.New("geocoding provider timed out")
 )
@@ -2,33 +2,126 @@ package geo
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
+	"strconv"
 
 	log "github.com/Sirupsen/logrus"
 
+	"github.com/jteppinette/peragrin-api/geocoder"
+	"github.com/jteppinette/peragrin-api/geometry"
 	"github.com/jteppinette/peragrin-api/models"
 	"github.com/jteppinette/peragrin-api/service"
 )
 
+const (
+	// defaultAutocompleteLimit is the number of suggestions returned when no limit is provided.
+	defaultAutocompleteLimit = 5
+	// maxAutocompleteLimit is the largest number of suggestions that may be requested.
+	maxAutocompleteLimit = 20
+)
+
+// geocodeError is the body of every failed geocoding response.
+type geocodeError struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// errorResponse logs a failed geocoding request and describes the failure to the client.
+// Addresses that cannot be found are 404s, provider timeouts are 504s, and other
+// provider failures are 502s.
+func errorResponse(r *http.Request, fields log.Fields, err error) *service.Response {
+	fields["error"] = err.Error()
+	fields["id"] = r.Header.Get("X-Request-ID")
+
+	if err == geocoder.ErrNotFound {
+		log.WithFields(fields).Info(errGeocode.Error())
+		return service.NewResponse(err, http.StatusNotFound, geocodeError{"not_found", geocoder.ErrNotFound.Error()})
+	}
+	if err == geocoder.ErrRateLimited {
+		log.WithFields(fields).Info(errGeocode.Error())
+		return service.NewResponse(err, http.StatusTooManyRequests, geocodeError{"rate_limited", geocoder.ErrRateLimited.Error()})
+	}
+	log.WithFields(fields).Error(errGeocode.Error())
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return service.NewResponse(err, http.StatusGatewayTimeout, geocodeError{"timeout", errTimeout.Error()})
+	}
+	return service.NewResponse(err, http.StatusBadGateway, geocodeError{"unavailable", errUnavailable.Error()})
+}
+
 // LookupHandler returns the longitude and latitude given an address.
 func (c *Config) LookupHandler(r *http.Request) *service.Response {
 	address := models.Address{}
 	if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
-		return service.NewResponse(err, http.StatusBadRequest, nil)
+		return service.NewResponse(err, http.StatusBadRequest, geocodeError{"invalid", err.Error()})
 	}
 
-	lon, lat, err := address.Geocode(c.LocationIQAPIKey)
+	result, err := c.GeocoderClient.Geocode(geocoder.Address(address))
 	if err != nil {
-		log.WithFields(log.Fields{
+		return errorResponse(r, log.Fields{
 			"street": address.Street, "city": address.City, "state": address.State, "country": address.Country, "zip": address.Zip,
-			"error": err.Error(),
-			"id":    r.Header.Get("X-Request-ID"),
-		}).Info(errGeocode.Error())
-		return service.NewResponse(err, http.StatusBadRequest, nil)
+		}, err)
 	}
 
 	return service.NewResponse(nil, http.StatusOK, struct {
 		Lon float64 `json:"lon"`
 		Lat float64 `json:"lat"`
-	}{lon, lat})
+	}{result.Lon, result.Lat})
+}
+
+// ReverseHandler returns the address at the given longitude and latitude.
+func (c *Config) ReverseHandler(r *http.Request) *service.Response {
+	location := struct {
+		Lon float64 `json:"lon"`
+		Lat float64 `json:"lat"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, geocodeError{"invalid", err.Error()})
+	}
+	if err := (geometry.Point{location.Lon, location.Lat}).Validate(); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, geocodeError{"invalid", errLocation.Error()})
+	}
+
+	result, err := c.GeocoderClient.Reverse(location.Lon, location.Lat)
+	if err != nil {
+		return errorResponse(r, log.Fields{"lon": location.Lon, "lat": location.Lat}, err)
+	}
+	return service.NewResponse(nil, http.StatusOK, models.Address(result.Address))
+}
+
+// suggestion is a single autocomplete result.
+type suggestion struct {
+	models.Address
+	Lon        float64 `json:"lon"`
+	Lat        float64 `json:"lat"`
+	Confidence float64 `json:"confidence"`
+}
+
+// AutocompleteHandler returns addresses that match the q query parameter with the best
+// matches first. The number of suggestions is controlled by the limit query parameter.
+func (c *Config) AutocompleteHandler(r *http.Request) *service.Response {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		return service.NewResponse(errQueryMissing, http.StatusBadRequest, geocodeError{"invalid", errQueryMissing.Error()})
+	}
+
+	limit := defaultAutocompleteLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAutocompleteLimit {
+			return service.NewResponse(errLimit, http.StatusBadRequest, geocodeError{"invalid", errLimit.Error()})
+		}
+		limit = n
+	}
+
+	results, err := c.GeocoderClient.Autocomplete(q, limit)
+	if err != nil {
+		return errorResponse(r, log.Fields{"q": q}, err)
+	}
+
+	suggestions := make([]suggestion, len(results))
+	for i, result := range results {
+		suggestions[i] = suggestion{models.Address(result.Address), result.Lon, result.Lat, result.Confidence}
+	}
+	return service.NewResponse(nil, http.StatusOK, suggestions)
 }
@@ -0,0 +1,75 @@
+package geocoder
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Cache remembers the results of another geocoder in the database, so that an address is
+// only sent to the provider once across restarts and instances. Addresses are keyed by
+// their normalized form, and results older than the TTL are looked up again. Addresses
+// that cannot be found are not cached.
+type Cache struct {
+	Geocoder Geocoder
+	Client   *sqlx.DB
+	TTL      time.Duration
+}
+
+// NewCache returns a geocoder that caches the results of the provided geocoder.
+func NewCache(geocoder Geocoder, client *sqlx.DB, ttl time.Duration) *Cache {
+	return &Cache{geocoder, client, ttl}
+}
+
+// Geocode implements the Geocoder interface.
+func (c *Cache) Geocode(address Address) (Result, error) {
+	return c.cached(Normalize(address), func() (Result, error) {
+		return c.Geocoder.Geocode(address)
+	})
+}
+
+// Reverse implements the Geocoder interface. Locations are cached to five decimal places,
+// which is roughly one meter.
+func (c *Cache) Reverse(lon, lat float64) (Result, error) {
+	return c.cached(fmt.Sprintf("reverse|%.5f|%.5f", lon, lat), func() (Result, error) {
+		return c.Geocoder.Reverse(lon, lat)
+	})
+}
+
+// Autocomplete implements the Geocoder interface. Suggestions are not cached.
+func (c *Cache) Autocomplete(query string, limit int) ([]Result, error) {
+	return c.Geocoder.Autocomplete(query, limit)
+}
+
+func (c *Cache) cached(key string, lookup func() (Result, error)) (Result, error) {
+	cached := struct {
+		Result string
+	}{}
+	err := c.Client.Get(&cached, "SELECT result FROM GeocodeCache WHERE key = $1 AND createdAt > $2;", key, time.Now().Add(-c.TTL))
+	if err == nil {
+		result := Result{}
+		if err := json.Unmarshal([]byte(cached.Result), &result); err == nil {
+			return result, nil
+		}
+	} else if err != sql.ErrNoRows {
+		return Result{}, err
+	}
+
+	result, err := lookup()
+	if err != nil {
+		return Result{}, err
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return Result{}, err
+	}
+	_, err = c.Client.Exec(`
+		INSERT INTO GeocodeCache (key, result, createdAt) VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET result = EXCLUDED.result, createdAt = EXCLUDED.createdAt;
+	`, key, string(b))
+	return result, err
+}
@@ -0,0 +1,14 @@
+package geocoder
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNotFound is returned when a provider cannot locate an address.
+	ErrNotFound = errors.New("address not found")
+	// ErrRateLimited is returned when a request would exceed the provider rate limit.
+	ErrRateLimited = errors.New("too many geocoding requests")
+
+	errGazetteerColumnsRequired = errors.New("gazetteer lon and lat columns required")
+)
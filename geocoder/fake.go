@@ -0,0 +1,91 @@
+package geocoder
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fake is a geocoder for tests. Results are keyed by normalized address, and addresses
+// without a result are not found. When Err is set, it is returned from every call.
+// Only forward lookups are recorded in Calls.
+type Fake struct {
+	Results map[string]Result
+	Err     error
+
+	mu    sync.Mutex
+	calls []Address
+}
+
+// NewFake returns a fake geocoder that knows the provided addresses.
+func NewFake(results map[Address]Result) *Fake {
+	f := &Fake{Results: map[string]Result{}}
+	for address, result := range results {
+		f.Results[Normalize(address)] = result
+	}
+	return f
+}
+
+// Geocode implements the Geocoder interface.
+func (f *Fake) Geocode(address Address) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, address)
+	if f.Err != nil {
+		return Result{}, f.Err
+	}
+	if result, ok := f.Results[Normalize(address)]; ok {
+		return result, nil
+	}
+	return Result{}, ErrNotFound
+}
+
+// Reverse implements the Geocoder interface with the known result at exactly the location.
+func (f *Fake) Reverse(lon, lat float64) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return Result{}, f.Err
+	}
+	for _, result := range f.Results {
+		if result.Lon == lon && result.Lat == lat {
+			return result, nil
+		}
+	}
+	return Result{}, ErrNotFound
+}
+
+// Autocomplete implements the Geocoder interface with the known results whose normalized
+// address contains the normalized query, ordered by address.
+func (f *Fake) Autocomplete(query string, limit int) ([]Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	keys := []string{}
+	for key := range f.Results {
+		if strings.Contains(key, normalize(query)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	results := []Result{}
+	for _, key := range keys {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, f.Results[key])
+	}
+	return results, nil
+}
+
+// Calls returns every address that has been geocoded.
+func (f *Fake) Calls() []Address {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Address{}, f.calls...)
+}
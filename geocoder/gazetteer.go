@@ -0,0 +1,172 @@
+package geocoder
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jteppinette/peragrin-api/geometry"
+)
+
+// maxReverseDistance is the furthest, in meters, that a place may be from a reverse lookup.
+const maxReverseDistance = 1000
+
+// Gazetteer is an offline geocoder backed by a list of known places. Full addresses
+// are matched first, followed by postal codes and then cities, each with a lower confidence.
+type Gazetteer struct {
+	places    []Result
+	addresses map[string]Result
+	zips      map[string]Result
+	cities    map[string]Result
+}
+
+// NewGazetteer reads a gazetteer from a csv file. The first record must be a header
+// naming the columns street, city, state, country, zip, lon, and lat. Only lon and lat
+// are required.
+func NewGazetteer(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadGazetteer(f)
+}
+
+// ReadGazetteer reads a gazetteer in the format described by NewGazetteer.
+func ReadGazetteer(reader io.Reader) (*Gazetteer, error) {
+	r := csv.NewReader(reader)
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["lon"]; !ok {
+		return nil, errGazetteerColumnsRequired
+	}
+	if _, ok := columns["lat"]; !ok {
+		return nil, errGazetteerColumnsRequired
+	}
+
+	g := &Gazetteer{[]Result{}, map[string]Result{}, map[string]Result{}, map[string]Result{}}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		result := Result{Source: "gazetteer"}
+		if result.Lon, err = strconv.ParseFloat(get("lon"), 64); err != nil {
+			return nil, err
+		}
+		if result.Lat, err = strconv.ParseFloat(get("lat"), 64); err != nil {
+			return nil, err
+		}
+		result.Address = Address{get("street"), get("city"), get("state"), get("country"), get("zip")}
+
+		if result.Address.Street != "" {
+			result.Confidence = 1
+			g.addresses[Normalize(result.Address)] = result
+			g.places = append(g.places, result)
+			continue
+		}
+		if result.Address.City != "" {
+			result.Confidence = 0.4
+			g.cities[cityKey(result.Address)] = result
+		}
+		if result.Address.Zip != "" {
+			result.Confidence = 0.6
+			g.zips[zipKey(result.Address)] = result
+		}
+		g.places = append(g.places, result)
+	}
+	return g, nil
+}
+
+func zipKey(a Address) string {
+	return normalize(a.Country) + "|" + normalize(a.Zip)
+}
+
+func cityKey(a Address) string {
+	return normalize(a.Country) + "|" + normalize(a.State) + "|" + normalize(a.City)
+}
+
+// Geocode implements the Geocoder interface.
+func (g *Gazetteer) Geocode(address Address) (Result, error) {
+	if result, ok := g.addresses[Normalize(address)]; ok {
+		return result, nil
+	}
+	if result, ok := g.zips[zipKey(address)]; ok && address.Zip != "" {
+		return result, nil
+	}
+	if result, ok := g.cities[cityKey(address)]; ok && address.City != "" {
+		return result, nil
+	}
+	return Result{}, ErrNotFound
+}
+
+// Reverse implements the Geocoder interface with the nearest place within maxReverseDistance.
+func (g *Gazetteer) Reverse(lon, lat float64) (Result, error) {
+	nearest, distance := Result{}, math.Inf(1)
+	for _, place := range g.places {
+		if d := geometry.Distance(geometry.Point{lon, lat}, geometry.Point{place.Lon, place.Lat}); d < distance {
+			nearest, distance = place, d
+		}
+	}
+	if distance > maxReverseDistance {
+		return Result{}, ErrNotFound
+	}
+	return nearest, nil
+}
+
+// Autocomplete implements the Geocoder interface. Places match when every word of the
+// query begins a word of the place's address, and they are ranked by confidence.
+func (g *Gazetteer) Autocomplete(query string, limit int) ([]Result, error) {
+	words := strings.Fields(normalize(query))
+	results := []Result{}
+	for _, place := range g.places {
+		a := place.Address
+		fields := strings.Fields(normalize(strings.Join([]string{a.Street, a.City, a.State, a.Country, a.Zip}, " ")))
+		if len(words) > 0 && matchesPrefixes(words, fields) {
+			results = append(results, place)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Confidence > results[j].Confidence })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func matchesPrefixes(words, fields []string) bool {
+	for _, w := range words {
+		found := false
+		for _, f := range fields {
+			if strings.HasPrefix(f, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+// Package geocoder converts addresses into coordinates through interchangeable providers.
+package geocoder
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Address is a physical location in the world as understood by geocoding providers.
+type Address struct {
+	Street  string `json:"street"`
+	City    string `json:"city"`
+	State   string `json:"state"`
+	Country string `json:"country"`
+	Zip     string `json:"zip"`
+}
+
+// Result is the location of an address. Confidence is between 0 and 1, and Source
+// names the provider that resolved the address. Address is the provider's normalized
+// form of the address when it returns one.
+type Result struct {
+	Lon        float64 `json:"lon"`
+	Lat        float64 `json:"lat"`
+	Confidence float64 `json:"confidence"`
+	Source     string  `json:"source"`
+	Address    Address `json:"address"`
+}
+
+// Geocoder represents a provider that can find the location of an address.
+type Geocoder interface {
+	// Geocode returns the location of the address, or ErrNotFound if it could not be located.
+	Geocode(address Address) (Result, error)
+
+	// Reverse returns the address at the location, or ErrNotFound if there is none nearby.
+	Reverse(lon, lat float64) (Result, error)
+
+	// Autocomplete returns at most limit addresses that match the partial query,
+	// with the best matches first.
+	Autocomplete(query string, limit int) ([]Result, error)
+}
+
+// Normalize returns a canonical form of the address that is suitable as a cache key.
+// Letters are lowercased, punctuation is removed, and runs of whitespace are collapsed.
+func Normalize(address Address) string {
+	parts := []string{address.Street, address.City, address.State, address.Country, address.Zip}
+	for i, part := range parts {
+		parts[i] = normalize(part)
+	}
+	return strings.Join(parts, "|")
+}
+
+func normalize(value string) string {
+	fields := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(fields, " ")
+}
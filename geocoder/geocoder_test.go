@@ -0,0 +1,118 @@
+package geocoder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	a := Address{Street: " 123  Main St. ", City: "Richmond", State: "VA", Country: "USA", Zip: "23220"}
+	b := Address{Street: "123 main st", City: "RICHMOND", State: "va", Country: "usa", Zip: "23220"}
+	if Normalize(a) != Normalize(b) {
+		t.Errorf("expected %q to equal %q", Normalize(a), Normalize(b))
+	}
+}
+
+func TestNominatim(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search.php" || r.URL.Query().Get("street") != "1 Main St & 2nd" || r.URL.Query().Get("key") != "secret" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		if r.URL.Query().Get("city") == "Nowhere" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		if r.URL.Query().Get("city") == "Broken" {
+			w.Write([]byte(`[{"lon": "west", "lat": "1"}]`))
+			return
+		}
+		w.Write([]byte(`[{"lon": "-77.43", "lat": "37.54", "importance": 0.8, "address": {"house_number": "1", "road": "Main Street", "town": "Richmond", "postcode": "23220"}}]`))
+	}))
+	defer server.Close()
+
+	n := NewNominatim(server.URL, "secret", "test", time.Second)
+	n.Suffix = ".php"
+
+	result, err := n.Geocode(Address{Street: "1 Main St & 2nd", City: "Richmond"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Lon != -77.43 || result.Lat != 37.54 || result.Confidence != 0.8 || result.Source != "test" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if result.Address != (Address{Street: "1 Main Street", City: "Richmond", Zip: "23220"}) {
+		t.Errorf("unexpected address: %+v", result.Address)
+	}
+
+	if _, err := n.Geocode(Address{Street: "1 Main St & 2nd", City: "Nowhere"}); err != ErrNotFound {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if _, err := n.Geocode(Address{Street: "1 Main St & 2nd", City: "Broken"}); err == nil {
+		t.Error("expected invalid coordinates to be an error")
+	}
+}
+
+func TestGazetteer(t *testing.T) {
+	g, err := ReadGazetteer(strings.NewReader(strings.Join([]string{
+		"street,city,state,country,zip,lon,lat",
+		"1 Main St,Richmond,VA,US,23220,-77.1,37.1",
+		",,,US,23221,-77.2,37.2",
+		",Richmond,VA,US,,-77.3,37.3",
+		",Petersburg,VA,US,23803,-77.4,37.2",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		address    Address
+		lon        float64
+		confidence float64
+	}{
+		{Address{"1 main st.", "richmond", "va", "us", "23220"}, -77.1, 1},
+		{Address{"9 Side St", "Richmond", "VA", "US", "23221"}, -77.2, 0.6},
+		{Address{"9 Side St", "Richmond", "VA", "US", "23299"}, -77.3, 0.4},
+		{Address{"9 Side St", "Hopewell", "VA", "US", "23803"}, -77.4, 0.6},
+		{Address{"9 Side St", "Petersburg", "VA", "US", "23899"}, -77.4, 0.4},
+	}
+	for _, test := range tests {
+		result, err := g.Geocode(test.address)
+		if err != nil || result.Lon != test.lon || result.Confidence != test.confidence {
+			t.Errorf("unexpected result for %+v: %+v, %v", test.address, result, err)
+		}
+	}
+	if _, err := g.Geocode(Address{City: "Norfolk", Country: "US"}); err != ErrNotFound {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	if result, err := g.Reverse(-77.1001, 37.1001); err != nil || result.Address.Street != "1 Main St" {
+		t.Errorf("expected the nearest address, got %+v, %v", result, err)
+	}
+	if _, err := g.Reverse(0, 0); err != ErrNotFound {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	results, err := g.Autocomplete("rich va", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Confidence != 1 || results[1].Confidence != 0.4 {
+		t.Errorf("unexpected suggestions: %+v", results)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ticks := make(chan time.Time, 1)
+	l := &Limiter{&Fake{}, ticks}
+
+	if _, err := l.Autocomplete("main", 5); err != ErrRateLimited {
+		t.Errorf("expected rate limited, got %v", err)
+	}
+	ticks <- time.Now()
+	if _, err := l.Autocomplete("main", 5); err == ErrRateLimited {
+		t.Errorf("expected the request to be sent, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package geocoder
+
+import (
+	"time"
+)
+
+// Limiter spaces out requests to another geocoder, so that provider rate limits are
+// respected. Lookups wait their turn rather than failing, while autocomplete requests, which
+// are sent as users type, fail with ErrRateLimited when no request may be sent right away.
+// Limiter is safe for concurrent use.
+type Limiter struct {
+	Geocoder Geocoder
+
+	ticks <-chan time.Time
+}
+
+// NewLimiter returns a geocoder that sends at most perSecond requests each second to the
+// provided geocoder.
+func NewLimiter(geocoder Geocoder, perSecond int) *Limiter {
+	return &Limiter{geocoder, time.NewTicker(time.Second / time.Duration(perSecond)).C}
+}
+
+// Geocode implements the Geocoder interface.
+func (l *Limiter) Geocode(address Address) (Result, error) {
+	<-l.ticks
+	return l.Geocoder.Geocode(address)
+}
+
+// Reverse implements the Geocoder interface.
+func (l *Limiter) Reverse(lon, lat float64) (Result, error) {
+	<-l.ticks
+	return l.Geocoder.Reverse(lon, lat)
+}
+
+// Autocomplete implements the Geocoder interface.
+func (l *Limiter) Autocomplete(query string, limit int) ([]Result, error) {
+	select {
+	case <-l.ticks:
+	default:
+		return nil, ErrRateLimited
+	}
+	return l.Geocoder.Autocomplete(query, limit)
+}
@@ -0,0 +1,193 @@
+package geocoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// LocationIQURL is the LocationIQ search endpoint, which is compatible with Nominatim.
+	LocationIQURL = "https://us1.locationiq.com/v1"
+
+	userAgent = "peragrin-api"
+)
+
+// Nominatim geocodes addresses with a Nominatim compatible search api, such as
+// LocationIQ or a self hosted Nominatim server. Suffix is appended to endpoint
+// paths for providers that serve them as scripts, e.g. /search.php.
+type Nominatim struct {
+	URL    string
+	Key    string
+	Source string
+	Suffix string
+	Client *http.Client
+}
+
+// NewNominatim returns a geocoder that uses the Nominatim compatible api at the provided url.
+// The key is sent with every request when it is not empty. Requests fail after the timeout.
+func NewNominatim(url, key, source string, timeout time.Duration) *Nominatim {
+	return &Nominatim{URL: strings.TrimRight(url, "/"), Key: key, Source: source, Client: &http.Client{Timeout: timeout}}
+}
+
+// NewLocationIQ returns a geocoder that uses the LocationIQ api.
+func NewLocationIQ(key string, timeout time.Duration) *Nominatim {
+	n := NewNominatim(LocationIQURL, key, "locationiq", timeout)
+	n.Suffix = ".php"
+	return n
+}
+
+// nominatimPlace is a single search result.
+type nominatimPlace struct {
+	Lon        string  `json:"lon"`
+	Lat        string  `json:"lat"`
+	Importance float64 `json:"importance"`
+	Address    struct {
+		HouseNumber string `json:"house_number"`
+		Road        string `json:"road"`
+		City        string `json:"city"`
+		Town        string `json:"town"`
+		Village     string `json:"village"`
+		State       string `json:"state"`
+		Country     string `json:"country"`
+		Postcode    string `json:"postcode"`
+	} `json:"address"`
+}
+
+func (p nominatimPlace) result(source string) (Result, error) {
+	lon, err := strconv.ParseFloat(p.Lon, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid lon: %q", p.Lon)
+	}
+	lat, err := strconv.ParseFloat(p.Lat, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid lat: %q", p.Lat)
+	}
+
+	a := p.Address
+	city := a.City
+	if city == "" {
+		city = a.Town
+	}
+	if city == "" {
+		city = a.Village
+	}
+	return Result{
+		Lon:        lon,
+		Lat:        lat,
+		Confidence: math.Max(0, math.Min(1, p.Importance)),
+		Source:     source,
+		Address: Address{
+			Street:  strings.TrimSpace(a.HouseNumber + " " + a.Road),
+			City:    city,
+			State:   a.State,
+			Country: a.Country,
+			Zip:     a.Postcode,
+		},
+	}, nil
+}
+
+// Geocode implements the Geocoder interface.
+func (n *Nominatim) Geocode(address Address) (Result, error) {
+	query := url.Values{}
+	query.Set("format", "json")
+	query.Set("limit", "1")
+	query.Set("addressdetails", "1")
+	for key, value := range map[string]string{
+		"street": address.Street, "city": address.City, "state": address.State, "country": address.Country, "postalcode": address.Zip,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	places := []nominatimPlace{}
+	if err := n.get("/search", query, &places); err != nil {
+		return Result{}, err
+	}
+	if len(places) == 0 {
+		return Result{}, ErrNotFound
+	}
+	return places[0].result(n.Source)
+}
+
+// Reverse implements the Geocoder interface.
+func (n *Nominatim) Reverse(lon, lat float64) (Result, error) {
+	query := url.Values{}
+	query.Set("format", "json")
+	query.Set("addressdetails", "1")
+	query.Set("lon", strconv.FormatFloat(lon, 'f', -1, 64))
+	query.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+
+	place := struct {
+		nominatimPlace
+		Error string `json:"error"`
+	}{}
+	if err := n.get("/reverse", query, &place); err != nil {
+		return Result{}, err
+	}
+	if place.Error != "" || place.Lon == "" {
+		return Result{}, ErrNotFound
+	}
+	return place.result(n.Source)
+}
+
+// Autocomplete implements the Geocoder interface with a free form search, which
+// is ranked by the provider.
+func (n *Nominatim) Autocomplete(q string, limit int) ([]Result, error) {
+	query := url.Values{}
+	query.Set("format", "json")
+	query.Set("addressdetails", "1")
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("q", q)
+
+	places := []nominatimPlace{}
+	if err := n.get("/search", query, &places); err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	results := []Result{}
+	for _, place := range places {
+		result, err := place.result(n.Source)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// get requests the path of the api and decodes the JSON response into v.
+func (n *Nominatim) get(path string, query url.Values, v interface{}) error {
+	if n.Key != "" {
+		query.Set("key", n.Key)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, n.URL+path+n.Suffix+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	r, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	switch {
+	case r.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case r.StatusCode != http.StatusOK:
+		return fmt.Errorf("expected response to be HTTP 200, received %s", r.Status)
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package geometry
+
+import (
+	"math"
+)
+
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6371008.8
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+// Distance returns the great circle distance in meters between two points.
+func Distance(a, b Point) float64 {
+	dLat, dLon := radians(b.Lat()-a.Lat()), radians(b.Lon()-a.Lon())
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(radians(a.Lat()))*math.Cos(radians(b.Lat()))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Bearing returns the initial bearing in degrees, clockwise from north, of the great
+// circle path from a to b. The result is between 0 and 360.
+func Bearing(a, b Point) float64 {
+	dLon := radians(b.Lon() - a.Lon())
+	y := math.Sin(dLon) * math.Cos(radians(b.Lat()))
+	x := math.Cos(radians(a.Lat()))*math.Sin(radians(b.Lat())) - math.Sin(radians(a.Lat()))*math.Cos(radians(b.Lat()))*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
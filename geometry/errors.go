@@ -0,0 +1,21 @@
+package geometry
+
+import (
+	"errors"
+)
+
+var (
+	errPolygonRequired       = errors.New("polygon or multipolygon required")
+	errRingTooShort          = errors.New("linear ring must have at least four positions")
+	errRingNotClosed         = errors.New("linear ring must be closed")
+	errCoordinatesOutOfRange = errors.New("coordinates out of range")
+	errGeoJSONObjectRequired = errors.New("geojson object required")
+	errTypeRequired          = errors.New("type required")
+	errFeatureRequired       = errors.New("feature required")
+	errPropertiesRequired    = errors.New("properties required")
+	errGeometryRequired      = errors.New("geometry required")
+	errObjectRequired        = errors.New("object or null required")
+	errArrayRequired         = errors.New("array required")
+	errPositionRequired      = errors.New("position must contain two or three numbers")
+	errInvalidBounds         = errors.New("bounds must be minLon,minLat,maxLon,maxLat")
+)
@@ -0,0 +1,207 @@
+package geometry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// ValidateGeoJSON strictly validates a GeoJSON document as defined by RFC 7946. The document
+// may be a FeatureCollection, a Feature, or a bare Geometry. Errors describe the location of
+// the first problem that was found, e.g. "features[2].geometry.coordinates[0]: ring not closed".
+func ValidateGeoJSON(data []byte) error {
+	object := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return errGeoJSONObjectRequired
+	}
+	return validateObject("", object)
+}
+
+func validateObject(path string, object map[string]json.RawMessage) error {
+	t, err := objectType(path, object)
+	if err != nil {
+		return err
+	}
+	switch t {
+	case "FeatureCollection":
+		features := []map[string]json.RawMessage{}
+		if err := json.Unmarshal(object["features"], &features); err != nil || object["features"] == nil {
+			return pathError(join(path, "features"), errArrayRequired)
+		}
+		for i, feature := range features {
+			p := fmt.Sprintf("%s[%d]", join(path, "features"), i)
+			if ft, err := objectType(p, feature); err != nil {
+				return err
+			} else if ft != "Feature" {
+				return pathError(join(p, "type"), errFeatureRequired)
+			}
+			if err := validateObject(p, feature); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "Feature":
+		if properties, ok := object["properties"]; !ok {
+			return pathError(join(path, "properties"), errPropertiesRequired)
+		} else if string(properties) != "null" {
+			if err := json.Unmarshal(properties, &map[string]interface{}{}); err != nil {
+				return pathError(join(path, "properties"), errObjectRequired)
+			}
+		}
+		geometry, ok := object["geometry"]
+		if !ok {
+			return pathError(join(path, "geometry"), errGeometryRequired)
+		}
+		if string(geometry) == "null" {
+			return nil
+		}
+		g := map[string]json.RawMessage{}
+		if err := json.Unmarshal(geometry, &g); err != nil {
+			return pathError(join(path, "geometry"), errObjectRequired)
+		}
+		return validateGeometry(join(path, "geometry"), g)
+	default:
+		return validateGeometry(path, object)
+	}
+}
+
+func validateGeometry(path string, object map[string]json.RawMessage) error {
+	t, err := objectType(path, object)
+	if err != nil {
+		return err
+	}
+
+	if t == "GeometryCollection" {
+		geometries := []map[string]json.RawMessage{}
+		if err := json.Unmarshal(object["geometries"], &geometries); err != nil || object["geometries"] == nil {
+			return pathError(join(path, "geometries"), errArrayRequired)
+		}
+		for i, g := range geometries {
+			if err := validateGeometry(fmt.Sprintf("%s[%d]", join(path, "geometries"), i), g); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	typePath := join(path, "type")
+	path = join(path, "coordinates")
+	coordinates := object["coordinates"]
+	if coordinates == nil {
+		return pathError(path, errArrayRequired)
+	}
+
+	switch t {
+	case "Point":
+		var p []float64
+		if err := json.Unmarshal(coordinates, &p); err != nil {
+			return pathError(path, errPositionRequired)
+		}
+		return validatePosition(path, p)
+	case "MultiPoint":
+		var ps [][]float64
+		if err := json.Unmarshal(coordinates, &ps); err != nil {
+			return pathError(path, errPositionRequired)
+		}
+		return validatePositions(path, ps, 0)
+	case "LineString":
+		var ps [][]float64
+		if err := json.Unmarshal(coordinates, &ps); err != nil {
+			return pathError(path, errPositionRequired)
+		}
+		return validatePositions(path, ps, 2)
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(coordinates, &lines); err != nil {
+			return pathError(path, errPositionRequired)
+		}
+		for i, ps := range lines {
+			if err := validatePositions(fmt.Sprintf("%s[%d]", path, i), ps, 2); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(coordinates, &rings); err != nil {
+			return pathError(path, errPositionRequired)
+		}
+		return validateRings(path, rings)
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(coordinates, &polygons); err != nil {
+			return pathError(path, errPositionRequired)
+		}
+		for i, rings := range polygons {
+			if err := validateRings(fmt.Sprintf("%s[%d]", path, i), rings); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return pathError(typePath, fmt.Errorf("unsupported type %q", t))
+	}
+}
+
+func validateRings(path string, rings [][][]float64) error {
+	if len(rings) == 0 {
+		return pathError(path, errPolygonRequired)
+	}
+	for i, ring := range rings {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		if err := validatePositions(p, ring, 4); err != nil {
+			return err
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return pathError(p, errRingNotClosed)
+		}
+	}
+	return nil
+}
+
+func validatePositions(path string, positions [][]float64, minimum int) error {
+	if len(positions) < minimum {
+		return pathError(path, fmt.Errorf("at least %d positions required", minimum))
+	}
+	for i, p := range positions {
+		if err := validatePosition(fmt.Sprintf("%s[%d]", path, i), p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePosition(path string, p []float64) error {
+	if len(p) < 2 || len(p) > 3 {
+		return pathError(path, errPositionRequired)
+	}
+	for _, v := range p {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return pathError(path, errCoordinatesOutOfRange)
+		}
+	}
+	if err := (Point{p[0], p[1]}).Validate(); err != nil {
+		return pathError(path, err)
+	}
+	return nil
+}
+
+func objectType(path string, object map[string]json.RawMessage) (string, error) {
+	var t string
+	if err := json.Unmarshal(object["type"], &t); err != nil || t == "" {
+		return "", pathError(join(path, "type"), errTypeRequired)
+	}
+	return t, nil
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathError(path string, err error) error {
+	return fmt.Errorf("%s: %s", path, err.Error())
+}
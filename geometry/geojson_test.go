@@ -0,0 +1,36 @@
+package geometry
+
+import (
+	"testing"
+)
+
+func TestValidateGeoJSON(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{`{"type": "Point", "coordinates": [1, 2]}`, ""},
+		{`{"type": "Feature", "properties": null, "geometry": null}`, ""},
+		{`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"name": "a"}, "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}},
+			{"type": "Feature", "properties": {}, "geometry": {"type": "GeometryCollection", "geometries": [{"type": "MultiPoint", "coordinates": [[0, 0, 5]]}]}}
+		]}`, ""},
+		{`[]`, "geojson object required"},
+		{`{"type": "Circle", "coordinates": [1, 2]}`, `type: unsupported type "Circle"`},
+		{`{"type": "Point", "coordinates": [181, 2]}`, "coordinates: coordinates out of range"},
+		{`{"type": "LineString", "coordinates": [[0, 0]]}`, "coordinates: at least 2 positions required"},
+		{`{"type": "Feature", "geometry": null}`, "properties: properties required"},
+		{`{"type": "FeatureCollection", "features": [{"type": "Point", "coordinates": [0, 0]}]}`, "features[0].type: feature required"},
+		{`{"type": "FeatureCollection", "features": [{"type": "Feature", "properties": null, "geometry": {
+			"type": "MultiPolygon", "coordinates": [[[[0, 0], [1, 0], [1, 1], [0, 1]]]]
+		}}]}`, "features[0].geometry.coordinates[0][0]: linear ring must be closed"},
+	}
+	for _, test := range tests {
+		err := ValidateGeoJSON([]byte(test.data))
+		if test.expected == "" && err != nil {
+			t.Errorf("expected %s to be valid, got %s", test.data, err)
+		} else if test.expected != "" && (err == nil || err.Error() != test.expected) {
+			t.Errorf("expected error %q, got %v", test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package geometry
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Point is a position in [longitude, latitude] order as defined by GeoJSON.
+type Point [2]float64
+
+// Lon returns the longitude of the point.
+func (p Point) Lon() float64 { return p[0] }
+
+// Lat returns the latitude of the point.
+func (p Point) Lat() float64 { return p[1] }
+
+// maxMercatorLat is the latitude at which the web mercator projection is cut off.
+const maxMercatorLat = 85.0511287798
+
+// Mercator projects the point into web mercator pixel coordinates for a map made of
+// 256 pixel tiles at the provided zoom. The origin is the top left corner of the map
+// and y increases downward.
+func (p Point) Mercator(zoom int) (float64, float64) {
+	n := 256 * math.Pow(2, float64(zoom))
+	lat := math.Max(-maxMercatorLat, math.Min(maxMercatorLat, p.Lat())) * math.Pi / 180
+	return (p.Lon() + 180) / 360 * n, (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * n
+}
+
+// Ring is a closed line string. The first and last points are identical.
+type Ring []Point
+
+// Polygon is an exterior ring followed by any number of interior rings (holes).
+type Polygon []Ring
+
+// MultiPolygon is a set of polygons.
+type MultiPolygon []Polygon
+
+// Bounds is an axis aligned bounding box.
+type Bounds struct {
+	MinLon float64 `json:"minLon"`
+	MinLat float64 `json:"minLat"`
+	MaxLon float64 `json:"maxLon"`
+	MaxLat float64 `json:"maxLat"`
+}
+
+// EmptyBounds returns bounds that contain nothing and can be extended.
+func EmptyBounds() Bounds {
+	return Bounds{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+}
+
+// ParseBounds reads bounds written as "minLon,minLat,maxLon,maxLat", such as the
+// bbox query parameter used by web map clients.
+func ParseBounds(value string) (Bounds, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return Bounds{}, errInvalidBounds
+	}
+	values := [4]float64{}
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return Bounds{}, errInvalidBounds
+		}
+		values[i] = v
+	}
+	b := Bounds{values[0], values[1], values[2], values[3]}
+	if b.IsEmpty() || (Point{b.MinLon, b.MinLat}).Validate() != nil || (Point{b.MaxLon, b.MaxLat}).Validate() != nil {
+		return Bounds{}, errInvalidBounds
+	}
+	return b, nil
+}
+
+// IsEmpty reports whether the bounds contain no points.
+func (b Bounds) IsEmpty() bool {
+	return b.MinLon > b.MaxLon || b.MinLat > b.MaxLat
+}
+
+// Extend grows the bounds to include the provided point.
+func (b Bounds) Extend(p Point) Bounds {
+	return Bounds{math.Min(b.MinLon, p.Lon()), math.Min(b.MinLat, p.Lat()), math.Max(b.MaxLon, p.Lon()), math.Max(b.MaxLat, p.Lat())}
+}
+
+// Union returns the bounds that contain both b and o.
+func (b Bounds) Union(o Bounds) Bounds {
+	return Bounds{math.Min(b.MinLon, o.MinLon), math.Min(b.MinLat, o.MinLat), math.Max(b.MaxLon, o.MaxLon), math.Max(b.MaxLat, o.MaxLat)}
+}
+
+// Contains reports whether the point is inside of or on the edge of the bounds.
+func (b Bounds) Contains(p Point) bool {
+	return p.Lon() >= b.MinLon && p.Lon() <= b.MaxLon && p.Lat() >= b.MinLat && p.Lat() <= b.MaxLat
+}
+
+// Intersects reports whether the two bounds overlap.
+func (b Bounds) Intersects(o Bounds) bool {
+	return b.MinLon <= o.MaxLon && o.MinLon <= b.MaxLon && b.MinLat <= o.MaxLat && o.MinLat <= b.MaxLat
+}
+
+// Bounds returns the bounding box of the ring.
+func (r Ring) Bounds() Bounds {
+	b := EmptyBounds()
+	for _, p := range r {
+		b = b.Extend(p)
+	}
+	return b
+}
+
+// Contains reports whether the point is inside of the ring using the even-odd rule.
+func (r Ring) Contains(p Point) bool {
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		a, b := r[i], r[j]
+		if (a.Lat() > p.Lat()) != (b.Lat() > p.Lat()) &&
+			p.Lon() < (b.Lon()-a.Lon())*(p.Lat()-a.Lat())/(b.Lat()-a.Lat())+a.Lon() {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Bounds returns the bounding box of the polygon's exterior ring.
+func (p Polygon) Bounds() Bounds {
+	if len(p) == 0 {
+		return EmptyBounds()
+	}
+	return p[0].Bounds()
+}
+
+// Contains reports whether the point is inside of the exterior ring and outside of every hole.
+func (p Polygon) Contains(point Point) bool {
+	if len(p) == 0 || !p[0].Contains(point) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if hole.Contains(point) {
+			return false
+		}
+	}
+	return true
+}
+
+// Bounds returns the bounding box of every polygon.
+func (mp MultiPolygon) Bounds() Bounds {
+	b := EmptyBounds()
+	for _, p := range mp {
+		b = b.Union(p.Bounds())
+	}
+	return b
+}
+
+// Contains reports whether the point is inside of any polygon.
+func (mp MultiPolygon) Contains(point Point) bool {
+	for _, p := range mp {
+		if p.Contains(point) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMultiPolygon reads a GeoJSON Polygon or MultiPolygon geometry. Polygons are
+// returned as a MultiPolygon containing a single polygon.
+func ParseMultiPolygon(data []byte) (MultiPolygon, error) {
+	geometry := struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}{}
+	if err := json.Unmarshal(data, &geometry); err != nil {
+		return nil, err
+	}
+
+	var mp MultiPolygon
+	switch geometry.Type {
+	case "Polygon":
+		p := Polygon{}
+		if err := json.Unmarshal(geometry.Coordinates, &p); err != nil {
+			return nil, err
+		}
+		mp = MultiPolygon{p}
+	case "MultiPolygon":
+		if err := json.Unmarshal(geometry.Coordinates, &mp); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errPolygonRequired
+	}
+
+	if len(mp) == 0 {
+		return nil, errPolygonRequired
+	}
+	for _, p := range mp {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return mp, nil
+}
+
+// Validate checks that every ring of the polygon is closed, has at least four
+// positions, and only contains valid longitudes and latitudes.
+func (p Polygon) Validate() error {
+	if len(p) == 0 {
+		return errPolygonRequired
+	}
+	for _, r := range p {
+		if len(r) < 4 {
+			return errRingTooShort
+		}
+		if r[0] != r[len(r)-1] {
+			return errRingNotClosed
+		}
+		for _, point := range r {
+			if err := point.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks that the point has a valid longitude and latitude.
+func (p Point) Validate() error {
+	if math.IsNaN(p.Lon()) || math.IsNaN(p.Lat()) || p.Lon() < -180 || p.Lon() > 180 || p.Lat() < -90 || p.Lat() > 90 {
+		return errCoordinatesOutOfRange
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMultiPolygonContains(t *testing.T) {
+	mp, err := ParseMultiPolygon([]byte(`{
+		"type": "Polygon",
+		"coordinates": [
+			[[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]],
+			[[4, 4], [6, 4], [6, 6], [4, 6], [4, 4]]
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		point    Point
+		expected bool
+	}{
+		{Point{1, 1}, true},
+		{Point{9, 5}, true},
+		{Point{5, 5}, false},
+		{Point{11, 5}, false},
+		{Point{-1, -1}, false},
+	}
+	for _, test := range tests {
+		if actual := mp.Contains(test.point); actual != test.expected {
+			t.Errorf("expected contains %v to be %t, got %t", test.point, test.expected, actual)
+		}
+	}
+
+	if b := mp.Bounds(); b != (Bounds{0, 0, 10, 10}) {
+		t.Errorf("unexpected bounds: %+v", b)
+	}
+}
+
+func TestParseMultiPolygon(t *testing.T) {
+	tests := []struct {
+		data string
+		err  error
+	}{
+		{`{"type": "MultiPolygon", "coordinates": [[[[0, 0], [1, 0], [1, 1], [0, 0]]]]}`, nil},
+		{`{"type": "Point", "coordinates": [0, 0]}`, errPolygonRequired},
+		{`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 1]]]}`, errRingNotClosed},
+		{`{"type": "Polygon", "coordinates": [[[0, 0], [1, 1], [0, 0]]]}`, errRingTooShort},
+		{`{"type": "Polygon", "coordinates": [[[0, 0], [181, 0], [1, 1], [0, 0]]]}`, errCoordinatesOutOfRange},
+	}
+	for _, test := range tests {
+		if _, err := ParseMultiPolygon([]byte(test.data)); err != test.err {
+			t.Errorf("expected %s to return %v, got %v", test.data, test.err, err)
+		}
+	}
+}
+
+func TestDistanceAndBearing(t *testing.T) {
+	tests := []struct {
+		a, b     Point
+		distance float64
+		bearing  float64
+	}{
+		{Point{0, 0}, Point{0, 1}, 111195, 0},
+		{Point{0, 0}, Point{1, 0}, 111195, 90},
+		{Point{0, 1}, Point{0, 0}, 111195, 180},
+		{Point{0, 0}, Point{-1, 0}, 111195, 270},
+		{Point{-77.436, 37.541}, Point{-76.286, 36.851}, 127522, 126.6},
+	}
+	for _, test := range tests {
+		if d := Distance(test.a, test.b); math.Abs(d-test.distance) > 1 {
+			t.Errorf("expected distance from %v to %v to be %f, got %f", test.a, test.b, test.distance, d)
+		}
+		if b := Bearing(test.a, test.b); math.Abs(b-test.bearing) > 0.1 {
+			t.Errorf("expected bearing from %v to %v to be %f, got %f", test.a, test.b, test.bearing, b)
+		}
+	}
+}
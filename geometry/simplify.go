@@ -0,0 +1,449 @@
+package geometry
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// ToleranceForZoom returns the simplification tolerance, in degrees, that is
+// equivalent to a single pixel of a 256 pixel web map tile at the provided zoom.
+func ToleranceForZoom(zoom int) float64 {
+	return 360 / (256 * math.Pow(2, float64(zoom)))
+}
+
+// Simplify reduces the number of points in a line using the Douglas–Peucker algorithm.
+// Points that are within tolerance of the simplified line are removed. The first and last
+// points are always kept, so closed rings remain closed.
+func Simplify(points []Point, tolerance float64) []Point {
+	if len(points) < 3 || tolerance <= 0 {
+		return points
+	}
+
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+
+	stack := [][2]int{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		span := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		index, max := 0, 0.0
+		for i := span[0] + 1; i < span[1]; i++ {
+			if d := segmentDistance(points[i], points[span[0]], points[span[1]]); d > max {
+				index, max = i, d
+			}
+		}
+		if max > tolerance {
+			keep[index] = true
+			stack = append(stack, [2]int{span[0], index}, [2]int{index, span[1]})
+		}
+	}
+
+	result := []Point{}
+	for i, p := range points {
+		if keep[i] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// segmentDistance returns the distance from p to the segment between a and b.
+func segmentDistance(p, a, b Point) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p[0]-a[0], p[1]-a[1])
+	}
+	t := ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p[0]-(a[0]+t*dx), p[1]-(a[1]+t*dy))
+}
+
+// ClipRing clips a closed ring to the bounds using the Sutherland–Hodgman algorithm.
+// The result is closed, or empty if the ring is entirely outside of the bounds.
+func ClipRing(ring []Point, b Bounds) []Point {
+	edges := []struct {
+		inside    func(Point) bool
+		intersect func(p, q Point) Point
+	}{
+		{func(p Point) bool { return p[0] >= b.MinLon }, func(p, q Point) Point { return intersectLon(p, q, b.MinLon) }},
+		{func(p Point) bool { return p[0] <= b.MaxLon }, func(p, q Point) Point { return intersectLon(p, q, b.MaxLon) }},
+		{func(p Point) bool { return p[1] >= b.MinLat }, func(p, q Point) Point { return intersectLat(p, q, b.MinLat) }},
+		{func(p Point) bool { return p[1] <= b.MaxLat }, func(p, q Point) Point { return intersectLat(p, q, b.MaxLat) }},
+	}
+
+	points := ring
+	if len(points) > 1 && points[0] == points[len(points)-1] {
+		points = points[:len(points)-1]
+	}
+	for _, edge := range edges {
+		if len(points) == 0 {
+			break
+		}
+		input := points
+		points = []Point{}
+		previous := input[len(input)-1]
+		for _, current := range input {
+			if edge.inside(current) {
+				if !edge.inside(previous) {
+					points = append(points, edge.intersect(previous, current))
+				}
+				points = append(points, current)
+			} else if edge.inside(previous) {
+				points = append(points, edge.intersect(previous, current))
+			}
+			previous = current
+		}
+	}
+
+	if len(points) < 3 {
+		return nil
+	}
+	return append(points, points[0])
+}
+
+// ClipLine clips a line to the bounds. Lines that leave and re-enter the bounds are
+// split, so any number of lines may be returned.
+func ClipLine(line []Point, b Bounds) [][]Point {
+	lines := [][]Point{}
+	current := []Point{}
+	for i := 0; i+1 < len(line); i++ {
+		p, q, ok := clipSegment(line[i], line[i+1], b)
+		if !ok {
+			if len(current) > 1 {
+				lines = append(lines, current)
+			}
+			current = []Point{}
+			continue
+		}
+		if len(current) == 0 || current[len(current)-1] != p {
+			if len(current) > 1 {
+				lines = append(lines, current)
+			}
+			current = []Point{p}
+		}
+		current = append(current, q)
+		if q != line[i+1] {
+			lines = append(lines, current)
+			current = []Point{}
+		}
+	}
+	if len(current) > 1 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// clipSegment clips the segment between p and q to the bounds using the Liang–Barsky algorithm.
+func clipSegment(p, q Point, b Bounds) (Point, Point, bool) {
+	dx, dy := q[0]-p[0], q[1]-p[1]
+	t0, t1 := 0.0, 1.0
+	for _, edge := range [][2]float64{
+		{-dx, p[0] - b.MinLon}, {dx, b.MaxLon - p[0]},
+		{-dy, p[1] - b.MinLat}, {dy, b.MaxLat - p[1]},
+	} {
+		if edge[0] == 0 {
+			if edge[1] < 0 {
+				return p, q, false
+			}
+			continue
+		}
+		t := edge[1] / edge[0]
+		if edge[0] < 0 {
+			t0 = math.Max(t0, t)
+		} else {
+			t1 = math.Min(t1, t)
+		}
+		if t0 > t1 {
+			return p, q, false
+		}
+	}
+	start, end := p, q
+	if t0 > 0 {
+		start = Point{p[0] + t0*dx, p[1] + t0*dy}
+	}
+	if t1 < 1 {
+		end = Point{p[0] + t1*dx, p[1] + t1*dy}
+	}
+	return start, end, true
+}
+
+func intersectLon(p, q Point, lon float64) Point {
+	t := (lon - p[0]) / (q[0] - p[0])
+	return Point{lon, p[1] + t*(q[1]-p[1])}
+}
+
+func intersectLat(p, q Point, lat float64) Point {
+	t := (lat - p[1]) / (q[1] - p[1])
+	return Point{p[0] + t*(q[0]-p[0]), lat}
+}
+
+// GeoJSONBounds returns the bounding box of every position in a GeoJSON document.
+// The bounds are empty if the document does not contain any geometry.
+func GeoJSONBounds(data []byte) (Bounds, error) {
+	b := EmptyBounds()
+	err := walkGeoJSON(data, func(t string, coordinates json.RawMessage) error {
+		points, err := positions(t, coordinates)
+		for _, p := range points {
+			b = b.Extend(p)
+		}
+		return err
+	})
+	return b, err
+}
+
+// TransformGeoJSON simplifies every line and ring of a GeoJSON document by the provided
+// tolerance and, when clip is not nil, clips the geometry to the clip bounds. Features and
+// geometries that are entirely outside of the bounds are removed. Positions in the result
+// only contain a longitude and latitude.
+func TransformGeoJSON(data []byte, tolerance float64, clip *Bounds) ([]byte, error) {
+	result, _, err := transformObject(data, tolerance, clip)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// walkGeoJSON calls fn with the type and coordinates of every geometry in the document.
+func walkGeoJSON(data []byte, fn func(t string, coordinates json.RawMessage) error) error {
+	object := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	var t string
+	json.Unmarshal(object["type"], &t)
+	switch t {
+	case "FeatureCollection":
+		features := []json.RawMessage{}
+		if err := json.Unmarshal(object["features"], &features); err != nil {
+			return err
+		}
+		for _, feature := range features {
+			if err := walkGeoJSON(feature, fn); err != nil {
+				return err
+			}
+		}
+	case "Feature":
+		if g := object["geometry"]; len(g) > 0 && string(g) != "null" {
+			return walkGeoJSON(g, fn)
+		}
+	case "GeometryCollection":
+		geometries := []json.RawMessage{}
+		if err := json.Unmarshal(object["geometries"], &geometries); err != nil {
+			return err
+		}
+		for _, g := range geometries {
+			if err := walkGeoJSON(g, fn); err != nil {
+				return err
+			}
+		}
+	default:
+		return fn(t, object["coordinates"])
+	}
+	return nil
+}
+
+// transformObject returns the transformed copy of a GeoJSON object, and false if
+// nothing remains of the object after clipping.
+func transformObject(data []byte, tolerance float64, clip *Bounds) (interface{}, bool, error) {
+	object := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, false, err
+	}
+	var t string
+	json.Unmarshal(object["type"], &t)
+
+	result := map[string]interface{}{}
+	for key, value := range object {
+		if key != "bbox" {
+			result[key] = value
+		}
+	}
+
+	switch t {
+	case "FeatureCollection":
+		features := []json.RawMessage{}
+		if err := json.Unmarshal(object["features"], &features); err != nil {
+			return nil, false, err
+		}
+		transformed := []interface{}{}
+		for _, feature := range features {
+			f, ok, err := transformObject(feature, tolerance, clip)
+			if err != nil {
+				return nil, false, err
+			} else if ok {
+				transformed = append(transformed, f)
+			}
+		}
+		result["features"] = transformed
+		return result, true, nil
+	case "Feature":
+		g := object["geometry"]
+		if len(g) == 0 || string(g) == "null" {
+			return result, clip == nil, nil
+		}
+		geometry, ok, err := transformObject(g, tolerance, clip)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		result["geometry"] = geometry
+		return result, true, nil
+	case "GeometryCollection":
+		geometries := []json.RawMessage{}
+		if err := json.Unmarshal(object["geometries"], &geometries); err != nil {
+			return nil, false, err
+		}
+		transformed := []interface{}{}
+		for _, g := range geometries {
+			geometry, ok, err := transformObject(g, tolerance, clip)
+			if err != nil {
+				return nil, false, err
+			} else if ok {
+				transformed = append(transformed, geometry)
+			}
+		}
+		result["geometries"] = transformed
+		return result, len(transformed) > 0, nil
+	default:
+		return transformGeometry(t, object["coordinates"], tolerance, clip)
+	}
+}
+
+// transformGeometry simplifies and clips a single geometry. Lines that are split by
+// clipping become MultiLineStrings.
+func transformGeometry(t string, coordinates json.RawMessage, tolerance float64, clip *Bounds) (interface{}, bool, error) {
+	geometry := func(t string, coordinates interface{}) map[string]interface{} {
+		return map[string]interface{}{"type": t, "coordinates": coordinates}
+	}
+	lines := func(ls [][]Point) (interface{}, bool, error) {
+		switch len(ls) {
+		case 0:
+			return nil, false, nil
+		case 1:
+			return geometry("LineString", ls[0]), true, nil
+		default:
+			return geometry("MultiLineString", ls), true, nil
+		}
+	}
+
+	switch t {
+	case "Point":
+		var p Point
+		if err := json.Unmarshal(coordinates, &p); err != nil {
+			return nil, false, err
+		}
+		return geometry(t, p), clip == nil || clip.Contains(p), nil
+	case "MultiPoint":
+		ps := []Point{}
+		if err := json.Unmarshal(coordinates, &ps); err != nil {
+			return nil, false, err
+		}
+		kept := []Point{}
+		for _, p := range ps {
+			if clip == nil || clip.Contains(p) {
+				kept = append(kept, p)
+			}
+		}
+		return geometry(t, kept), len(kept) > 0, nil
+	case "LineString":
+		ps := []Point{}
+		if err := json.Unmarshal(coordinates, &ps); err != nil {
+			return nil, false, err
+		}
+		return lines(transformLine(ps, tolerance, clip))
+	case "MultiLineString":
+		ls := [][]Point{}
+		if err := json.Unmarshal(coordinates, &ls); err != nil {
+			return nil, false, err
+		}
+		result := [][]Point{}
+		for _, l := range ls {
+			result = append(result, transformLine(l, tolerance, clip)...)
+		}
+		return lines(result)
+	case "Polygon":
+		rings := [][]Point{}
+		if err := json.Unmarshal(coordinates, &rings); err != nil {
+			return nil, false, err
+		}
+		polygon := transformPolygon(rings, tolerance, clip)
+		return geometry(t, polygon), polygon != nil, nil
+	case "MultiPolygon":
+		polygons := [][][]Point{}
+		if err := json.Unmarshal(coordinates, &polygons); err != nil {
+			return nil, false, err
+		}
+		result := [][][]Point{}
+		for _, rings := range polygons {
+			if polygon := transformPolygon(rings, tolerance, clip); polygon != nil {
+				result = append(result, polygon)
+			}
+		}
+		return geometry(t, result), len(result) > 0, nil
+	}
+	return nil, false, nil
+}
+
+func transformLine(line []Point, tolerance float64, clip *Bounds) [][]Point {
+	line = Simplify(line, tolerance)
+	if clip == nil {
+		return [][]Point{line}
+	}
+	return ClipLine(line, *clip)
+}
+
+// transformPolygon returns nil if the exterior ring collapses or is clipped away.
+// Holes that collapse or are clipped away are removed.
+func transformPolygon(rings [][]Point, tolerance float64, clip *Bounds) [][]Point {
+	result := [][]Point{}
+	for i, ring := range rings {
+		ring = Simplify(ring, tolerance)
+		if clip != nil {
+			ring = ClipRing(ring, *clip)
+		}
+		if len(ring) < 4 {
+			if i == 0 {
+				return nil
+			}
+			continue
+		}
+		result = append(result, ring)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// positions returns every position of a single geometry.
+func positions(t string, coordinates json.RawMessage) ([]Point, error) {
+	switch t {
+	case "Point":
+		var p Point
+		err := json.Unmarshal(coordinates, &p)
+		return []Point{p}, err
+	case "MultiPoint", "LineString":
+		ps := []Point{}
+		err := json.Unmarshal(coordinates, &ps)
+		return ps, err
+	case "MultiLineString", "Polygon":
+		ls := [][]Point{}
+		err := json.Unmarshal(coordinates, &ls)
+		result := []Point{}
+		for _, l := range ls {
+			result = append(result, l...)
+		}
+		return result, err
+	case "MultiPolygon":
+		polygons := [][][]Point{}
+		err := json.Unmarshal(coordinates, &polygons)
+		result := []Point{}
+		for _, rings := range polygons {
+			for _, l := range rings {
+				result = append(result, l...)
+			}
+		}
+		return result, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,60 @@
+package geometry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	line := []Point{{0, 0}, {1, 0.01}, {2, -0.01}, {3, 5}, {4, 6}, {5, 7}, {6, 8.01}, {7, 9}}
+	expected := []Point{{0, 0}, {2, -0.01}, {3, 5}, {7, 9}}
+	if actual := Simplify(line, 0.1); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	if actual := Simplify(line, 0); !reflect.DeepEqual(actual, line) {
+		t.Errorf("expected zero tolerance to keep every point, got %v", actual)
+	}
+}
+
+func TestClipRing(t *testing.T) {
+	ring := []Point{{-5, -5}, {5, -5}, {5, 5}, {-5, 5}, {-5, -5}}
+	actual := ClipRing(ring, Bounds{0, 0, 10, 10})
+	if b := Ring(actual).Bounds(); b != (Bounds{0, 0, 5, 5}) || actual[0] != actual[len(actual)-1] {
+		t.Errorf("unexpected clipped ring: %v", actual)
+	}
+	if actual := ClipRing(ring, Bounds{20, 20, 30, 30}); actual != nil {
+		t.Errorf("expected ring outside of bounds to be removed, got %v", actual)
+	}
+}
+
+func TestClipLine(t *testing.T) {
+	line := []Point{{-5, 1}, {5, 1}, {5, 20}, {8, 20}, {8, 2}}
+	expected := [][]Point{{{0, 1}, {5, 1}, {5, 10}}, {{8, 10}, {8, 2}}}
+	if actual := ClipLine(line, Bounds{0, 0, 10, 10}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestTransformGeoJSON(t *testing.T) {
+	data := []byte(`{"type": "FeatureCollection", "bbox": [0, 0, 30, 30], "features": [
+		{"type": "Feature", "properties": {"name": "inside"}, "geometry": {"type": "Point", "coordinates": [1, 1]}},
+		{"type": "Feature", "properties": {"name": "outside"}, "geometry": {"type": "Point", "coordinates": [20, 20]}}
+	]}`)
+	b := Bounds{0, 0, 10, 10}
+	actual, err := TransformGeoJSON(data, 0, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"features":[{"geometry":{"coordinates":[1,1],"type":"Point"},"properties":{"name":"inside"},"type":"Feature"}],"type":"FeatureCollection"}`
+	if string(actual) != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+
+	bounds, err := GeoJSONBounds(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds != (Bounds{1, 1, 20, 20}) {
+		t.Errorf("unexpected bounds: %+v", bounds)
+	}
+}
@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+)
+
+// Template is an email whose subject and body are text/template templates.
+type Template struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Parse checks that the subject and body are valid templates.
+func (t Template) Parse() error {
+	if _, err := template.New("subject").Parse(t.Subject); err != nil {
+		return err
+	}
+	_, err := template.New("body").Parse(t.Body)
+	return err
+}
+
+// Render executes the subject and body templates with the provided data. Line breaks
+// are removed from the subject so that it cannot add headers to the message.
+func (t Template) Render(data interface{}) (subject, body string, err error) {
+	if subject, err = execute(t.Subject, data); err != nil {
+		return "", "", err
+	}
+	if body, err = execute(t.Body, data); err != nil {
+		return "", "", err
+	}
+	return strings.Join(strings.Fields(subject), " "), body, nil
+}
+
+func execute(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// SendTemplate renders the template with the provided data and sends it.
+func (c *Config) SendTemplate(to []string, t Template, data interface{}) error {
+	subject, body, err := t.Render(data)
+	if err != nil {
+		return err
+	}
+	return c.Send(to, subject, body)
+}
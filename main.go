@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -57,6 +58,9 @@ func main() {
 	root.PersistentFlags().StringP("db-name", "", "db", "db name")
 	viper.BindPFlag("DB_NAME", root.PersistentFlags().Lookup("db-name"))
 
+	root.PersistentFlags().StringP("store-backend", "", "minio", "store backend [minio, local]")
+	viper.BindPFlag("STORE_BACKEND", root.PersistentFlags().Lookup("store-backend"))
+
 	root.PersistentFlags().StringP("store-endpoint", "", "minio:9000", "store endpoint")
 	viper.BindPFlag("STORE_ENDPOINT", root.PersistentFlags().Lookup("store-endpoint"))
 
@@ -69,6 +73,15 @@ func main() {
 	root.PersistentFlags().BoolP("store-secure", "", false, "store secure")
 	viper.BindPFlag("STORE_SECURE", root.PersistentFlags().Lookup("store-secure"))
 
+	root.PersistentFlags().StringP("store-bucket", "", "peragrin", "store bucket")
+	viper.BindPFlag("STORE_BUCKET", root.PersistentFlags().Lookup("store-bucket"))
+
+	root.PersistentFlags().StringP("store-directory", "", ".store", "local store directory")
+	viper.BindPFlag("STORE_DIRECTORY", root.PersistentFlags().Lookup("store-directory"))
+
+	root.PersistentFlags().StringP("store-url", "", "http://localhost:8000/store", "public url of the local store download handler")
+	viper.BindPFlag("STORE_URL", root.PersistentFlags().Lookup("store-url"))
+
 	root.PersistentFlags().StringP("log-level", "l", "info", "log level [debug, info, warning, error, fatal, panic]")
 	viper.BindPFlag("LOG_LEVEL", root.PersistentFlags().Lookup("log-level"))
 
@@ -78,6 +91,63 @@ func main() {
 	root.PersistentFlags().StringP("locationiq-api-key", "", "", "api key to access location iq api")
 	viper.BindPFlag("LOCATIONIQ_API_KEY", root.PersistentFlags().Lookup("locationiq-api-key"))
 
+	root.PersistentFlags().StringP("geocoder-backend", "", "locationiq", "geocoder backend [locationiq, nominatim, gazetteer]")
+	viper.BindPFlag("GEOCODER_BACKEND", root.PersistentFlags().Lookup("geocoder-backend"))
+
+	root.PersistentFlags().StringP("geocoder-url", "", "https://nominatim.openstreetmap.org", "url of the nominatim compatible geocoder api")
+	viper.BindPFlag("GEOCODER_URL", root.PersistentFlags().Lookup("geocoder-url"))
+
+	root.PersistentFlags().StringP("geocoder-gazetteer", "", "gazetteer.csv", "path of the offline gazetteer csv file")
+	viper.BindPFlag("GEOCODER_GAZETTEER", root.PersistentFlags().Lookup("geocoder-gazetteer"))
+
+	root.PersistentFlags().DurationP("geocoder-timeout", "", 10*time.Second, "geocoder request timeout")
+	viper.BindPFlag("GEOCODER_TIMEOUT", root.PersistentFlags().Lookup("geocoder-timeout"))
+
+	root.PersistentFlags().IntP("geocoder-rate", "", 1, "maximum geocoder requests per second")
+	viper.BindPFlag("GEOCODER_RATE", root.PersistentFlags().Lookup("geocoder-rate"))
+
+	root.PersistentFlags().DurationP("geocoder-cache-ttl", "", 30*24*time.Hour, "how long geocoded addresses are cached")
+	viper.BindPFlag("GEOCODER_CACHE_TTL", root.PersistentFlags().Lookup("geocoder-cache-ttl"))
+
+	root.PersistentFlags().Float64P("walking-speed", "", 1.4, "walking speed in meters per second used to estimate walking times")
+	viper.BindPFlag("WALKING_SPEED", root.PersistentFlags().Lookup("walking-speed"))
+
+	root.PersistentFlags().StringP("payment-backend", "", "stripe", "payment backend [stripe, fake]")
+	viper.BindPFlag("PAYMENT_BACKEND", root.PersistentFlags().Lookup("payment-backend"))
+
+	root.PersistentFlags().StringP("stripe-api-key", "", "", "secret key to access the stripe api")
+	viper.BindPFlag("STRIPE_API_KEY", root.PersistentFlags().Lookup("stripe-api-key"))
+
+	root.PersistentFlags().StringP("payment-webhook-secret", "", "", "the secret used to verify payment webhook signatures, webhooks are rejected until it is set")
+	viper.BindPFlag("PAYMENT_WEBHOOK_SECRET", root.PersistentFlags().Lookup("payment-webhook-secret"))
+
+	root.PersistentFlags().StringP("payment-currency", "", "usd", "currency that memberships are priced in")
+	viper.BindPFlag("PAYMENT_CURRENCY", root.PersistentFlags().Lookup("payment-currency"))
+
+	root.PersistentFlags().DurationP("payment-timeout", "", 30*time.Second, "payment gateway request timeout")
+	viper.BindPFlag("PAYMENT_TIMEOUT", root.PersistentFlags().Lookup("payment-timeout"))
+
+	root.PersistentFlags().StringP("apple-pass-type-id", "", "", "pass type identifier of apple wallet membership cards")
+	viper.BindPFlag("APPLE_PASS_TYPE_ID", root.PersistentFlags().Lookup("apple-pass-type-id"))
+
+	root.PersistentFlags().StringP("apple-team-id", "", "", "apple developer team identifier of the pass type")
+	viper.BindPFlag("APPLE_TEAM_ID", root.PersistentFlags().Lookup("apple-team-id"))
+
+	root.PersistentFlags().StringP("apple-pass-certificate", "", "", "PEM file with the pass type certificate and its private key, or empty to disable apple wallet cards")
+	viper.BindPFlag("APPLE_PASS_CERTIFICATE", root.PersistentFlags().Lookup("apple-pass-certificate"))
+
+	root.PersistentFlags().StringP("apple-wwdr-certificate", "", "", "PEM file with the apple worldwide developer relations certificate")
+	viper.BindPFlag("APPLE_WWDR_CERTIFICATE", root.PersistentFlags().Lookup("apple-wwdr-certificate"))
+
+	root.PersistentFlags().StringP("google-wallet-issuer-id", "", "", "google wallet issuer identifier of membership cards")
+	viper.BindPFlag("GOOGLE_WALLET_ISSUER_ID", root.PersistentFlags().Lookup("google-wallet-issuer-id"))
+
+	root.PersistentFlags().StringP("google-wallet-credentials", "", "", "service account key file that signs google wallet cards, or empty to disable saving them")
+	viper.BindPFlag("GOOGLE_WALLET_CREDENTIALS", root.PersistentFlags().Lookup("google-wallet-credentials"))
+
+	root.PersistentFlags().DurationP("reminder-interval", "", time.Hour, "how often membership reminders are sent by the server, or 0 to disable them")
+	viper.BindPFlag("REMINDER_INTERVAL", root.PersistentFlags().Lookup("reminder-interval"))
+
 	root.PersistentFlags().StringP("app-domain", "", "http://localhost:8080", "app domain")
 	viper.BindPFlag("APP_DOMAIN", root.PersistentFlags().Lookup("app-domain"))
 
@@ -100,6 +170,8 @@ func main() {
 	root.AddCommand(cmd.Serve)
 	root.AddCommand(cmd.AddSuperUser)
 	root.AddCommand(cmd.SendTestMail)
+	root.AddCommand(cmd.SendReminders)
+	root.AddCommand(cmd.ImportOrganizations)
 
 	if err := root.Execute(); err != nil {
 		log.Fatal(err)
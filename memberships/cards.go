@@ -0,0 +1,167 @@
+package memberships
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/jteppinette/peragrin-api/models"
+	"github.com/jteppinette/peragrin-api/qrcode"
+	"github.com/jteppinette/peragrin-api/service"
+	"github.com/jteppinette/peragrin-api/wallet"
+)
+
+const (
+	defaultQRCodeScale = 8
+	maxQRCodeScale     = 40
+)
+
+// cardFormats maps the supported card formats to their content type.
+var cardFormats = map[string]string{
+	"png":    "image/png",
+	"svg":    "image/svg+xml",
+	"pkpass": "application/vnd.apple.pkpass",
+	"google": "application/json",
+}
+
+// CardHandler returns the membership card of an account membership in the requested format:
+// a png or svg QR code of the membership credential, an Apple Wallet pass, or a Google Wallet
+// object with a link that saves it. Cards are available to the member and to administrators
+// of the membership's community.
+func (c *Config) CardHandler(r *http.Request) *service.Response {
+	format := mux.Vars(r)["format"]
+	contentType, ok := cardFormats[format]
+	if !ok {
+		return service.NewResponse(errCardFormatInvalid, http.StatusBadRequest, map[string]string{"msg": errCardFormatInvalid.Error()})
+	}
+
+	credential, response := c.authorizeCard(r)
+	if response != nil {
+		return response
+	}
+	pass := credential.Pass()
+
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	header.Set("Cache-Control", "private, no-cache")
+
+	switch format {
+	case "png", "svg":
+		code, err := qrcode.Encode([]byte(credential.Token))
+		if err != nil {
+			return service.NewResponse(err, http.StatusInternalServerError, nil)
+		}
+		buf := &bytes.Buffer{}
+		if format == "svg" {
+			err = code.SVG(buf)
+		} else {
+			scale := defaultQRCodeScale
+			if v := r.URL.Query().Get("scale"); v != "" {
+				if scale, err = strconv.Atoi(v); err != nil || scale < 1 || scale > maxQRCodeScale {
+					return service.NewResponse(errQRCodeScaleInvalid, http.StatusBadRequest, map[string]string{"msg": errQRCodeScaleInvalid.Error()})
+				}
+			}
+			err = code.PNG(buf, scale)
+		}
+		if err != nil {
+			return service.NewResponse(err, http.StatusInternalServerError, nil)
+		}
+		return &service.Response{Code: http.StatusOK, Data: service.Raw(buf.Bytes()), Header: header}
+
+	case "pkpass":
+		b, err := c.AppleWallet.Pass(pass)
+		if err == wallet.ErrNotConfigured {
+			return service.NewResponse(err, http.StatusNotImplemented, map[string]string{"msg": err.Error()})
+		} else if err != nil {
+			return service.NewResponse(err, http.StatusInternalServerError, nil)
+		}
+		header.Set("Content-Disposition", `attachment; filename="membership.pkpass"`)
+		return &service.Response{Code: http.StatusOK, Data: service.Raw(b), Header: header}
+	}
+
+	card := struct {
+		Object  wallet.GoogleObject `json:"object"`
+		SaveURL string              `json:"saveURL,omitempty"`
+	}{Object: c.GoogleWallet.Object(pass)}
+	var err error
+	if card.SaveURL, err = c.GoogleWallet.SaveURL(pass); err != nil && err != wallet.ErrNotConfigured {
+		return service.NewResponse(err, http.StatusInternalServerError, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, card)
+}
+
+// VerifyHandler verifies a scanned membership credential. Credentials can only be verified
+// by operators of the organizations in the membership's community, and the response only
+// includes the member's name, membership and expiration.
+func (c *Config) VerifyHandler(r *http.Request) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	body := struct {
+		Credential string `json:"credential"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if body.Credential == "" {
+		return service.NewResponse(errCredentialRequired, http.StatusBadRequest, map[string]string{"msg": errCredentialRequired.Error()})
+	}
+
+	credential, err := models.VerifyMembershipCredential(body.Credential, c.TokenSecret, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if credential == nil {
+		return service.NewResponse(nil, http.StatusOK, models.CredentialVerification{})
+	}
+
+	if ok, err := account.IsCommunityOperator(credential.CommunityID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return service.NewResponse(errOperatorRequired, http.StatusForbidden, map[string]string{"msg": errOperatorRequired.Error()})
+	}
+
+	return service.NewResponse(nil, http.StatusOK, models.CredentialVerification{Valid: credential.Valid(), Credential: credential})
+}
+
+// authorizeCard returns the credential of the requested account membership if the
+// authenticated account is its member or administers its community.
+func (c *Config) authorizeCard(r *http.Request) (*models.MembershipCredential, *service.Response) {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return nil, service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	membershipID, err := strconv.Atoi(mux.Vars(r)["membershipID"])
+	if err != nil {
+		return nil, service.NewResponse(errors.Wrap(err, errMembershipIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+	accountID, err := strconv.Atoi(mux.Vars(r)["accountID"])
+	if err != nil {
+		return nil, service.NewResponse(errors.Wrap(err, errAccountIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	credential, err := models.GetMembershipCredential(accountID, membershipID, c.TokenSecret, c.DBClient)
+	if err != nil {
+		return nil, service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if credential == nil {
+		return nil, service.NewResponse(errAccountMembershipNotFound, http.StatusNotFound, map[string]string{"msg": errAccountMembershipNotFound.Error()})
+	}
+
+	if account.ID != accountID {
+		if ok, err := account.IsCommunityAdministrator(credential.CommunityID, c.DBClient); err != nil {
+			return nil, service.NewResponse(err, http.StatusBadRequest, nil)
+		} else if !ok {
+			return nil, service.NewResponse(errCardForbidden, http.StatusForbidden, nil)
+		}
+	}
+	return credential, nil
+}
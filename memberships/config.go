@@ -4,6 +4,8 @@ import (
 	"github.com/jmoiron/sqlx"
 
 	"github.com/jteppinette/peragrin-api/mail"
+	"github.com/jteppinette/peragrin-api/payment"
+	"github.com/jteppinette/peragrin-api/wallet"
 )
 
 // Config represents the configuration objects necessary to
@@ -13,10 +15,20 @@ type Config struct {
 	MailClient  *mail.Config
 	TokenSecret string
 	AppDomain   string
+
+	PaymentGateway payment.Gateway
+	// Currency is the currency that memberships are priced in, e.g. usd.
+	Currency string
+
+	// AppleWallet and GoogleWallet produce membership cards. Apple Wallet passes are not
+	// available when AppleWallet is nil, and Google Wallet objects can not be saved when
+	// GoogleWallet does not have a key.
+	AppleWallet  *wallet.Apple
+	GoogleWallet *wallet.Google
 }
 
 // Init returns a configuration struct that can be used to initialize
 // the objects in this package.
-func Init(dbClient *sqlx.DB, mailClient *mail.Config, tokenSecret, appDomain string) *Config {
-	return &Config{dbClient, mailClient, tokenSecret, appDomain}
+func Init(dbClient *sqlx.DB, mailClient *mail.Config, tokenSecret, appDomain string, paymentGateway payment.Gateway, currency string, appleWallet *wallet.Apple, googleWallet *wallet.Google) *Config {
+	return &Config{dbClient, mailClient, tokenSecret, appDomain, paymentGateway, currency, appleWallet, googleWallet}
 }
@@ -7,7 +7,24 @@ import (
 var (
 	errMembershipIDRequired = errors.New("membership id required")
 	errAccountIDRequired    = errors.New("account id required")
+	errCredentialRequired   = errors.New("credential required")
+
+	errAuthenticationRequired = errors.New("authentication required")
+	errCardForbidden          = errors.New("membership cards are only available to their member and community administrators")
+	errAdministratorRequired  = errors.New("community administrator required")
+	errDependentRequired      = errors.New("only the invited dependent can accept a seat")
+	errOperatorRequired       = errors.New("credentials can only be verified by operators of the membership's community")
+
+	errAccountNotFound           = errors.New("account not found")
+	errMembershipNotFound        = errors.New("membership not found")
+	errAccountMembershipNotFound = errors.New("account membership not found")
+
+	errCardFormatInvalid  = errors.New("card format must be png, svg, pkpass or google")
+	errQRCodeScaleInvalid = errors.New("scale must be between 1 and 40")
+
+	errRosterFormatNotSupported = errors.New("roster format not supported, use text/csv or application/json")
 
 	errAccountCreation        = errors.New("account creation")
 	errAccountActivationEmail = errors.New("account activation email")
+	errSeatInvitationEmail    = errors.New("seat invitation email")
 )
@@ -8,9 +8,11 @@ import (
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 
+	"github.com/jteppinette/peragrin-api/common"
 	"github.com/jteppinette/peragrin-api/models"
 	"github.com/jteppinette/peragrin-api/service"
 )
@@ -48,7 +50,7 @@ func (c *Config) UpdateHandler(r *http.Request) *service.Response {
 
 	membership.ID = id
 	if err := membership.Update(c.DBClient); err != nil {
-		return service.NewResponse(err, http.StatusBadRequest, nil)
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
 	}
 
 	return service.NewResponse(nil, http.StatusOK, membership)
@@ -87,8 +89,9 @@ func (c *Config) AddAccountHandler(r *http.Request) *service.Response {
 	} else if existing != nil {
 		existing.Expiration = account.Expiration
 		if err := existing.AddMembership(membershipID, c.DBClient); err != nil {
-			return service.NewResponse(err, http.StatusBadRequest, nil)
+			return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
 		}
+		account.Expiration = existing.Expiration
 		return service.NewResponse(nil, http.StatusOK, account)
 	}
 
@@ -104,7 +107,7 @@ func (c *Config) AddAccountHandler(r *http.Request) *service.Response {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
 
-	if err := account.SendActivationEmail(fmt.Sprintf("/map?community=%s", community.Name), c.AppDomain, c.TokenSecret, fmt.Sprintf("%s Membership", community.Name), c.MailClient); err != nil {
+	if err := account.SendActivationEmail(community.MapPath(), c.AppDomain, c.TokenSecret, fmt.Sprintf("%s Membership", community.Name), c.MailClient); err != nil {
 		log.WithFields(log.Fields{
 			"email": account.Email, "error": err.Error(), "id": r.Header.Get("X-Request-ID"),
 		}).Info(errAccountActivationEmail.Error())
@@ -113,57 +116,119 @@ func (c *Config) AddAccountHandler(r *http.Request) *service.Response {
 	return service.NewResponse(nil, http.StatusOK, account)
 }
 
-// BulkAddAccountsHandler creates and invites multiple accounts to a community
-// membership in a single atomic action.
-func (c *Config) BulkAddAccountsHandler(r *http.Request) *service.Response {
+// CheckoutHandler starts a purchase of the provided membership. The response contains the
+// url of the payment gateway's checkout page. The member joins the membership once the
+// gateway reports that it has been paid.
+func (c *Config) CheckoutHandler(r *http.Request) *service.Response {
 	id, err := strconv.Atoi(mux.Vars(r)["membershipID"])
 	if err != nil {
 		return service.NewResponse(errors.Wrap(err, errMembershipIDRequired.Error()), http.StatusBadRequest, nil)
 	}
 
+	session := models.CheckoutSession{}
+	if err := json.NewDecoder(r.Body).Decode(&session); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
 	membership, err := models.GetMembershipByID(id, c.DBClient)
 	if err != nil {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
+	if membership == nil {
+		return service.NewResponse(errMembershipNotFound, http.StatusNotFound, map[string]string{"msg": errMembershipNotFound.Error()})
+	}
 
 	community, err := models.GetCommunityByMembershipID(membership.ID, c.DBClient)
 	if err != nil {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
 
-	accounts := models.Accounts{}
-	if err := json.NewDecoder(r.Body).Decode(&accounts); err != nil {
-		return service.NewResponse(err, http.StatusBadRequest, nil)
+	next := community.MapURL(c.AppDomain)
+	if err := membership.Checkout(&session, c.Currency, next+"&checkout=success", next+"&checkout=canceled", c.PaymentGateway, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+
+	return service.NewResponse(nil, http.StatusCreated, session)
+}
+
+// BulkAddAccountsHandler reconciles the membership with a roster of accounts in a single
+// atomic action. The roster is a csv file, or a JSON array of accounts. Csv columns are
+// mapped to roster fields by query parameters named after the fields, e.g. email=E-mail,
+// and otherwise by their header. New emails create accounts that are invited to the
+// membership, existing accounts are attached to it, and the expirations of existing members
+// are changed. When the removeMissing query parameter is true, members that are not in the
+// roster are removed. When the dryRun query parameter is true, the reconciliation is
+// returned without changing anything.
+func (c *Config) BulkAddAccountsHandler(r *http.Request) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["membershipID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errMembershipIDRequired.Error()), http.StatusBadRequest, nil)
 	}
 
-	emails := []string{}
-	for idx, _ := range accounts {
-		accounts[idx].Email = strings.ToLower(accounts[idx].Email)
-		emails = append(emails, accounts[idx].Email)
+	membership, err := models.GetMembershipByID(id, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if membership == nil {
+		return service.NewResponse(errMembershipNotFound, http.StatusNotFound, map[string]string{"msg": errMembershipNotFound.Error()})
 	}
 
-	existing, err := models.GetAccountsByEmails(emails, c.DBClient)
+	community, err := models.GetCommunityByMembershipID(membership.ID, c.DBClient)
 	if err != nil {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
 
-	needs := models.Accounts{}
-	for _, account := range accounts {
-		var exists bool
-		for _, lookup := range existing {
-			if account.Email == lookup.Email {
-				exists = true
-				break
+	if ok, err := account.IsCommunityAdministrator(community.ID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return service.NewResponse(errAdministratorRequired, http.StatusForbidden, nil)
+	}
+
+	query := r.URL.Query()
+	var roster *models.RosterImport
+	switch contentType := strings.Split(r.Header.Get("Content-Type"), ";")[0]; contentType {
+	case "text/csv":
+		columns := map[string]string{}
+		for _, field := range models.RosterFields {
+			if column := query.Get(field); column != "" {
+				columns[field] = column
 			}
 		}
-		if !exists {
-			needs = append(needs, account)
+		if roster, err = models.ParseRosterCSV(r.Body, columns, community.Location()); err != nil {
+			return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+		}
+	case "application/json", "":
+		accounts := models.Accounts{}
+		if err := json.NewDecoder(r.Body).Decode(&accounts); err != nil {
+			return service.NewResponse(err, http.StatusBadRequest, nil)
 		}
+		roster = models.NewRosterImport(accounts)
+	default:
+		return service.NewResponse(errRosterFormatNotSupported, http.StatusUnsupportedMediaType, map[string]string{"msg": errRosterFormatNotSupported.Error()})
 	}
-	if err := needs.CreateWithMembership(membership.ID, c.DBClient); err != nil {
+
+	roster.DryRun, _ = strconv.ParseBool(query.Get("dryRun"))
+	roster.RemoveMissing, _ = strconv.ParseBool(query.Get("removeMissing"))
+	if err := roster.Reconcile(membership, c.DBClient); err != nil {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
+	if !roster.Valid {
+		return service.NewResponse(nil, http.StatusUnprocessableEntity, roster)
+	}
+
+	if err := roster.Apply(membership.ID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	if roster.DryRun {
+		return service.NewResponse(nil, http.StatusOK, roster)
+	}
 
+	needs := roster.Created()
 	go func() {
 		defer func() {
 			if err := recover(); err != nil {
@@ -171,7 +236,7 @@ func (c *Config) BulkAddAccountsHandler(r *http.Request) *service.Response {
 			}
 		}()
 		for _, need := range needs {
-			if err := need.SendActivationEmail(fmt.Sprintf("/map?community=%s", community.Name), c.AppDomain, c.TokenSecret, fmt.Sprintf("%s Membership", community.Name), c.MailClient); err != nil {
+			if err := need.SendActivationEmail(community.MapPath(), c.AppDomain, c.TokenSecret, fmt.Sprintf("%s Membership", community.Name), c.MailClient); err != nil {
 				log.WithFields(log.Fields{
 					"email": need.Email, "error": err.Error(), "id": r.Header.Get("X-Request-ID"),
 				}).Info(errAccountActivationEmail.Error())
@@ -179,7 +244,7 @@ func (c *Config) BulkAddAccountsHandler(r *http.Request) *service.Response {
 		}
 	}()
 
-	return service.NewResponse(nil, http.StatusOK, nil)
+	return service.NewResponse(nil, http.StatusOK, roster)
 }
 
 // UpdateAccountHandler updates an account and account membership relationship.
@@ -207,6 +272,32 @@ func (c *Config) UpdateAccountHandler(r *http.Request) *service.Response {
 	return service.NewResponse(nil, http.StatusOK, account)
 }
 
+// RenewAccountHandler extends an account membership by another term of the membership's plan.
+func (c *Config) RenewAccountHandler(r *http.Request) *service.Response {
+	membershipID, err := strconv.Atoi(mux.Vars(r)["membershipID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errMembershipIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+	accountID, err := strconv.Atoi(mux.Vars(r)["accountID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errAccountIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	account, err := models.GetAccountByID(accountID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if account == nil {
+		return service.NewResponse(errAccountNotFound, http.StatusNotFound, map[string]string{"msg": errAccountNotFound.Error()})
+	}
+
+	if err := account.RenewMembership(membershipID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+
+	return service.NewResponse(nil, http.StatusOK, account)
+}
+
 // DeleteHandler deletes a membership.
 func (c *Config) DeleteHandler(r *http.Request) *service.Response {
 	id, err := strconv.Atoi(mux.Vars(r)["membershipID"])
@@ -238,3 +329,101 @@ func (c *Config) RemoveAccountHandler(r *http.Request) *service.Response {
 
 	return service.NewResponse(nil, http.StatusNoContent, nil)
 }
+
+// ChangeAccountHandler moves an account to another membership of the community, e.g. to
+// upgrade or downgrade its tier. The remaining time of the account membership is prorated
+// by the prices of the memberships unless an expiration is provided.
+func (c *Config) ChangeAccountHandler(r *http.Request) *service.Response {
+	membershipID, accountID, account, response := c.authorizeAccountMembership(r, false)
+	if response != nil {
+		return response
+	}
+
+	body := struct {
+		MembershipID int                 `json:"membershipID"`
+		Expiration   common.JSONNullTime `json:"expiration"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if body.MembershipID == 0 {
+		return service.NewResponse(errMembershipIDRequired, http.StatusBadRequest, map[string]string{"msg": errMembershipIDRequired.Error()})
+	}
+
+	member := models.Account{ID: accountID}
+	change, err := member.ChangeMembership(membershipID, body.MembershipID, body.Expiration, account.ID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusOK, change)
+}
+
+// TransferAccountHandler moves an account membership to the account with the provided email,
+// which is created and invited when it does not exist. Memberships can be transferred by
+// their holder and by community administrators.
+func (c *Config) TransferAccountHandler(r *http.Request) *service.Response {
+	membershipID, accountID, account, response := c.authorizeAccountMembership(r, true)
+	if response != nil {
+		return response
+	}
+
+	recipient := models.Account{}
+	if err := json.NewDecoder(r.Body).Decode(&recipient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	holder := models.Account{ID: accountID}
+	change, created, err := holder.TransferMembership(membershipID, &recipient, account.ID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+
+	if created {
+		community, err := models.GetCommunityByMembershipID(membershipID, c.DBClient)
+		if err != nil {
+			return service.NewResponse(err, http.StatusBadRequest, nil)
+		}
+		if err := recipient.SendActivationEmail(community.MapPath(), c.AppDomain, c.TokenSecret, fmt.Sprintf("%s Membership", community.Name), c.MailClient); err != nil {
+			log.WithFields(log.Fields{
+				"email": recipient.Email, "error": err.Error(), "id": r.Header.Get("X-Request-ID"),
+			}).Info(errAccountActivationEmail.Error())
+		}
+	}
+	return service.NewResponse(nil, http.StatusOK, change)
+}
+
+// authorizeAccountMembership returns the requested membership and account ids, and the
+// authenticated account, if the authenticated account administers the membership's
+// community, or when holder is true, if it is the requested account.
+func (c *Config) authorizeAccountMembership(r *http.Request, holder bool) (int, int, models.Account, *service.Response) {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return 0, 0, account, service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	membershipID, err := strconv.Atoi(mux.Vars(r)["membershipID"])
+	if err != nil {
+		return 0, 0, account, service.NewResponse(errors.Wrap(err, errMembershipIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+	accountID, err := strconv.Atoi(mux.Vars(r)["accountID"])
+	if err != nil {
+		return 0, 0, account, service.NewResponse(errors.Wrap(err, errAccountIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+	if holder && account.ID == accountID {
+		return membershipID, accountID, account, nil
+	}
+
+	membership, err := models.GetMembershipByID(membershipID, c.DBClient)
+	if err != nil {
+		return 0, 0, account, service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if membership == nil {
+		return 0, 0, account, service.NewResponse(errMembershipNotFound, http.StatusNotFound, map[string]string{"msg": errMembershipNotFound.Error()})
+	}
+	if ok, err := account.IsCommunityAdministrator(membership.CommunityID, c.DBClient); err != nil {
+		return 0, 0, account, service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return 0, 0, account, service.NewResponse(errAdministratorRequired, http.StatusForbidden, nil)
+	}
+	return membershipID, accountID, account, nil
+}
@@ -0,0 +1,150 @@
+package memberships
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/jteppinette/peragrin-api/models"
+	"github.com/jteppinette/peragrin-api/service"
+)
+
+// ListSeatsHandler returns the seats of an account membership and the dependents that have
+// them. Seats are available to their holder and community administrators.
+func (c *Config) ListSeatsHandler(r *http.Request) *service.Response {
+	membershipID, accountID, _, response := c.authorizeAccountMembership(r, true)
+	if response != nil {
+		return response
+	}
+
+	seats, err := models.GetMembershipSeats(accountID, membershipID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if seats == nil {
+		return service.NewResponse(errAccountMembershipNotFound, http.StatusNotFound, map[string]string{"msg": errAccountMembershipNotFound.Error()})
+	}
+	return service.NewResponse(nil, http.StatusOK, seats)
+}
+
+// AddSeatHandler gives a seat of an account membership to the account with the provided email,
+// which is created and sent an activation email when it does not exist. Existing accounts are
+// invited instead, and only use the seat once they accept it. Seats are given by the holder of
+// the account membership and by community administrators.
+func (c *Config) AddSeatHandler(r *http.Request) *service.Response {
+	membershipID, accountID, _, response := c.authorizeAccountMembership(r, true)
+	if response != nil {
+		return response
+	}
+
+	dependent := models.Account{}
+	if err := json.NewDecoder(r.Body).Decode(&dependent); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	holder := models.Account{ID: accountID}
+	seat, created, err := holder.AddDependent(membershipID, &dependent, c.DBClient)
+	if err == models.ErrSeatsFull {
+		return service.NewResponse(err, http.StatusConflict, map[string]string{"msg": err.Error()})
+	} else if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+
+	community, err := models.GetCommunityByMembershipID(membershipID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if created {
+		if err := dependent.SendActivationEmail(community.MapPath(), c.AppDomain, c.TokenSecret, fmt.Sprintf("%s Membership", community.Name), c.MailClient); err != nil {
+			log.WithFields(log.Fields{
+				"email": dependent.Email, "error": err.Error(), "id": r.Header.Get("X-Request-ID"),
+			}).Info(errAccountActivationEmail.Error())
+		}
+		return service.NewResponse(nil, http.StatusCreated, seat)
+	}
+
+	primary, err := models.GetAccountByID(accountID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if primary == nil {
+		return service.NewResponse(errAccountMembershipNotFound, http.StatusNotFound, map[string]string{"msg": errAccountMembershipNotFound.Error()})
+	}
+	subject := fmt.Sprintf("%s Membership Invitation", community.Name)
+	body := fmt.Sprintf("%s has invited you to share their %s membership. Accept the invitation at %s/#/accounts/%d/memberships", primary.Email, community.Name, c.AppDomain, dependent.ID)
+	if err := c.MailClient.Send([]string{dependent.Email}, subject, body); err != nil {
+		log.WithFields(log.Fields{
+			"email": dependent.Email, "error": err.Error(), "id": r.Header.Get("X-Request-ID"),
+		}).Info(errSeatInvitationEmail.Error())
+	}
+	return service.NewResponse(nil, http.StatusCreated, seat)
+}
+
+// AcceptSeatHandler accepts an invitation to a seat of an account membership. Invitations are
+// only accepted by the invited dependent.
+func (c *Config) AcceptSeatHandler(r *http.Request) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	vars := mux.Vars(r)
+	membershipID, err := strconv.Atoi(vars["membershipID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errMembershipIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+	accountID, err := strconv.Atoi(vars["accountID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errAccountIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+	dependentID, err := strconv.Atoi(vars["dependentID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errAccountIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+	if account.ID != dependentID {
+		return service.NewResponse(errDependentRequired, http.StatusForbidden, nil)
+	}
+
+	seat, err := account.AcceptSeat(membershipID, accountID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusOK, seat)
+}
+
+// RemoveSeatHandler takes a seat of an account membership away from its dependent. Seats are
+// removed by the holder of the account membership, community administrators and the
+// dependent itself.
+func (c *Config) RemoveSeatHandler(r *http.Request) *service.Response {
+	dependentID, err := strconv.Atoi(mux.Vars(r)["dependentID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errAccountIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	var membershipID, accountID int
+	if account, ok := context.Get(r, "account").(models.Account); ok && account.ID == dependentID {
+		if membershipID, err = strconv.Atoi(mux.Vars(r)["membershipID"]); err != nil {
+			return service.NewResponse(errors.Wrap(err, errMembershipIDRequired.Error()), http.StatusBadRequest, nil)
+		}
+		if accountID, err = strconv.Atoi(mux.Vars(r)["accountID"]); err != nil {
+			return service.NewResponse(errors.Wrap(err, errAccountIDRequired.Error()), http.StatusBadRequest, nil)
+		}
+	} else {
+		var response *service.Response
+		if membershipID, accountID, _, response = c.authorizeAccountMembership(r, true); response != nil {
+			return response
+		}
+	}
+
+	holder := models.Account{ID: accountID}
+	if err := holder.RemoveDependent(membershipID, dependentID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusNoContent, nil)
+}
@@ -0,0 +1,3 @@
+DROP TABLE OrganizationClaim;
+
+ALTER TABLE Organization DROP COLUMN verified;
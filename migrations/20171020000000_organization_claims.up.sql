@@ -0,0 +1,19 @@
+ALTER TABLE Organization ADD COLUMN verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Organizations that existed before claims were introduced keep their capabilities.
+UPDATE Organization SET verified = TRUE;
+
+CREATE TABLE OrganizationClaim (
+    id SERIAL PRIMARY KEY,
+    organizationID INTEGER NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+    accountID INTEGER NOT NULL REFERENCES Account (id) ON DELETE CASCADE,
+    method TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    note TEXT NOT NULL DEFAULT '',
+    code TEXT NOT NULL DEFAULT '',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    createdAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolvedAt TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX OrganizationClaim_organizationID ON OrganizationClaim (organizationID);
@@ -0,0 +1,9 @@
+DROP INDEX CommunityOrganization_communityID_status;
+
+DELETE FROM CommunityOrganization WHERE status != 'approved';
+
+ALTER TABLE CommunityOrganization
+    DROP COLUMN status,
+    DROP COLUMN reason,
+    DROP COLUMN requestedAt,
+    DROP COLUMN resolvedAt;
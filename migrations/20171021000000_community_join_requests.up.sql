@@ -0,0 +1,9 @@
+ALTER TABLE CommunityOrganization
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'approved',
+    ADD COLUMN reason TEXT NOT NULL DEFAULT '',
+    ADD COLUMN requestedAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ADD COLUMN resolvedAt TIMESTAMP WITH TIME ZONE;
+
+UPDATE CommunityOrganization SET resolvedAt = requestedAt;
+
+CREATE INDEX CommunityOrganization_communityID_status ON CommunityOrganization (communityID, status);
@@ -0,0 +1 @@
+ALTER TABLE Community DROP COLUMN boundary;
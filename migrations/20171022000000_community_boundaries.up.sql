@@ -0,0 +1 @@
+ALTER TABLE Community ADD COLUMN boundary JSONB;
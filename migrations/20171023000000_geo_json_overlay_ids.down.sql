@@ -0,0 +1,2 @@
+ALTER TABLE GeoJSONOverlay DROP COLUMN position;
+ALTER TABLE GeoJSONOverlay DROP COLUMN id;
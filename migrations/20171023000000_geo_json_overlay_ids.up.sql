@@ -0,0 +1,6 @@
+ALTER TABLE GeoJSONOverlay ADD COLUMN id SERIAL UNIQUE;
+ALTER TABLE GeoJSONOverlay ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+UPDATE GeoJSONOverlay SET position = ordered.position
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY communityID ORDER BY id) - 1 AS position FROM GeoJSONOverlay) AS ordered
+WHERE GeoJSONOverlay.id = ordered.id;
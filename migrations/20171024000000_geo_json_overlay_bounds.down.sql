@@ -0,0 +1,2 @@
+ALTER TABLE GeoJSONOverlay DROP COLUMN updatedAt;
+ALTER TABLE GeoJSONOverlay DROP COLUMN bounds;
@@ -0,0 +1,2 @@
+ALTER TABLE GeoJSONOverlay ADD COLUMN bounds JSONB;
+ALTER TABLE GeoJSONOverlay ADD COLUMN updatedAt TIMESTAMP NOT NULL DEFAULT NOW();
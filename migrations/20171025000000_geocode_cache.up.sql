@@ -0,0 +1,5 @@
+CREATE TABLE GeocodeCache (
+    key TEXT PRIMARY KEY,
+    result JSONB NOT NULL,
+    createdAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+ALTER TABLE Organization DROP COLUMN reviewReason;
+ALTER TABLE Organization DROP COLUMN needsReview;
+ALTER TABLE Organization DROP COLUMN geocodeSource;
+ALTER TABLE Organization DROP COLUMN geocodeConfidence;
@@ -0,0 +1,6 @@
+ALTER TABLE Organization ADD COLUMN geocodeConfidence DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE Organization ADD COLUMN geocodeSource TEXT NOT NULL DEFAULT '';
+ALTER TABLE Organization ADD COLUMN needsReview BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE Organization ADD COLUMN reviewReason TEXT NOT NULL DEFAULT '';
+
+UPDATE Organization SET needsReview = TRUE, reviewReason = 'missing coordinates' WHERE lon = 0 AND lat = 0;
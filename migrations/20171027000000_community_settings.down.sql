@@ -0,0 +1,11 @@
+DROP TABLE CommunitySlugRedirect;
+
+ALTER TABLE Community DROP COLUMN locale;
+ALTER TABLE Community DROP COLUMN timezone;
+ALTER TABLE Community DROP COLUMN website;
+ALTER TABLE Community DROP COLUMN phone;
+ALTER TABLE Community DROP COLUMN email;
+ALTER TABLE Community DROP COLUMN welcomeText;
+ALTER TABLE Community DROP COLUMN primaryColor;
+ALTER TABLE Community DROP COLUMN logo;
+ALTER TABLE Community DROP COLUMN slug;
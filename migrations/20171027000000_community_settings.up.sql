@@ -0,0 +1,22 @@
+ALTER TABLE Community ADD COLUMN slug TEXT;
+ALTER TABLE Community ADD COLUMN logo TEXT NOT NULL DEFAULT '';
+ALTER TABLE Community ADD COLUMN primaryColor TEXT NOT NULL DEFAULT '';
+ALTER TABLE Community ADD COLUMN welcomeText TEXT NOT NULL DEFAULT '';
+ALTER TABLE Community ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE Community ADD COLUMN phone TEXT NOT NULL DEFAULT '';
+ALTER TABLE Community ADD COLUMN website TEXT NOT NULL DEFAULT '';
+ALTER TABLE Community ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE Community ADD COLUMN locale TEXT NOT NULL DEFAULT 'en-US';
+
+UPDATE Community SET slug = COALESCE(NULLIF(TRIM(BOTH '-' FROM LOWER(REGEXP_REPLACE(name, '[^a-zA-Z0-9]+', '-', 'g'))), ''), 'community');
+UPDATE Community SET slug = slug || '-' || id
+WHERE id IN (SELECT id FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY slug ORDER BY id) AS n FROM Community) AS ranked WHERE n > 1);
+
+ALTER TABLE Community ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE Community ADD CONSTRAINT Community_slug_key UNIQUE (slug);
+
+CREATE TABLE CommunitySlugRedirect (
+    slug TEXT PRIMARY KEY,
+    communityID INTEGER NOT NULL REFERENCES Community (id) ON DELETE CASCADE,
+    createdAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
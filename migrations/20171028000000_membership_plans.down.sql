@@ -0,0 +1,4 @@
+ALTER TABLE Membership DROP COLUMN gracePeriodDays;
+ALTER TABLE Membership DROP COLUMN seasonEnd;
+ALTER TABLE Membership DROP COLUMN seasonStart;
+ALTER TABLE Membership DROP COLUMN duration;
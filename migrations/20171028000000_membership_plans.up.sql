@@ -0,0 +1,6 @@
+ALTER TABLE Membership ADD COLUMN duration TEXT NOT NULL DEFAULT 'annual';
+ALTER TABLE Membership ADD COLUMN seasonStart TIMESTAMP WITH TIME ZONE;
+ALTER TABLE Membership ADD COLUMN seasonEnd TIMESTAMP WITH TIME ZONE;
+ALTER TABLE Membership ADD COLUMN gracePeriodDays INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE AccountMembership ALTER COLUMN expiration DROP NOT NULL;
@@ -0,0 +1,2 @@
+DROP TABLE Payment;
+DROP TABLE CheckoutSession;
@@ -0,0 +1,33 @@
+CREATE TABLE CheckoutSession (
+    id SERIAL PRIMARY KEY,
+    communityID INTEGER NOT NULL REFERENCES Community (id) ON DELETE CASCADE,
+    membershipID INTEGER REFERENCES Membership (id) ON DELETE SET NULL,
+    accountID INTEGER REFERENCES Account (id) ON DELETE SET NULL,
+    email TEXT NOT NULL,
+    firstName TEXT NOT NULL DEFAULT '',
+    lastName TEXT NOT NULL DEFAULT '',
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    gatewaySessionID TEXT UNIQUE,
+    url TEXT NOT NULL DEFAULT '',
+    createdAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completedAt TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE Payment (
+    id SERIAL PRIMARY KEY,
+    communityID INTEGER NOT NULL REFERENCES Community (id) ON DELETE CASCADE,
+    membershipID INTEGER REFERENCES Membership (id) ON DELETE SET NULL,
+    accountID INTEGER REFERENCES Account (id) ON DELETE SET NULL,
+    checkoutSessionID INTEGER REFERENCES CheckoutSession (id) ON DELETE SET NULL,
+    refundedPaymentID INTEGER REFERENCES Payment (id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    gatewayReference TEXT NOT NULL UNIQUE,
+    createdAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX Payment_communityID_createdAt_idx ON Payment (communityID, createdAt);
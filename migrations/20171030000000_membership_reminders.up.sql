@@ -0,0 +1,16 @@
+CREATE TABLE MembershipReminder (
+    accountID INTEGER NOT NULL REFERENCES Account (id) ON DELETE CASCADE,
+    membershipID INTEGER NOT NULL REFERENCES Membership (id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    expiration TIMESTAMP WITH TIME ZONE NOT NULL,
+    sentAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (accountID, membershipID, kind, expiration)
+);
+
+CREATE TABLE MembershipReminderTemplate (
+    communityID INTEGER NOT NULL REFERENCES Community (id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    PRIMARY KEY (communityID, kind)
+);
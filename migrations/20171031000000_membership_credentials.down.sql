@@ -0,0 +1 @@
+ALTER TABLE AccountMembership DROP COLUMN credentialID;
@@ -0,0 +1 @@
+ALTER TABLE AccountMembership ADD COLUMN credentialID TEXT NOT NULL UNIQUE DEFAULT md5(random()::TEXT || clock_timestamp()::TEXT);
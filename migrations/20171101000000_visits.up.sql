@@ -0,0 +1,14 @@
+CREATE TABLE Visit (
+    id SERIAL PRIMARY KEY,
+    accountID INTEGER NOT NULL REFERENCES Account (id) ON DELETE CASCADE,
+    membershipID INTEGER NOT NULL REFERENCES Membership (id) ON DELETE CASCADE,
+    organizationID INTEGER NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+    communityID INTEGER NOT NULL REFERENCES Community (id) ON DELETE CASCADE,
+    method TEXT NOT NULL,
+    operatorID INTEGER REFERENCES Account (id) ON DELETE SET NULL,
+    createdAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX Visit_accountID_createdAt ON Visit (accountID, createdAt);
+CREATE INDEX Visit_organizationID_createdAt ON Visit (organizationID, createdAt);
+CREATE INDEX Visit_communityID_createdAt ON Visit (communityID, createdAt);
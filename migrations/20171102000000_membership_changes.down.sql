@@ -0,0 +1 @@
+DROP TABLE MembershipChange;
@@ -0,0 +1,17 @@
+CREATE TABLE MembershipChange (
+    id SERIAL PRIMARY KEY,
+    accountID INTEGER NOT NULL REFERENCES Account (id) ON DELETE CASCADE,
+    fromAccountID INTEGER REFERENCES Account (id) ON DELETE SET NULL,
+    fromMembershipID INTEGER REFERENCES Membership (id) ON DELETE SET NULL,
+    toMembershipID INTEGER REFERENCES Membership (id) ON DELETE SET NULL,
+    fromMembershipName TEXT NOT NULL,
+    toMembershipName TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    previousExpiration TIMESTAMP WITH TIME ZONE,
+    expiration TIMESTAMP WITH TIME ZONE,
+    changedBy INTEGER REFERENCES Account (id) ON DELETE SET NULL,
+    createdAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX MembershipChange_accountID ON MembershipChange (accountID);
+CREATE INDEX MembershipChange_fromAccountID ON MembershipChange (fromAccountID);
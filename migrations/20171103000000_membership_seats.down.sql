@@ -0,0 +1,2 @@
+DROP TABLE MembershipSeat;
+ALTER TABLE Membership DROP COLUMN seats;
@@ -0,0 +1,12 @@
+ALTER TABLE Membership ADD COLUMN seats INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE MembershipSeat (
+    membershipID INTEGER NOT NULL REFERENCES Membership (id) ON DELETE CASCADE,
+    primaryAccountID INTEGER NOT NULL REFERENCES Account (id) ON DELETE CASCADE,
+    accountID INTEGER NOT NULL REFERENCES Account (id) ON DELETE CASCADE,
+    createdAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (membershipID, accountID)
+);
+
+CREATE INDEX MembershipSeat_primaryAccountID ON MembershipSeat (primaryAccountID, membershipID);
+CREATE INDEX MembershipSeat_accountID ON MembershipSeat (accountID);
@@ -0,0 +1,7 @@
+DROP INDEX AccountPromotion_promotionID_consumedAt;
+
+ALTER TABLE Promotion
+    DROP COLUMN accountLimit,
+    DROP COLUMN accountLimitPeriod,
+    DROP COLUMN totalLimit,
+    DROP COLUMN dailyLimit;
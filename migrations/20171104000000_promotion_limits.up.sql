@@ -0,0 +1,7 @@
+ALTER TABLE Promotion
+    ADD COLUMN accountLimit INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN accountLimitPeriod TEXT NOT NULL DEFAULT '',
+    ADD COLUMN totalLimit INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN dailyLimit INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX AccountPromotion_promotionID_consumedAt ON AccountPromotion (promotionID, consumedAt);
@@ -0,0 +1,5 @@
+DROP TABLE PromotionWindow;
+
+ALTER TABLE Promotion
+    DROP COLUMN startsAt,
+    DROP COLUMN blackoutDates;
@@ -0,0 +1,12 @@
+ALTER TABLE Promotion
+    ADD COLUMN startsAt TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN blackoutDates DATE[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE PromotionWindow (
+    promotionID INTEGER NOT NULL REFERENCES Promotion (id) ON DELETE CASCADE,
+    weekday INTEGER NOT NULL,
+    start INTEGER NOT NULL,
+    close INTEGER NOT NULL
+);
+
+CREATE INDEX PromotionWindow_promotionID ON PromotionWindow (promotionID);
@@ -0,0 +1 @@
+ALTER TABLE MembershipSeat DROP COLUMN acceptedAt;
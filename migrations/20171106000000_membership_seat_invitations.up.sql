@@ -0,0 +1,3 @@
+ALTER TABLE MembershipSeat ADD COLUMN acceptedAt TIMESTAMP WITH TIME ZONE;
+
+UPDATE MembershipSeat SET acceptedAt = createdAt;
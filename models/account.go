@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/jteppinette/peragrin-api/common"
 	"github.com/jteppinette/peragrin-api/mail"
 )
 
@@ -24,9 +26,10 @@ type Account struct {
 	LastName  string `json:"lastName"`
 	IsSuper   bool   `json:"isSuper"`
 
-	// Expiration is used to define the time left for a provided membership.
+	// Expiration and Status are used to define the time left for a provided membership.
 	// This information is only useful when in the context of a Membership.
-	Expiration time.Time `json:"expiration,omitempty"`
+	Expiration common.JSONNullTime `json:"expiration"`
+	Status     string              `json:"status,omitempty"`
 }
 
 // Create adds all accounts in the provided slice to the database.
@@ -62,9 +65,14 @@ func (accounts *Accounts) CreateWithMembership(membershipID int, client *sqlx.DB
 		return nil
 	}
 
-	expirations := map[string]time.Time{}
+	now := time.Now()
+	expirations := map[string]common.JSONNullTime{}
 	for _, account := range *accounts {
-		expirations[account.Email] = account.Expiration
+		expiration, err := membershipExpiration(membershipID, account.Expiration, now, client)
+		if err != nil {
+			return err
+		}
+		expirations[account.Email] = expiration
 	}
 
 	statement := "INSERT INTO Account (email, firstName, lastName) VALUES "
@@ -164,7 +172,7 @@ func (a *Account) SendActivationEmail(next, appDomain, tokenSecret, name string,
 		subject = fmt.Sprintf("%s Account Activation", name)
 	}
 
-	return client.Send([]string{a.Email}, subject, fmt.Sprintf("%s/#/auth/activate?token=%s&next=%s", appDomain, token, next))
+	return client.Send([]string{a.Email}, subject, fmt.Sprintf("%s/#/auth/activate?token=%s&next=%s", appDomain, token, url.QueryEscape(next)))
 }
 
 // CreateWithMembership creates a new account with a connection to the
@@ -188,6 +196,11 @@ func (a *Account) CreateWithMembership(membershipID int, client *sqlx.DB) error
 		return err
 	}
 
+	a.Expiration, err = membershipExpiration(membershipID, a.Expiration, time.Now(), tx)
+	if err != nil {
+		return err
+	}
+
 	_, err = tx.Exec("INSERT INTO AccountMembership (accountID, membershipID, expiration) VALUES ($1, $2, $3);", a.ID, membershipID, a.Expiration)
 	if err != nil {
 		return err
@@ -253,20 +266,108 @@ func (a *Account) CreateWithOrganization(organizationID int, client *sqlx.DB) er
 	return nil
 }
 
-// AddMembership adds a new membership to the given account.
+// AddMembership adds a new membership to the given account. The expiration is
+// computed from the membership's plan when one is not provided. Dependents that have
+// a seat of the membership must leave it first.
 func (a *Account) AddMembership(membershipID int, client *sqlx.DB) error {
+	if dependent, err := isDependent(a.ID, membershipID, client); err != nil {
+		return err
+	} else if dependent {
+		return errAccountIsDependent
+	}
+	expiration, err := membershipExpiration(membershipID, a.Expiration, time.Now(), client)
+	if err != nil {
+		return err
+	}
+	a.Expiration = expiration
 	if _, err := client.Exec("INSERT INTO AccountMembership (accountID, membershipID, expiration) VALUES ($1, $2, $3);", a.ID, membershipID, a.Expiration); err != nil {
 		return err
 	}
 	return nil
 }
 
-// RemoveMembership removes a membership from the given account.
+// RenewMembership extends the account's membership by another term of the membership's plan.
+func (a *Account) RenewMembership(membershipID int, client *sqlx.DB) error {
+	tx, err := client.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = a.txRenewMembership(membershipID, false, time.Now(), tx)
+	return err
+}
+
+// txRenewMembership extends the account's membership by another term of the membership's
+// plan. When join is true, accounts without the membership are joined to it instead, and
+// a seat that they had of the membership is given back to its holder.
+func (a *Account) txRenewMembership(membershipID int, join bool, now time.Time, tx *sqlx.Tx) error {
+	m, err := getMembershipByID(membershipID, tx)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return errMembershipNotFound
+	}
+
+	var current common.JSONNullTime
+	err = tx.Get(&current, "SELECT expiration FROM AccountMembership WHERE accountID = $1 AND membershipID = $2 FOR UPDATE;", a.ID, membershipID)
+	exists := err == nil
+	if err == sql.ErrNoRows && !join {
+		return errAccountMembershipNotFound
+	} else if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if a.Expiration, err = m.ExpirationAt(now, current); err != nil {
+		return err
+	}
+
+	if !exists {
+		if _, err := tx.Exec("DELETE FROM MembershipSeat WHERE accountID = $1 AND membershipID = $2;", a.ID, membershipID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO AccountMembership (accountID, membershipID, expiration) VALUES ($1, $2, $3);", a.ID, membershipID, a.Expiration); err != nil {
+			return err
+		}
+		a.Status = m.statusAt(now)
+		return nil
+	}
+	return tx.Get(a, `
+		UPDATE AccountMembership SET expiration = $3
+		FROM Membership WHERE Membership.id = AccountMembership.membershipID
+		AND AccountMembership.accountID = $1 AND AccountMembership.membershipID = $2
+		RETURNING AccountMembership.expiration, `+membershipStatus+` AS status;
+	`, a.ID, membershipID, a.Expiration)
+}
+
+// RemoveMembership removes a membership, and the seats of its dependents, from the given account.
 func (a *Account) RemoveMembership(membershipID int, client *sqlx.DB) error {
-	if _, err := client.Exec("DELETE FROM AccountMembership WHERE accountID = $1 AND membershipID = $2;", a.ID, membershipID); err != nil {
+	tx, err := client.Beginx()
+	if err != nil {
 		return err
 	}
-	return nil
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	if _, err = tx.Exec("DELETE FROM AccountMembership WHERE accountID = $1 AND membershipID = $2;", a.ID, membershipID); err != nil {
+		return err
+	}
+	err = txRemoveDependents(a.ID, membershipID, tx)
+	return err
 }
 
 // AddOrganization adds a new organization to the given account.
@@ -313,8 +414,10 @@ func GetAccountByID(id int, client *sqlx.DB) (*Account, error) {
 func GetAccountsByMembership(membershipID int, client *sqlx.DB) (Accounts, error) {
 	accounts := Accounts{}
 	if err := client.Select(&accounts, `
-		SELECT Account.id, Account.email, Account.firstName, Account.lastName, Account.isSuper, AccountMembership.Expiration
-		FROM Account INNER JOIN AccountMembership ON (Account.id = AccountMembership.accountID)
+		SELECT Account.id, Account.email, Account.firstName, Account.lastName, Account.isSuper, AccountMembership.expiration, `+membershipStatus+` AS status
+		FROM Account
+		INNER JOIN AccountMembership ON (Account.id = AccountMembership.accountID)
+		INNER JOIN Membership ON (AccountMembership.membershipID = Membership.id)
 		WHERE AccountMembership.membershipID = $1
 	`, membershipID); err != nil {
 		return nil, err
@@ -350,3 +453,120 @@ func GetAccountsByEmails(emails []string, client *sqlx.DB) (Accounts, error) {
 	}
 	return accounts, nil
 }
+
+// GetAdministratorsByCommunity returns all accounts that operate an administrative
+// organization of the provided community.
+func GetAdministratorsByCommunity(communityID int, client *sqlx.DB) (Accounts, error) {
+	accounts := Accounts{}
+	if err := client.Select(&accounts, `
+		SELECT DISTINCT Account.id, Account.email, Account.firstName, Account.lastName, Account.isSuper
+		FROM Account
+		INNER JOIN AccountOrganization ON (Account.id = AccountOrganization.accountID)
+		INNER JOIN CommunityOrganization ON (AccountOrganization.organizationID = CommunityOrganization.organizationID)
+		WHERE CommunityOrganization.communityID = $1 AND CommunityOrganization.isAdministrator AND CommunityOrganization.status = 'approved';
+	`, communityID); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// GetAdministratorsByOrganization returns all accounts that operate an administrative
+// organization of any community that the provided organization is a member of.
+func GetAdministratorsByOrganization(organizationID int, client *sqlx.DB) (Accounts, error) {
+	accounts := Accounts{}
+	if err := client.Select(&accounts, `
+		SELECT DISTINCT Account.id, Account.email, Account.firstName, Account.lastName, Account.isSuper
+		FROM Account
+		INNER JOIN AccountOrganization ON (Account.id = AccountOrganization.accountID)
+		INNER JOIN CommunityOrganization AS Administrator ON (AccountOrganization.organizationID = Administrator.organizationID AND Administrator.isAdministrator AND Administrator.status = 'approved')
+		INNER JOIN CommunityOrganization AS Member ON (Administrator.communityID = Member.communityID AND Member.status = 'approved')
+		WHERE Member.organizationID = $1;
+	`, organizationID); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// GetSuperUsers returns every super user. They review the claims of organizations that are
+// not a member of a community.
+func GetSuperUsers(client *sqlx.DB) (Accounts, error) {
+	accounts := Accounts{}
+	if err := client.Select(&accounts, "SELECT id, email, firstName, lastName, isSuper FROM Account WHERE isSuper;"); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// IsCommunityAdministrator determines if the given account operates an administrative
+// organization of the provided community. Super users administer every community.
+func (a *Account) IsCommunityAdministrator(communityID int, client *sqlx.DB) (bool, error) {
+	if a.IsSuper {
+		return true, nil
+	}
+	var exists bool
+	if err := client.Get(&exists, `
+		SELECT EXISTS(
+			SELECT FROM AccountOrganization
+			INNER JOIN CommunityOrganization ON (AccountOrganization.organizationID = CommunityOrganization.organizationID)
+			WHERE AccountOrganization.accountID = $1 AND CommunityOrganization.communityID = $2
+			AND CommunityOrganization.isAdministrator AND CommunityOrganization.status = 'approved'
+		);
+	`, a.ID, communityID); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// IsOrganizationAdministrator determines if the given account administers any community
+// that the provided organization is a member of. Super users administer every organization.
+func (a *Account) IsOrganizationAdministrator(organizationID int, client *sqlx.DB) (bool, error) {
+	if a.IsSuper {
+		return true, nil
+	}
+	var exists bool
+	if err := client.Get(&exists, `
+		SELECT EXISTS(
+			SELECT FROM AccountOrganization
+			INNER JOIN CommunityOrganization AS Administrator ON (AccountOrganization.organizationID = Administrator.organizationID AND Administrator.isAdministrator AND Administrator.status = 'approved')
+			INNER JOIN CommunityOrganization AS Member ON (Administrator.communityID = Member.communityID AND Member.status = 'approved')
+			WHERE AccountOrganization.accountID = $1 AND Member.organizationID = $2
+		);
+	`, a.ID, organizationID); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// IsCommunityOperator determines if the given account operates an approved member
+// organization of the provided community. Community administrators operate every
+// community that they administer.
+func (a *Account) IsCommunityOperator(communityID int, client *sqlx.DB) (bool, error) {
+	if a.IsSuper {
+		return true, nil
+	}
+	var exists bool
+	if err := client.Get(&exists, `
+		SELECT EXISTS(
+			SELECT FROM AccountOrganization
+			INNER JOIN CommunityOrganization ON (AccountOrganization.organizationID = CommunityOrganization.organizationID)
+			WHERE AccountOrganization.accountID = $1 AND CommunityOrganization.communityID = $2
+			AND CommunityOrganization.status = 'approved'
+		);
+	`, a.ID, communityID); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// IsOrganizationOperator determines if the given account operates the provided organization.
+// Super users operate every organization.
+func (a *Account) IsOrganizationOperator(organizationID int, client *sqlx.DB) (bool, error) {
+	if a.IsSuper {
+		return true, nil
+	}
+	var exists bool
+	if err := client.Get(&exists, "SELECT EXISTS(SELECT FROM AccountOrganization WHERE accountID = $1 AND organizationID = $2);", a.ID, organizationID); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
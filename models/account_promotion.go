@@ -13,17 +13,10 @@ type AccountPromotion struct {
 	ConsumedAt  time.Time `json:"consumedAt"`
 }
 
-// Create inserts a new account promotion relationship in the database.
-func (ap *AccountPromotion) Create(client *sqlx.DB) error {
-	return client.Get(ap, `
-		INSERT INTO AccountPromotion (accountID, promotionID)
-		VALUES ($1, $2)
-		RETURNING *;
-	`, ap.AccountID, ap.PromotionID)
-}
-
 // HasPermission determines if the provided account has access to redeem
-// the provided promotion.
+// the provided promotion. Expired memberships do not grant access once their
+// grace period ends, including to the dependents that have a seat of them. Seats that were
+// not accepted yet do not grant access.
 func (ap *AccountPromotion) HasPermission(client *sqlx.DB) (bool, error) {
 	result := struct {
 		Exists   bool
@@ -34,9 +27,15 @@ func (ap *AccountPromotion) HasPermission(client *sqlx.DB) (bool, error) {
 		SELECT
 			EXISTS(
 				SELECT FROM AccountMembership
-				LEFT OUTER JOIN Membership ON (AccountMembership.membershipID = Membership.id)
-				LEFT OUTER JOIN CommunityPromotion ON (Membership.communityID = CommunityPromotion.communityID)
-				WHERE CommunityPromotion.promotionID = $1 AND AccountMembership.accountID = $2
+				INNER JOIN Membership ON (AccountMembership.membershipID = Membership.id)
+				INNER JOIN CommunityPromotion ON (Membership.communityID = CommunityPromotion.communityID)
+				WHERE CommunityPromotion.promotionID = $1 AND AccountMembership.accountID = $2 AND `+activeMembership+`
+			) OR EXISTS(
+				SELECT FROM MembershipSeat
+				INNER JOIN AccountMembership ON (MembershipSeat.primaryAccountID = AccountMembership.accountID AND MembershipSeat.membershipID = AccountMembership.membershipID)
+				INNER JOIN Membership ON (AccountMembership.membershipID = Membership.id)
+				INNER JOIN CommunityPromotion ON (Membership.communityID = CommunityPromotion.communityID)
+				WHERE CommunityPromotion.promotionID = $1 AND MembershipSeat.accountID = $2 AND MembershipSeat.acceptedAt IS NOT NULL AND `+activeMembership+`
 			) AS exists,
 			EXISTS(SELECT FROM CommunityPromotion WHERE promotionID = $1) AS required;
 	`, ap.PromotionID, ap.AccountID); err != nil {
@@ -1,10 +1,9 @@
 package models
 
 import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strconv"
+	"strings"
+
+	"github.com/jteppinette/peragrin-api/geocoder"
 )
 
 // Address represents a physical location in the world.
@@ -16,28 +15,164 @@ type Address struct {
 	Zip     string `json:"zip"`
 }
 
-func (a Address) Geocode(key string) (float64, float64, error) {
-	r, err := http.Get(fmt.Sprintf("http://locationiq.org/v1/search.php?key=%s&format=json&limit=1&street=%s&city=%s&state=%s&country=%s&postalcode=%s", key, a.Street, a.City, a.State, a.Country, a.Zip))
-	if err != nil {
-		return 0, 0, err
+// Geocode looks up the location of the address with the provided geocoder.
+func (a Address) Geocode(g geocoder.Geocoder) (geocoder.Result, error) {
+	result, err := g.Geocode(geocoder.Address(a))
+	if err == geocoder.ErrNotFound {
+		return result, errGeocodeNotFound
+	}
+	return result, err
+}
+
+// Normalize returns the address in a consistent format. Whitespace is collapsed, street
+// suffixes and directions are abbreviated, countries are written as ISO 3166 codes, and
+// states of the United States and provinces of Canada are written as postal codes.
+func (a Address) Normalize() Address {
+	a.Street = normalizeStreet(a.Street)
+	a.City = collapse(a.City)
+	a.Zip = strings.ToUpper(collapse(a.Zip))
+	a.Country = normalizeCountry(a.Country)
+	a.State = normalizeState(a.State, a.Country)
+	return a
+}
+
+// Equal reports whether the addresses refer to the same location, ignoring differences in case,
+// punctuation, and whitespace.
+func (a Address) Equal(b Address) bool {
+	return geocoder.Normalize(geocoder.Address(a)) == geocoder.Normalize(geocoder.Address(b))
+}
+
+// fill returns the address with its empty fields set from b.
+func (a Address) fill(b Address) Address {
+	if a.Street == "" {
+		a.Street = b.Street
+	}
+	if a.City == "" {
+		a.City = b.City
 	}
-	defer r.Body.Close()
+	if a.State == "" {
+		a.State = b.State
+	}
+	if a.Country == "" {
+		a.Country = b.Country
+	}
+	if a.Zip == "" {
+		a.Zip = b.Zip
+	}
+	return a
+}
 
-	if r.StatusCode != http.StatusOK {
-		return 0, 0, fmt.Errorf("expected response to be HTTP 200, received %s", r.Status)
+func collapse(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func addressKey(value string) string {
+	return strings.ToLower(strings.Trim(collapse(strings.Replace(value, ".", "", -1)), " ,"))
+}
+
+// normalizeStreet abbreviates the street suffix and any leading or trailing direction,
+// e.g. "123 north main street." becomes "123 N main St".
+func normalizeStreet(street string) string {
+	words := strings.Fields(strings.TrimSuffix(collapse(street), "."))
+	if len(words) < 2 {
+		return strings.Join(words, " ")
 	}
 
-	codes := []struct {
-		Lon string `json:"lon"`
-		Lat string `json:"lat"`
-	}{}
-	if err := json.NewDecoder(r.Body).Decode(&codes); err != nil {
-		return 0, 0, err
+	last := len(words) - 1
+	if d, ok := streetDirections[addressKey(words[last])]; ok {
+		words[last] = d
+		last--
+	}
+	if last > 0 {
+		if s, ok := streetSuffixes[addressKey(words[last])]; ok {
+			words[last] = s
+		}
 	}
-	for _, code := range codes {
-		lon, _ := strconv.ParseFloat(code.Lon, 64)
-		lat, _ := strconv.ParseFloat(code.Lat, 64)
-		return lon, lat, nil
+	if d, ok := streetDirections[addressKey(words[1])]; ok && last > 2 {
+		words[1] = d
 	}
-	return 0, 0, errGeocodeNotFound
+	return strings.Join(words, " ")
+}
+
+func normalizeCountry(country string) string {
+	if code, ok := countries[addressKey(country)]; ok {
+		return code
+	}
+	return collapse(country)
+}
+
+func normalizeState(state, country string) string {
+	k := addressKey(state)
+	switch country {
+	case "US", "":
+		if code, ok := states[k]; ok {
+			return code
+		}
+	case "CA":
+		if code, ok := provinces[k]; ok {
+			return code
+		}
+	}
+	if len(k) == 2 && (country == "US" || country == "CA") {
+		return strings.ToUpper(k)
+	}
+	return collapse(state)
+}
+
+var streetSuffixes = map[string]string{
+	"alley": "Aly", "aly": "Aly",
+	"avenue": "Ave", "ave": "Ave", "av": "Ave",
+	"boulevard": "Blvd", "blvd": "Blvd",
+	"circle": "Cir", "cir": "Cir",
+	"court": "Ct", "ct": "Ct",
+	"drive": "Dr", "dr": "Dr",
+	"expressway": "Expy", "expy": "Expy",
+	"highway": "Hwy", "hwy": "Hwy",
+	"lane": "Ln", "ln": "Ln",
+	"parkway": "Pkwy", "pkwy": "Pkwy",
+	"place": "Pl", "pl": "Pl",
+	"road": "Rd", "rd": "Rd",
+	"square": "Sq", "sq": "Sq",
+	"street": "St", "st": "St",
+	"terrace": "Ter", "ter": "Ter",
+	"trail": "Trl", "trl": "Trl",
+	"way": "Way",
+}
+
+var streetDirections = map[string]string{
+	"north": "N", "n": "N",
+	"south": "S", "s": "S",
+	"east": "E", "e": "E",
+	"west": "W", "w": "W",
+	"northeast": "NE", "ne": "NE",
+	"northwest": "NW", "nw": "NW",
+	"southeast": "SE", "se": "SE",
+	"southwest": "SW", "sw": "SW",
+}
+
+var countries = map[string]string{
+	"us": "US", "usa": "US", "united states": "US", "united states of america": "US", "america": "US",
+	"ca": "CA", "can": "CA", "canada": "CA",
+	"mx": "MX", "mex": "MX", "mexico": "MX",
+	"gb": "GB", "uk": "GB", "united kingdom": "GB", "great britain": "GB",
+}
+
+var states = map[string]string{
+	"alabama": "AL", "alaska": "AK", "arizona": "AZ", "arkansas": "AR", "california": "CA",
+	"colorado": "CO", "connecticut": "CT", "delaware": "DE", "district of columbia": "DC", "florida": "FL",
+	"georgia": "GA", "hawaii": "HI", "idaho": "ID", "illinois": "IL", "indiana": "IN",
+	"iowa": "IA", "kansas": "KS", "kentucky": "KY", "louisiana": "LA", "maine": "ME",
+	"maryland": "MD", "massachusetts": "MA", "michigan": "MI", "minnesota": "MN", "mississippi": "MS",
+	"missouri": "MO", "montana": "MT", "nebraska": "NE", "nevada": "NV", "new hampshire": "NH",
+	"new jersey": "NJ", "new mexico": "NM", "new york": "NY", "north carolina": "NC", "north dakota": "ND",
+	"ohio": "OH", "oklahoma": "OK", "oregon": "OR", "pennsylvania": "PA", "rhode island": "RI",
+	"south carolina": "SC", "south dakota": "SD", "tennessee": "TN", "texas": "TX", "utah": "UT",
+	"vermont": "VT", "virginia": "VA", "washington": "WA", "west virginia": "WV", "wisconsin": "WI",
+	"wyoming": "WY", "puerto rico": "PR",
+}
+
+var provinces = map[string]string{
+	"alberta": "AB", "british columbia": "BC", "manitoba": "MB", "new brunswick": "NB",
+	"newfoundland and labrador": "NL", "nova scotia": "NS", "ontario": "ON", "prince edward island": "PE",
+	"quebec": "QC", "saskatchewan": "SK", "northwest territories": "NT", "nunavut": "NU", "yukon": "YT",
 }
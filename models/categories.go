@@ -1,6 +1,22 @@
 package models
 
+import "strings"
+
 const (
 	Restaurant    = "Restaurant"
 	Entertainment = "Entertainment"
 )
+
+// Categories is the list of all supported organization categories.
+var Categories = []string{Restaurant, Entertainment}
+
+// NormalizeCategory returns the supported category that matches the provided
+// value regardless of case. If the value is not supported, then false is returned.
+func NormalizeCategory(value string) (string, bool) {
+	for _, category := range Categories {
+		if strings.EqualFold(category, strings.TrimSpace(value)) {
+			return category, true
+		}
+	}
+	return "", false
+}
@@ -6,6 +6,9 @@ import (
 	"strconv"
 
 	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/common"
+	"github.com/jteppinette/peragrin-api/geometry"
 )
 
 // Communities represents a list of community objects.
@@ -20,15 +23,50 @@ type Community struct {
 	Lat  float64 `json:"lat"`
 	Zoom int     `json:"zoom"`
 
+	// Slug is the community's unique name in urls. It is derived from the name when a
+	// community is created without one, and it is kept when an update does not provide
+	// one. Previous slugs continue to resolve to the community.
+	Slug string `json:"slug"`
+
+	// Boundary is an optional GeoJSON Polygon or MultiPolygon that
+	// encloses every member organization.
+	Boundary common.JSONNullText `json:"boundary"`
+
+	// Logo, PrimaryColor, and WelcomeText brand the community's pages. The logo
+	// can only be changed by uploading a new one.
+	Logo         string `json:"logo"`
+	PrimaryColor string `json:"primaryColor"`
+	WelcomeText  string `json:"welcomeText"`
+
+	Email   string `json:"email"`
+	Phone   string `json:"phone"`
+	Website string `json:"website"`
+
+	// Timezone is the IANA name of the community's local time zone, and Locale is
+	// the BCP 47 language tag used when presenting the community.
+	Timezone string `json:"timezone"`
+	Locale   string `json:"locale"`
+
+	// LogoURL is used to send the presigned Logo url to the client.
+	LogoURL string `json:"logoURL"`
+
 	// IsAdministrator is only populated when this community
 	// is in the context of an organization.
 	IsAdministrator *bool `json:"isAdministrator,omitempty"`
+
+	// JoinStatus is only populated when this community is in the
+	// context of an organization that has requested to join it.
+	JoinStatus *string `json:"joinStatus,omitempty"`
 }
 
 // CreateWithOrganization persists the provided community in the database, and it creates
 // the relationship to the provided organization. This will be an administrative
 // relationship.
 func (c *Community) CreateWithOrganization(organizationID int, client *sqlx.DB) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
 	tx, err := client.Beginx()
 	if err != nil {
 		return err
@@ -42,7 +80,7 @@ func (c *Community) CreateWithOrganization(organizationID int, client *sqlx.DB)
 		tx.Commit()
 	}()
 
-	err = tx.Get(c, "INSERT INTO Community (name, lon, lat, zoom) VALUES ($1, $2, $3, $4) RETURNING *;", c.Name, c.Lon, c.Lat, c.Zoom)
+	err = c.txCreate(tx)
 	if err != nil {
 		return err
 	}
@@ -57,12 +95,116 @@ func (c *Community) CreateWithOrganization(organizationID int, client *sqlx.DB)
 
 // Create adds a new community to the database.
 func (c *Community) Create(client *sqlx.DB) error {
-	return client.Get(c, "INSERT INTO Community (name, lon, lat, zoom) VALUES ($1, $2, $3, $4) RETURNING *;", c.Name, c.Lon, c.Lat, c.Zoom)
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := client.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = c.txCreate(tx)
+	return err
 }
 
-// Update updates a community in the database.
+func (c *Community) txCreate(tx *sqlx.Tx) error {
+	if err := c.txSetSlug(tx); err != nil {
+		return err
+	}
+	return tx.Get(c, `
+		INSERT INTO Community (name, slug, lon, lat, zoom, boundary, primaryColor, welcomeText, email, phone, website, timezone, locale)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING *;
+	`, c.Name, c.Slug, c.Lon, c.Lat, c.Zoom, c.Boundary, c.PrimaryColor, c.WelcomeText, c.Email, c.Phone, c.Website, c.Timezone, c.Locale)
+}
+
+// Update updates a community in the database. An empty slug keeps the community's current
+// slug. When the community's slug changes, the previous slug is kept as a redirect.
 func (c *Community) Update(client *sqlx.DB) error {
-	return client.Get(c, "UPDATE Community SET name = $2, lon = $3, lat = $4, zoom = $5 WHERE id = $1 RETURNING *;", c.ID, c.Name, c.Lon, c.Lat, c.Zoom)
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := client.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	var previous string
+	err = tx.Get(&previous, "SELECT slug FROM Community WHERE id = $1 FOR UPDATE;", c.ID)
+	if err != nil {
+		return err
+	}
+	if c.Slug == "" {
+		c.Slug = previous
+	}
+	err = c.txSetSlug(tx)
+	if err != nil {
+		return err
+	}
+	err = tx.Get(c, `
+		UPDATE Community
+		SET name = $2, slug = $3, lon = $4, lat = $5, zoom = $6, boundary = $7,
+			primaryColor = $8, welcomeText = $9, email = $10, phone = $11, website = $12, timezone = $13, locale = $14
+		WHERE id = $1
+		RETURNING *;
+	`, c.ID, c.Name, c.Slug, c.Lon, c.Lat, c.Zoom, c.Boundary, c.PrimaryColor, c.WelcomeText, c.Email, c.Phone, c.Website, c.Timezone, c.Locale)
+	if err != nil {
+		return err
+	}
+	err = c.txRedirectSlug(previous, tx)
+	return err
+}
+
+// ParseBoundary returns the community's boundary. If the community does not have a
+// boundary, then nil is returned.
+func (c *Community) ParseBoundary() (geometry.MultiPolygon, error) {
+	if !c.Boundary.Valid {
+		return nil, nil
+	}
+	return geometry.ParseMultiPolygon(c.Boundary.JSONText)
+}
+
+// Contains reports whether the provided coordinates are inside of the community's boundary.
+// Communities without a boundary contain every location.
+func (c *Community) Contains(lon, lat float64) (bool, error) {
+	boundary, err := c.ParseBoundary()
+	if err != nil || boundary == nil {
+		return true, err
+	}
+	return boundary.Contains(geometry.Point{lon, lat}), nil
+}
+
+// OrganizationsOutside returns the provided organizations that are outside of the community's boundary.
+func (c *Community) OrganizationsOutside(organizations Organizations) (Organizations, error) {
+	outside := Organizations{}
+	boundary, err := c.ParseBoundary()
+	if err != nil || boundary == nil {
+		return outside, err
+	}
+	for _, o := range organizations {
+		if !boundary.Contains(geometry.Point{o.Lon, o.Lat}) {
+			outside = append(outside, o)
+		}
+	}
+	return outside, nil
 }
 
 // GetCommunities returns all communities in the database.
@@ -75,11 +217,48 @@ func GetCommunities(client *sqlx.DB) (Communities, error) {
 	return communities, nil
 }
 
+// checkBoundary returns an error if the provided coordinates are outside of the
+// given community's boundary.
+func checkBoundary(communityID int, lon, lat float64, client *sqlx.DB) error {
+	c, err := GetCommunityByID(communityID, client)
+	if err != nil {
+		return err
+	}
+	if ok, err := c.Contains(lon, lat); err != nil {
+		return err
+	} else if !ok {
+		return errOrganizationOutsideBoundary
+	}
+	return nil
+}
+
+// GetCommunitiesByLocation returns all communities with a boundary that contains the
+// provided coordinates. Communities without a boundary are never returned.
+func GetCommunitiesByLocation(lon, lat float64, client *sqlx.DB) (Communities, error) {
+	candidates := Communities{}
+	if err := client.Select(&candidates, "SELECT * FROM Community WHERE boundary IS NOT NULL;"); err != nil {
+		return nil, err
+	}
+
+	point := geometry.Point{lon, lat}
+	communities := Communities{}
+	for _, c := range candidates {
+		boundary, err := c.ParseBoundary()
+		if err != nil {
+			return nil, err
+		}
+		if boundary.Bounds().Contains(point) && boundary.Contains(point) {
+			communities = append(communities, c)
+		}
+	}
+	return communities, nil
+}
+
 // GetCommunitiesByOrganization returns all communities with a relationship
 // to the provided organization.
 func GetCommunitiesByOrganization(organizationID int, client *sqlx.DB) (Communities, error) {
 	communities := Communities{}
-	if err := client.Select(&communities, "SELECT Community.*, CommunityOrganization.isAdministrator FROM Community INNER JOIN CommunityOrganization ON (Community.id = CommunityOrganization.communityID) WHERE organizationID = $1", organizationID); err != nil {
+	if err := client.Select(&communities, "SELECT Community.*, CommunityOrganization.isAdministrator, CommunityOrganization.status AS joinStatus FROM Community INNER JOIN CommunityOrganization ON (Community.id = CommunityOrganization.communityID) WHERE organizationID = $1", organizationID); err != nil {
 		return nil, err
 	}
 	return communities, nil
@@ -125,7 +304,7 @@ func GetCommunitiesByAccount(accountID int, query url.Values, client *sqlx.DB) (
 		SELECT DISTINCT ON (Community.id) Community.*, CommunityOrganization.isAdministrator FROM Community
 		INNER JOIN CommunityOrganization ON (Community.id = CommunityOrganization.communityID)
 		INNER JOIN AccountOrganization ON (CommunityOrganization.organizationID = AccountOrganization.organizationID)
-		WHERE AccountOrganization.accountID = $1 %s ORDER BY Community.id, CommunityOrganization.isAdministrator DESC;
+		WHERE AccountOrganization.accountID = $1 AND CommunityOrganization.status = 'approved' %s ORDER BY Community.id, CommunityOrganization.isAdministrator DESC;
 	`
 	if b, err := strconv.ParseBool(query.Get("isAdministrator")); err != nil {
 		if err := client.Select(&communities, fmt.Sprintf(format, ""), accountID); err != nil {
@@ -1,23 +1,115 @@
 package models
 
 import (
+	"database/sql"
+	"time"
+
 	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/common"
+)
+
+const (
+	// JoinStatusPending relationships are waiting on a community administrator's decision.
+	JoinStatusPending = "pending"
+	// JoinStatusApproved relationships make the organization a member of the community.
+	JoinStatusApproved = "approved"
+	// JoinStatusRejected relationships have been declined by a community administrator.
+	JoinStatusRejected = "rejected"
 )
 
 // CommunityOrganization represents the relationship between organizations and communities.
+// An organization is only a member of a community once the relationship has been approved.
 type CommunityOrganization struct {
-	OrganizationID  int
-	CommunityID     int
-	IsAdministrator bool
+	OrganizationID  int                 `json:"organizationID"`
+	CommunityID     int                 `json:"communityID"`
+	IsAdministrator bool                `json:"isAdministrator"`
+	Status          string              `json:"status"`
+	Reason          string              `json:"reason"`
+	RequestedAt     time.Time           `json:"requestedAt"`
+	ResolvedAt      common.JSONNullTime `json:"resolvedAt"`
+
+	// Organization is only populated when listing join requests.
+	Organization *Organization `json:"organization,omitempty" db:"-"`
 }
 
-// Create inserts a new community organization relationship in the database.
+// Create inserts a new approved community organization relationship in the database. A previously
+// rejected relationship is approved instead. ErrJoinRequestExists is returned if the organization
+// already has a pending or approved relationship with the community.
 func (co *CommunityOrganization) Create(client *sqlx.DB) error {
-	return client.Get(co, `
-		INSERT INTO CommunityOrganization (organizationID, communityID, isAdministrator)
-		VALUES ($1, $2, $3)
+	if err := co.checkBoundary(client); err != nil {
+		return err
+	}
+	err := client.Get(co, `
+		INSERT INTO CommunityOrganization (organizationID, communityID, isAdministrator, status, resolvedAt)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (communityID, organizationID) DO UPDATE
+		SET isAdministrator = EXCLUDED.isAdministrator, status = EXCLUDED.status, reason = '', resolvedAt = EXCLUDED.resolvedAt
+		WHERE CommunityOrganization.status = $5
+		RETURNING *;
+	`, co.OrganizationID, co.CommunityID, co.IsAdministrator, JoinStatusApproved, JoinStatusRejected)
+	if err == sql.ErrNoRows {
+		return ErrJoinRequestExists
+	}
+	return err
+}
+
+// Request inserts a new pending community organization relationship in the database. A previously
+// rejected relationship will be reset to pending so that the organization may ask again.
+// ErrJoinRequestExists is returned if the request is already pending or has been approved.
+func (co *CommunityOrganization) Request(client *sqlx.DB) error {
+	err := client.Get(co, `
+		UPDATE CommunityOrganization SET status = $3, reason = '', requestedAt = NOW(), resolvedAt = NULL
+		WHERE organizationID = $1 AND communityID = $2 AND status = $4
+		RETURNING *;
+	`, co.OrganizationID, co.CommunityID, JoinStatusPending, JoinStatusRejected)
+	if err != sql.ErrNoRows {
+		return err
+	}
+	err = client.Get(co, `
+		INSERT INTO CommunityOrganization (organizationID, communityID, isAdministrator, status)
+		VALUES ($1, $2, FALSE, $3)
+		ON CONFLICT DO NOTHING
+		RETURNING *;
+	`, co.OrganizationID, co.CommunityID, JoinStatusPending)
+	if err == sql.ErrNoRows {
+		return ErrJoinRequestExists
+	}
+	return err
+}
+
+// Approve makes the organization a member of the community.
+func (co *CommunityOrganization) Approve(client *sqlx.DB) error {
+	if err := co.checkBoundary(client); err != nil {
+		return err
+	}
+	return co.resolve(JoinStatusApproved, "", client)
+}
+
+// checkBoundary returns an error if the organization is outside of the community's boundary.
+func (co *CommunityOrganization) checkBoundary(client *sqlx.DB) error {
+	o, err := GetOrganizationByID(co.OrganizationID, client)
+	if err != nil {
+		return err
+	}
+	return checkBoundary(co.CommunityID, o.Lon, o.Lat, client)
+}
+
+// Reject declines the organization's request to join the community.
+func (co *CommunityOrganization) Reject(reason string, client *sqlx.DB) error {
+	return co.resolve(JoinStatusRejected, reason, client)
+}
+
+func (co *CommunityOrganization) resolve(status, reason string, client *sqlx.DB) error {
+	err := client.Get(co, `
+		UPDATE CommunityOrganization SET status = $3, reason = $4, resolvedAt = NOW()
+		WHERE organizationID = $1 AND communityID = $2 AND status = $5
 		RETURNING *;
-	`, co.OrganizationID, co.CommunityID, co.IsAdministrator)
+	`, co.OrganizationID, co.CommunityID, status, reason, JoinStatusPending)
+	if err == sql.ErrNoRows {
+		return errJoinRequestNotPending
+	}
+	return err
 }
 
 // Delete removes a community organization relationship from the database.
@@ -27,3 +119,32 @@ func (co *CommunityOrganization) Delete(client *sqlx.DB) error {
 	}
 	return nil
 }
+
+// GetCommunityOrganization returns the relationship between the provided community
+// and organization, or nil if one does not exist.
+func GetCommunityOrganization(communityID, organizationID int, client *sqlx.DB) (*CommunityOrganization, error) {
+	co := &CommunityOrganization{}
+	if err := client.Get(co, "SELECT * FROM CommunityOrganization WHERE communityID = $1 AND organizationID = $2;", communityID, organizationID); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return co, nil
+}
+
+// GetPendingCommunityOrganizations returns all pending join requests for the provided community
+// with the requesting organization populated. The oldest requests are returned first.
+func GetPendingCommunityOrganizations(communityID int, client *sqlx.DB) ([]CommunityOrganization, error) {
+	requests := []CommunityOrganization{}
+	if err := client.Select(&requests, "SELECT * FROM CommunityOrganization WHERE communityID = $1 AND status = $2 ORDER BY requestedAt;", communityID, JoinStatusPending); err != nil {
+		return nil, err
+	}
+	for i, request := range requests {
+		organization, err := GetOrganizationByID(request.OrganizationID, client)
+		if err != nil {
+			return nil, err
+		}
+		requests[i].Organization = &organization
+	}
+	return requests, nil
+}
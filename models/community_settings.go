@@ -0,0 +1,207 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/store"
+)
+
+const (
+	// DefaultTimezone is used by communities that have not set their time zone.
+	DefaultTimezone = "UTC"
+	// DefaultLocale is used by communities that have not set their locale.
+	DefaultLocale = "en-US"
+	// MaxWelcomeTextLength is the longest welcome text, in characters, that a community may have.
+	MaxWelcomeTextLength = 2000
+)
+
+var (
+	slugPattern    = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+	localePattern  = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+	hexColor       = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+)
+
+// Slugify returns a url safe form of the provided name, e.g. "St. John's Market" becomes
+// "st-john-s-market". Names without any letters or numbers become "community".
+func Slugify(name string) string {
+	slug := strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		return "community"
+	}
+	return slug
+}
+
+// Validate checks the community's boundary and settings. Empty time zones and locales
+// are set to their defaults.
+func (c *Community) Validate() error {
+	if _, err := c.ParseBoundary(); err != nil {
+		return err
+	}
+	if c.Slug != "" && !slugPattern.MatchString(c.Slug) {
+		return errSlugInvalid
+	}
+	if c.PrimaryColor != "" && !hexColor.MatchString(c.PrimaryColor) {
+		return errPrimaryColorInvalid
+	}
+	if len([]rune(c.WelcomeText)) > MaxWelcomeTextLength {
+		return errWelcomeTextTooLong
+	}
+	if c.Email != "" {
+		if _, err := mail.ParseAddress(c.Email); err != nil {
+			return errCommunityEmailInvalid
+		}
+	}
+	if c.Website != "" {
+		if u, err := url.Parse(c.Website); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errCommunityWebsiteInvalid
+		}
+	}
+	if c.Timezone == "" {
+		c.Timezone = DefaultTimezone
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return errTimezoneInvalid
+	}
+	if c.Locale == "" {
+		c.Locale = DefaultLocale
+	}
+	if !localePattern.MatchString(c.Locale) {
+		return errLocaleInvalid
+	}
+	return nil
+}
+
+// Location returns the community's time zone. UTC is returned if the time zone cannot be loaded.
+func (c *Community) Location() *time.Location {
+	if loc, err := time.LoadLocation(c.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// MapPath returns the app path of the community's map. The community is identified by its
+// id, so that existing links keep working, and its slug is included for readable urls.
+func (c *Community) MapPath() string {
+	return "/map?" + url.Values{"community": {strconv.Itoa(c.ID)}, "slug": {c.Slug}}.Encode()
+}
+
+// MapURL returns the url of the community's map in the app at appDomain.
+func (c *Community) MapURL(appDomain string) string {
+	return appDomain + "/#" + c.MapPath()
+}
+
+// txSetSlug makes sure that the community has a slug that is not used by any other community,
+// including as a redirect. Slugs that were provided are rejected when they are taken, while
+// slugs derived from the community's name are numbered until one is available.
+func (c *Community) txSetSlug(tx *sqlx.Tx) error {
+	const taken = `
+		SELECT EXISTS (SELECT 1 FROM Community WHERE slug = $1 AND id <> $2)
+			OR EXISTS (SELECT 1 FROM CommunitySlugRedirect WHERE slug = $1 AND communityID <> $2);
+	`
+
+	if c.Slug != "" {
+		var exists bool
+		if err := tx.Get(&exists, taken, c.Slug, c.ID); err != nil {
+			return err
+		}
+		if exists {
+			return errSlugTaken
+		}
+		return nil
+	}
+
+	base := Slugify(c.Name)
+	for n := 1; ; n++ {
+		slug := base
+		if n > 1 {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		var exists bool
+		if err := tx.Get(&exists, taken, slug, c.ID); err != nil {
+			return err
+		}
+		if !exists {
+			c.Slug = slug
+			return nil
+		}
+	}
+}
+
+// txRedirectSlug keeps the community's previous slug as a redirect when it changed. A slug
+// that the community takes back is no longer a redirect.
+func (c *Community) txRedirectSlug(previous string, tx *sqlx.Tx) error {
+	if _, err := tx.Exec("DELETE FROM CommunitySlugRedirect WHERE slug = $1;", c.Slug); err != nil {
+		return err
+	}
+	if previous == "" || previous == c.Slug {
+		return nil
+	}
+	_, err := tx.Exec("INSERT INTO CommunitySlugRedirect (slug, communityID) VALUES ($1, $2) ON CONFLICT (slug) DO NOTHING;", previous, c.ID)
+	return err
+}
+
+// GetCommunityBySlug returns the community with the provided slug, or nil if one does not exist.
+// Previous slugs also return their community, in which case moved is true.
+func GetCommunityBySlug(slug string, client *sqlx.DB) (community *Community, moved bool, err error) {
+	community = &Community{}
+	err = client.Get(community, "SELECT * FROM Community WHERE slug = $1;", slug)
+	if err == nil {
+		return community, false, nil
+	} else if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	err = client.Get(community, `
+		SELECT Community.* FROM Community
+		INNER JOIN CommunitySlugRedirect ON (Community.id = CommunitySlugRedirect.communityID)
+		WHERE CommunitySlugRedirect.slug = $1;
+	`, slug)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return community, true, nil
+}
+
+// UploadLogo puts the community's logo in the static store and records its name.
+func (c *Community) UploadLogo(name string, reader io.Reader, storeClient store.Store, client *sqlx.DB) error {
+	if err := storeClient.Put(fmt.Sprintf("community-logos/%d-%s", c.ID, name), reader, "application/octet-stream"); err != nil {
+		return err
+	}
+	return client.Get(c, "UPDATE Community SET logo = $2 WHERE id = $1 RETURNING *;", c.ID, name)
+}
+
+// SetPresignedLogoLink sets the LogoURL field with a presigned get request url.
+func (c *Community) SetPresignedLogoLink(client store.Store) error {
+	if c.Logo == "" {
+		return nil
+	}
+	url, err := client.Presign(fmt.Sprintf("community-logos/%d-%s", c.ID, c.Logo), time.Second*24*60*60)
+	if err != nil {
+		return err
+	}
+	c.LogoURL = url
+	return nil
+}
+
+// SetPresignedLogoLinks sets the LogoURL field with a presigned get request url for each community provided.
+func (communities Communities) SetPresignedLogoLinks(client store.Store) error {
+	for i := range communities {
+		if err := communities[i].SetPresignedLogoLink(client); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Richmond":            "richmond",
+		"St. John's Market!":  "st-john-s-market",
+		"  Old   Town -- RVA": "old-town-rva",
+		"東京":                  "community",
+	}
+	for name, expected := range tests {
+		if actual := Slugify(name); actual != expected {
+			t.Errorf("expected %q to be slugified as %q, got %q", name, expected, actual)
+		}
+	}
+}
+
+func TestCommunityValidate(t *testing.T) {
+	c := Community{Name: "Richmond"}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Timezone != DefaultTimezone || c.Locale != DefaultLocale {
+		t.Errorf("expected default timezone and locale, got %q and %q", c.Timezone, c.Locale)
+	}
+
+	tests := []struct {
+		community Community
+		err       error
+	}{
+		{Community{Slug: "Richmond VA"}, errSlugInvalid},
+		{Community{PrimaryColor: "blue"}, errPrimaryColorInvalid},
+		{Community{Email: "not an email"}, errCommunityEmailInvalid},
+		{Community{Website: "ftp://example.com"}, errCommunityWebsiteInvalid},
+		{Community{Timezone: "Mars/Olympus_Mons"}, errTimezoneInvalid},
+		{Community{Locale: "english"}, errLocaleInvalid},
+		{Community{Slug: "rva", PrimaryColor: "#336699", Email: "hello@rva.org", Website: "https://rva.org", Timezone: "America/New_York", Locale: "es-US"}, nil},
+	}
+	for _, test := range tests {
+		if err := test.community.Validate(); err != test.err {
+			t.Errorf("expected %+v to return %v, got %v", test.community, test.err, err)
+		}
+	}
+
+	c = Community{ID: 7, Slug: "old-town"}
+	if path := c.MapPath(); path != "/map?community=7&slug=old-town" {
+		t.Errorf("unexpected map path: %s", path)
+	}
+}
@@ -4,8 +4,117 @@ import (
 	"errors"
 )
 
+// ErrSeatsFull is returned when a dependent is added to an account membership whose seats
+// are all taken.
+var ErrSeatsFull = errors.New("every seat of the account membership is taken")
+
+// ErrOverlayNotFound is returned when a geo json overlay does not exist in the community.
+var ErrOverlayNotFound = errors.New("overlay not found")
+
+// ErrJoinRequestExists is returned when an organization asks to join a community that it has
+// already asked to join or is already a member of.
+var ErrJoinRequestExists = errors.New("organization has already requested to join the community")
+
+// ErrClaimRateLimited is returned when an organization has received too many recent claims.
+var ErrClaimRateLimited = errors.New("too many recent claims of the organization, try again later")
+
+// Redemption errors are returned when a promotion is not available or redeeming it would
+// exceed one of its limits.
+var (
+	ErrPromotionRedeemed     = errors.New("promotion can only be redeemed once")
+	ErrPromotionAccountLimit = errors.New("promotion redemption limit of the account reached")
+	ErrPromotionSoldOut      = errors.New("promotion has no redemptions left")
+	ErrPromotionDailyLimit   = errors.New("promotion has no redemptions left today")
+
+	ErrPromotionNotStarted    = errors.New("promotion has not started")
+	ErrPromotionExpired       = errors.New("promotion expired")
+	ErrPromotionBlackedOut    = errors.New("promotion is not available today")
+	ErrPromotionOutsideWindow = errors.New("promotion is not available at this time")
+)
+
 var (
-	errGeocodeNotFound = errors.New("geocode not found")
+	errGeocodeNotFound    = errors.New("geocode not found")
 	errAccountNotFound    = errors.New("account not found")
 	errInvalidCredentials = errors.New("invalid credentials")
+
+	errClaimMethodNotSupported   = errors.New("claim method not supported")
+	errClaimNotPending           = errors.New("claim not pending")
+	errClaimCodeExpired          = errors.New("claim code expired")
+	errClaimCodeInvalid          = errors.New("claim code invalid")
+	errOrganizationEmailRequired = errors.New("organization email required")
+
+	errJoinRequestNotPending = errors.New("join request not pending")
+
+	errImportNameColumnRequired        = errors.New("import name column required")
+	errImportFeatureCollectionRequired = errors.New("import feature collection required")
+	errImportInvalid                   = errors.New("import invalid")
+
+	errOrganizationOutsideBoundary = errors.New("organization outside community boundary")
+
+	errSlugInvalid             = errors.New("slug must contain only lowercase letters, numbers, and single dashes")
+	errSlugTaken               = errors.New("slug taken")
+	errPrimaryColorInvalid     = errors.New("primary color must be a hex color")
+	errWelcomeTextTooLong      = errors.New("welcome text too long")
+	errCommunityEmailInvalid   = errors.New("community email invalid")
+	errCommunityWebsiteInvalid = errors.New("community website must be an http or https url")
+	errTimezoneInvalid         = errors.New("timezone invalid")
+	errLocaleInvalid           = errors.New("locale must be a language tag, e.g. en-US")
+
+	errDurationInvalid           = errors.New("duration must be monthly, annual, lifetime or season")
+	errSeasonInvalid             = errors.New("season memberships require a season start before the season end")
+	errSeasonEnded               = errors.New("membership season ended")
+	errGracePeriodInvalid        = errors.New("grace period days must not be negative")
+	errSeatsInvalid              = errors.New("seats must be positive")
+	errMembershipNotFound        = errors.New("membership not found")
+	errAccountMembershipNotFound = errors.New("account membership not found")
+
+	errMembershipFree         = errors.New("membership is free")
+	errCheckoutEmailInvalid   = errors.New("checkout email invalid")
+	errCheckoutAmountMismatch = errors.New("checkout event amount or currency does not match the checkout session")
+	errPaymentNotFound        = errors.New("payment not found")
+	errRefundAmountInvalid    = errors.New("refund amount must be positive and no more than the amount that has not been refunded")
+
+	errReminderKindInvalid      = errors.New("reminder kind must be 30-days, 7-days, 1-day or expired")
+	errReminderTemplateRequired = errors.New("reminder subject and body required")
+	errReminderTemplateInvalid  = errors.New("reminder template invalid")
+
+	errCredentialInvalid = errors.New("membership credential invalid")
+
+	errCredentialExpired          = errors.New("membership credential expired")
+	errCheckInCodeInvalid         = errors.New("check-in code invalid")
+	errOrganizationNotInCommunity = errors.New("organization is not a member of the membership's community")
+	errActiveMembershipRequired   = errors.New("an active membership of one of the organization's communities is required")
+	errTrafficIntervalInvalid     = errors.New("interval must be day, week or month")
+	errTrafficPeriodInvalid       = errors.New("from and to must be dates, e.g. 2017-10-31, that are at most two years apart")
+
+	errRosterEmailColumnRequired = errors.New("roster email column required")
+	errRosterDateInvalid         = errors.New("roster dates must be formatted as 2006-01-02 or 01/02/2006")
+	errRosterInvalid             = errors.New("roster invalid")
+
+	errMembershipChangeSame        = errors.New("account membership is already of that membership")
+	errMembershipCommunityMismatch = errors.New("memberships can only be changed within a community")
+	errAlreadyMember               = errors.New("account already has that membership")
+	errTransferEmailInvalid        = errors.New("transfer email invalid")
+	errTransferToSelf              = errors.New("memberships can not be transferred to their holder")
+
+	errPromotionNotFound      = errors.New("promotion not found")
+	errPromotionLimitInvalid  = errors.New("promotion limits must not be negative")
+	errPromotionPeriodInvalid = errors.New("account limit period must be day, week, month, year or empty")
+
+	errPromotionScheduleInvalid     = errors.New("promotions must start before they expire")
+	errPromotionWindowInvalid       = errors.New("promotion windows require a weekday and different start and close times, e.g. 1600-1800")
+	errPromotionBlackoutDateInvalid = errors.New("blackout dates must be formatted as 2006-01-02")
+
+	errSeatsExceeded          = errors.New("the membership does not have a seat for every dependent of the account membership")
+	errDependentEmailInvalid  = errors.New("dependent email invalid")
+	errDependentIsHolder      = errors.New("the holder of an account membership can not be its dependent")
+	errDependentNotFound      = errors.New("dependent not found")
+	errSeatInvitationNotFound = errors.New("seat invitation not found")
+	errAccountIsDependent     = errors.New("account is a dependent of that membership")
+
+	errOverlayNameRequired        = errors.New("overlay name required")
+	errOverlayDataTooLarge        = errors.New("overlay data too large")
+	errOverlayStyleTooLarge       = errors.New("overlay style too large")
+	errOverlayStyleObjectRequired = errors.New("overlay style must be an object")
+	errOverlayOrderInvalid        = errors.New("overlay order must contain every overlay exactly once")
 )
@@ -1,31 +1,304 @@
 package models
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/jmoiron/sqlx/types"
+
+	"github.com/jteppinette/peragrin-api/geometry"
 )
 
+const (
+	// MaxGeoJSONOverlayDataSize is the largest GeoJSON document, in bytes, that may be stored in an overlay.
+	MaxGeoJSONOverlayDataSize = 2 << 20
+	// MaxGeoJSONOverlayStyleSize is the largest style object, in bytes, that may be stored in an overlay.
+	MaxGeoJSONOverlayStyleSize = 4 << 10
+)
+
+// geoJSONOverlayColumns are selected and returned by every overlay query.
+const geoJSONOverlayColumns = "id, name, communityID, data, style, position, bounds, updatedAt"
+
+// overlayStyleColor matches css hex colors and simple color names.
+var overlayStyleColor = regexp.MustCompile(`^(#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6}|[a-zA-Z]+)$`)
+
 // GeoJSONOverlay is an object that represents a list of features
 // each determined by parameters and a set of geographical coordinates.
 // These sets of geographical coordinates are called "Features". These
 // features can be styled independtly through the Style object.
 type GeoJSONOverlay struct {
-	Name  string         `json:"name"`
-	Data  types.JSONText `json:"data"`
-	Style types.JSONText `json:"style"`
+	ID          int            `json:"id"`
+	CommunityID int            `json:"communityID"`
+	Name        string         `json:"name"`
+	Data        types.JSONText `json:"data"`
+	Style       types.JSONText `json:"style"`
+	Position    int            `json:"position"`
+
+	// Bounds is the bounding box of the overlay's data. It is computed when the
+	// overlay is saved and is nil when the data does not contain any geometry.
+	Bounds    *GeoJSONOverlayBounds `json:"bounds"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
+
+// GeoJSONOverlayBounds is the bounding box of an overlay stored as JSON.
+type GeoJSONOverlayBounds struct {
+	geometry.Bounds
+}
+
+// Scan implements the sql.Scanner interface.
+func (b *GeoJSONOverlayBounds) Scan(src interface{}) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported bounds type %T", src)
+	}
+	return json.Unmarshal(data, &b.Bounds)
+}
+
+// Value implements the driver.Valuer interface. Nil bounds are stored as NULL.
+func (b *GeoJSONOverlayBounds) Value() (driver.Value, error) {
+	if b == nil {
+		return nil, nil
+	}
+	return json.Marshal(b.Bounds)
+}
+
+// computeBounds sets the overlay's bounds from its data.
+func (o *GeoJSONOverlay) computeBounds() error {
+	b, err := geometry.GeoJSONBounds(o.Data)
+	if err != nil {
+		return err
+	}
+	if b.IsEmpty() {
+		o.Bounds = nil
+	} else {
+		o.Bounds = &GeoJSONOverlayBounds{b}
+	}
+	return nil
+}
+
+// Validate checks that the overlay's data is a valid GeoJSON document and that its
+// style only contains the supported path options with values of the correct type.
+// The overlay's bounds are computed from valid data.
+func (o *GeoJSONOverlay) Validate() error {
+	if o.Name == "" {
+		return errOverlayNameRequired
+	}
+	if len(o.Data) > MaxGeoJSONOverlayDataSize {
+		return errOverlayDataTooLarge
+	}
+	if len(o.Style) > MaxGeoJSONOverlayStyleSize {
+		return errOverlayStyleTooLarge
+	}
+	if err := geometry.ValidateGeoJSON(o.Data); err != nil {
+		return fmt.Errorf("data: %s", err.Error())
+	}
+	if len(o.Style) == 0 {
+		o.Style = types.JSONText("{}")
+	}
+	if err := validateOverlayStyle(o.Style); err != nil {
+		return err
+	}
+	return o.computeBounds()
 }
 
-// Create creates a new geo json overlay in the database.
+// validateOverlayStyle checks a Leaflet style object. Colors must be css hex colors or
+// color names, opacities must be between 0 and 1, and widths must not be negative.
+func validateOverlayStyle(data []byte) error {
+	style := map[string]interface{}{}
+	if err := json.Unmarshal(data, &style); err != nil {
+		return errOverlayStyleObjectRequired
+	}
+	for key, value := range style {
+		switch key {
+		case "stroke", "fill":
+			if _, ok := value.(bool); !ok {
+				return fmt.Errorf("style: %s must be a boolean", key)
+			}
+		case "color", "fillColor":
+			if s, ok := value.(string); !ok || !overlayStyleColor.MatchString(s) {
+				return fmt.Errorf("style: %s must be a color", key)
+			}
+		case "opacity", "fillOpacity":
+			if n, ok := value.(float64); !ok || n < 0 || n > 1 {
+				return fmt.Errorf("style: %s must be a number between 0 and 1", key)
+			}
+		case "weight", "radius":
+			if n, ok := value.(float64); !ok || n < 0 {
+				return fmt.Errorf("style: %s must be a positive number", key)
+			}
+		case "dashArray", "lineCap", "lineJoin":
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("style: %s must be a string", key)
+			}
+		default:
+			return fmt.Errorf("style: unsupported property %q", key)
+		}
+	}
+	return nil
+}
+
+// Create creates a new geo json overlay in the database. The overlay is positioned after
+// all of the community's existing overlays.
 func (o *GeoJSONOverlay) Create(communityID int, client *sqlx.DB) error {
-	return client.Get(o, "INSERT INTO GeoJSONOverlay (name, communityID, data, style) VALUES ($1, $2, $3, $4) RETURNING name, data, style;", o.Name, communityID, o.Data, o.Style)
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	return client.Get(o, `
+		INSERT INTO GeoJSONOverlay (name, communityID, data, style, bounds, position)
+		VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX(position) + 1, 0) FROM GeoJSONOverlay WHERE communityID = $2))
+		RETURNING `+geoJSONOverlayColumns+`;
+	`, o.Name, communityID, o.Data, o.Style, o.Bounds)
+}
+
+// Update updates the name, data, and style of the geo json overlay in the database.
+func (o *GeoJSONOverlay) Update(client *sqlx.DB) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	err := client.Get(o, `
+		UPDATE GeoJSONOverlay SET name = $3, data = $4, style = $5, bounds = $6, updatedAt = NOW()
+		WHERE id = $1 AND communityID = $2
+		RETURNING `+geoJSONOverlayColumns+`;
+	`, o.ID, o.CommunityID, o.Name, o.Data, o.Style, o.Bounds)
+	if err == sql.ErrNoRows {
+		return ErrOverlayNotFound
+	}
+	return err
+}
+
+// Delete removes the geo json overlay from the database. ErrOverlayNotFound is returned
+// when the community does not have the overlay.
+func (o *GeoJSONOverlay) Delete(client *sqlx.DB) error {
+	result, err := client.Exec("DELETE FROM GeoJSONOverlay WHERE id = $1 AND communityID = $2;", o.ID, o.CommunityID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrOverlayNotFound
+	}
+	return nil
+}
+
+// GetGeoJSONOverlayByID returns the community's geo json overlay with the provided id,
+// or nil if one does not exist.
+func GetGeoJSONOverlayByID(communityID, id int, client *sqlx.DB) (*GeoJSONOverlay, error) {
+	o := &GeoJSONOverlay{}
+	if err := client.Get(o, "SELECT "+geoJSONOverlayColumns+" FROM GeoJSONOverlay WHERE communityID = $1 AND id = $2;", communityID, id); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return o, nil
 }
 
 // GetGeoJSONOverlaysByCommunity returns the set of geo JSON overlays that
-// are used by the given community.
+// are used by the given community in display order.
 func GetGeoJSONOverlaysByCommunity(communityID int, client *sqlx.DB) ([]GeoJSONOverlay, error) {
 	geoJSONOverlays := []GeoJSONOverlay{}
-	if err := client.Select(&geoJSONOverlays, "SELECT name, data, style FROM GeoJSONOverlay WHERE communityID = $1;", communityID); err != nil {
+	if err := client.Select(&geoJSONOverlays, "SELECT "+geoJSONOverlayColumns+" FROM GeoJSONOverlay WHERE communityID = $1 ORDER BY position, id;", communityID); err != nil {
+		return nil, err
+	}
+	return geoJSONOverlays, nil
+}
+
+// GetGeoJSONOverlaysByBounds returns the geo JSON overlays of the given community whose
+// bounds intersect the provided bounds in display order.
+func GetGeoJSONOverlaysByBounds(communityID int, b geometry.Bounds, client *sqlx.DB) ([]GeoJSONOverlay, error) {
+	candidates := []GeoJSONOverlay{}
+	if err := client.Select(&candidates, `
+		SELECT `+geoJSONOverlayColumns+` FROM GeoJSONOverlay
+		WHERE communityID = $1 AND (bounds IS NULL OR (
+			(bounds->>'minLon')::float <= $4 AND (bounds->>'maxLon')::float >= $2 AND
+			(bounds->>'minLat')::float <= $5 AND (bounds->>'maxLat')::float >= $3
+		))
+		ORDER BY position, id;
+	`, communityID, b.MinLon, b.MinLat, b.MaxLon, b.MaxLat); err != nil {
 		return nil, err
 	}
+
+	// Overlays that were saved before bounds were recorded are checked here.
+	geoJSONOverlays := []GeoJSONOverlay{}
+	for _, o := range candidates {
+		if o.Bounds == nil {
+			if err := o.computeBounds(); err != nil {
+				return nil, err
+			}
+			if o.Bounds == nil || !o.Bounds.Intersects(b) {
+				continue
+			}
+		}
+		geoJSONOverlays = append(geoJSONOverlays, o)
+	}
 	return geoJSONOverlays, nil
 }
+
+// GetGeoJSONOverlaysVersion returns a value that changes whenever any of the given
+// community's geo JSON overlays are created, updated, reordered, or deleted.
+func GetGeoJSONOverlaysVersion(communityID int, client *sqlx.DB) (string, error) {
+	var version string
+	err := client.Get(&version, `
+		SELECT MD5(COALESCE(STRING_AGG(id || ':' || updatedAt, ',' ORDER BY id), ''))
+		FROM GeoJSONOverlay WHERE communityID = $1;
+	`, communityID)
+	return version, err
+}
+
+// ReorderGeoJSONOverlays sets the display order of the community's geo json overlays.
+// The provided ids must contain every overlay in the community exactly once.
+func ReorderGeoJSONOverlays(communityID int, ids []int, client *sqlx.DB) ([]GeoJSONOverlay, error) {
+	tx, err := client.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	existing := []int{}
+	err = tx.Select(&existing, "SELECT id FROM GeoJSONOverlay WHERE communityID = $1 FOR UPDATE;", communityID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[int]bool{}
+	for _, id := range existing {
+		seen[id] = false
+	}
+	for _, id := range ids {
+		if done, ok := seen[id]; !ok || done {
+			err = errOverlayOrderInvalid
+			return nil, err
+		}
+		seen[id] = true
+	}
+	if len(ids) != len(existing) {
+		err = errOverlayOrderInvalid
+		return nil, err
+	}
+
+	for position, id := range ids {
+		_, err = tx.Exec("UPDATE GeoJSONOverlay SET position = $1, updatedAt = NOW() WHERE id = $2;", position, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	overlays := []GeoJSONOverlay{}
+	err = tx.Select(&overlays, "SELECT "+geoJSONOverlayColumns+" FROM GeoJSONOverlay WHERE communityID = $1 ORDER BY position, id;", communityID)
+	if err != nil {
+		return nil, err
+	}
+	return overlays, nil
+}
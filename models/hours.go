@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -16,9 +18,45 @@ type Hour struct {
 // Hours represents the full week schedule.
 type Hours []Hour
 
+// String formats the hours as semicolon separated "weekday:start-close" entries.
+// This is the same format that is accepted by organization imports.
+func (h Hours) String() string {
+	entries := make([]string, len(h))
+	for i, v := range h {
+		entries[i] = fmt.Sprintf("%s:%d-%d", strings.ToLower(v.Weekday.String()), v.Start, v.Close)
+	}
+	return strings.Join(entries, "; ")
+}
+
+// IsOpen reports whether the schedule is open at the provided time. Start and close
+// times are written as hours and minutes, e.g. 930 for 9:30 AM. A close that is not after
+// the start, such as 2200-200, is open past midnight into the following day.
+func (h Hours) IsOpen(t time.Time) bool {
+	now := t.Hour()*100 + t.Minute()
+	yesterday := (t.Weekday() + 6) % 7
+	for _, v := range h {
+		if v.Close > v.Start {
+			if v.Weekday == t.Weekday() && now >= v.Start && now < v.Close {
+				return true
+			}
+			continue
+		}
+		if (v.Weekday == t.Weekday() && now >= v.Start) || (v.Weekday == yesterday && now < v.Close) {
+			return true
+		}
+	}
+	return false
+}
+
 // Set replaces an organizations hours of operation.
 func (h Hours) txSet(organizationID int, tx *sqlx.Tx) error {
-	_, err := tx.Exec("DELETE FROM Hours WHERE organizationID = $1", organizationID)
+	return h.txReplace("Hours", "organizationID", organizationID, tx)
+}
+
+// txReplace replaces the hours in table whose column is id, e.g. the windows of a
+// promotion in PromotionWindow. Table and column are never user input.
+func (h Hours) txReplace(table, column string, id int, tx *sqlx.Tx) error {
+	_, err := tx.Exec("DELETE FROM "+table+" WHERE "+column+" = $1", id)
 	if err != nil {
 		return err
 	}
@@ -27,13 +65,13 @@ func (h Hours) txSet(organizationID int, tx *sqlx.Tx) error {
 		return nil
 	}
 
-	statement := "INSERT INTO Hours (organizationID, weekday, start, close) VALUES "
+	statement := "INSERT INTO " + table + " (" + column + ", weekday, start, close) VALUES "
 	args := make([]interface{}, len(h)*4)
 
 	for i, v := range h {
 		statement = statement + "(?, ?, ?, ?),"
 		set := i * 4
-		args[set+0] = organizationID
+		args[set+0] = id
 		args[set+1] = v.Weekday
 		args[set+2] = v.Start
 		args[set+3] = v.Close
@@ -55,3 +93,31 @@ func GetHoursByOrganization(organizationID int, client *sqlx.DB) (Hours, error)
 	}
 	return hours, nil
 }
+
+// GetHoursByOrganizations returns the hours of the organizations with the provided ids keyed
+// by organization id.
+func GetHoursByOrganizations(ids []int, client *sqlx.DB) (map[int]Hours, error) {
+	if len(ids) == 0 {
+		return map[int]Hours{}, nil
+	}
+	rows := []struct {
+		OrganizationID int
+		Hour
+	}{}
+	query, args, err := sqlx.In(`
+		SELECT Hours.organizationID, Hours.weekday, Hours.start, Hours.close FROM Hours
+		WHERE Hours.organizationID IN (?)
+		ORDER BY Hours.organizationID, Hours.weekday, Hours.start;
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Select(&rows, client.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	hours := map[int]Hours{}
+	for _, row := range rows {
+		hours[row.OrganizationID] = append(hours[row.OrganizationID], row.Hour)
+	}
+	return hours, nil
+}
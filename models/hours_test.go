@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHoursIsOpen(t *testing.T) {
+	hours := Hours{{time.Monday, 900, 1700}, {time.Friday, 2200, 200}}
+
+	tests := []struct {
+		time     time.Time
+		expected bool
+	}{
+		{time.Date(2017, 10, 16, 9, 0, 0, 0, time.UTC), true},
+		{time.Date(2017, 10, 16, 16, 59, 0, 0, time.UTC), true},
+		{time.Date(2017, 10, 16, 17, 0, 0, 0, time.UTC), false},
+		{time.Date(2017, 10, 17, 12, 0, 0, 0, time.UTC), false},
+		{time.Date(2017, 10, 20, 23, 0, 0, 0, time.UTC), true},
+		{time.Date(2017, 10, 21, 1, 30, 0, 0, time.UTC), true},
+		{time.Date(2017, 10, 21, 2, 0, 0, 0, time.UTC), false},
+	}
+	for _, test := range tests {
+		if actual := hours.IsOpen(test.time); actual != test.expected {
+			t.Errorf("expected open at %s to be %t, got %t", test.time, test.expected, actual)
+		}
+	}
+}
@@ -5,8 +5,51 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/common"
+)
+
+const (
+	// DurationMonthly memberships expire one month after they are joined or renewed.
+	DurationMonthly = "monthly"
+	// DurationAnnual memberships expire one year after they are joined or renewed.
+	DurationAnnual = "annual"
+	// DurationLifetime memberships never expire.
+	DurationLifetime = "lifetime"
+	// DurationSeason memberships expire at the end of the membership's season.
+	DurationSeason = "season"
 )
 
+const (
+	// MembershipStatusUpcoming account memberships were joined before their season started,
+	// and are honored once it starts.
+	MembershipStatusUpcoming = "upcoming"
+	// MembershipStatusActive account memberships have not expired.
+	MembershipStatusActive = "active"
+	// MembershipStatusGrace account memberships have expired, but are still honored
+	// until the membership's grace period ends.
+	MembershipStatusGrace = "grace"
+	// MembershipStatusExpired account memberships are no longer honored.
+	MembershipStatusExpired = "expired"
+	// MembershipStatusInvited seats have not been accepted by their dependent yet.
+	MembershipStatusInvited = "invited"
+)
+
+// membershipColumns are selected and returned by membership queries.
+const membershipColumns = "Membership.id, Membership.name, Membership.description, Membership.price, Membership.duration, Membership.seasonStart, Membership.seasonEnd, Membership.gracePeriodDays, Membership.seats"
+
+// membershipStatus is a sql expression for the status of an AccountMembership joined with its Membership.
+// Only season memberships have a season start.
+const membershipStatus = `CASE
+	WHEN Membership.seasonStart > NOW() THEN 'upcoming'
+	WHEN AccountMembership.expiration IS NULL OR AccountMembership.expiration > NOW() THEN 'active'
+	WHEN AccountMembership.expiration + Membership.gracePeriodDays * INTERVAL '1 day' > NOW() THEN 'grace'
+	ELSE 'expired' END`
+
+// activeMembership is a sql condition that is true when an AccountMembership joined with its Membership
+// is active or in its grace period. Season memberships are not active before their season starts.
+const activeMembership = "((Membership.seasonStart IS NULL OR Membership.seasonStart <= NOW()) AND (AccountMembership.expiration IS NULL OR AccountMembership.expiration + Membership.gracePeriodDays * INTERVAL '1 day' > NOW()))"
+
 // Membership represents a level of membership that
 // a patron could possess with a community.
 type Membership struct {
@@ -16,25 +59,129 @@ type Membership struct {
 	CommunityID int     `json:"communityID,omitempty"`
 	Price       float64 `json:"price"`
 
-	// Expiration is used to define the time left for a provided account membership.
-	// This information is only useful when in the context of an account.
-	Expiration time.Time `json:"expiration,omitempty"`
+	// Duration determines when account memberships expire. Season memberships start at
+	// SeasonStart, expire at SeasonEnd and cannot be joined once it has passed. Expired account memberships are
+	// still honored for GracePeriodDays.
+	Duration        string              `json:"duration"`
+	SeasonStart     common.JSONNullTime `json:"seasonStart"`
+	SeasonEnd       common.JSONNullTime `json:"seasonEnd"`
+	GracePeriodDays int                 `json:"gracePeriodDays"`
+
+	// Seats is how many accounts share an account membership, including its primary holder,
+	// who invites the others as dependents. Dependents share the holder's expiration.
+	Seats int `json:"seats"`
+
+	// Expiration and Status are used to define the time left for a provided account membership.
+	// This information is only useful when in the context of an account. Lifetime memberships
+	// do not have an expiration. PrimaryAccountID is the holder of the account membership when
+	// the account is one of its dependents.
+	Expiration       common.JSONNullTime `json:"expiration"`
+	Status           string              `json:"status,omitempty"`
+	PrimaryAccountID *int                `json:"primaryAccountID,omitempty"`
+}
+
+// Validate checks the membership's plan. Memberships without a duration are annual, and
+// memberships without seats are individual.
+func (m *Membership) Validate() error {
+	if m.Duration == "" {
+		m.Duration = DurationAnnual
+	}
+	if m.Seats == 0 {
+		m.Seats = 1
+	}
+	if m.Seats < 0 {
+		return errSeatsInvalid
+	}
+	switch m.Duration {
+	case DurationMonthly, DurationAnnual, DurationLifetime:
+		m.SeasonStart, m.SeasonEnd = common.JSONNullTime{}, common.JSONNullTime{}
+	case DurationSeason:
+		if !m.SeasonStart.Valid || !m.SeasonEnd.Valid || !m.SeasonEnd.Time.After(m.SeasonStart.Time) {
+			return errSeasonInvalid
+		}
+	default:
+		return errDurationInvalid
+	}
+	if m.GracePeriodDays < 0 {
+		return errGracePeriodInvalid
+	}
+	return nil
+}
+
+// ExpirationAt returns when an account membership that is joined or renewed at now expires.
+// Renewals extend the current expiration when it has not passed the grace period, so that
+// members who renew early do not lose any time. Months that are shorter than the day of
+// the month end on their last day. Lifetime memberships return a null expiration.
+func (m *Membership) ExpirationAt(now time.Time, current common.JSONNullTime) (common.JSONNullTime, error) {
+	start := now
+	if current.Valid && current.Time.AddDate(0, 0, m.GracePeriodDays).After(now) {
+		start = current.Time
+	}
+
+	switch m.Duration {
+	case DurationMonthly:
+		return common.NewJSONNullTime(addMonths(start, 1)), nil
+	case DurationLifetime:
+		return common.JSONNullTime{}, nil
+	case DurationSeason:
+		if !m.SeasonEnd.Valid || !m.SeasonEnd.Time.After(now) {
+			return common.JSONNullTime{}, errSeasonEnded
+		}
+		return m.SeasonEnd, nil
+	default:
+		return common.NewJSONNullTime(addMonths(start, 12)), nil
+	}
+}
+
+// statusAt returns the status of an account membership that was just joined or renewed at
+// now. Season memberships that are joined before the season starts are upcoming.
+func (m *Membership) statusAt(now time.Time) string {
+	if m.SeasonStart.Valid && now.Before(m.SeasonStart.Time) {
+		return MembershipStatusUpcoming
+	}
+	return MembershipStatusActive
+}
+
+// addMonths adds n months to t. The day of the month is clamped to the last day of the
+// resulting month, so that one month after January 31 is February 28 or 29 rather than
+// March 3.
+func addMonths(t time.Time, n int) time.Time {
+	month := t.Month() + time.Month(n)
+	day := t.Day()
+	if last := time.Date(t.Year(), month+1, 0, 0, 0, 0, 0, t.Location()).Day(); day > last {
+		day = last
+	}
+	return time.Date(t.Year(), month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
 }
 
 // Create adds a new membership to the database.
 func (m *Membership) Create(communityID int, client *sqlx.DB) error {
-	return client.Get(m, "INSERT INTO Membership (communityID, name, description, price) VALUES ($1, $2, $3, $4) RETURNING id, name, description, price;", communityID, m.Name, m.Description, m.Price)
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	return client.Get(m, `
+		INSERT INTO Membership (communityID, name, description, price, duration, seasonStart, seasonEnd, gracePeriodDays, seats)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+membershipColumns+`;
+	`, communityID, m.Name, m.Description, m.Price, m.Duration, m.SeasonStart, m.SeasonEnd, m.GracePeriodDays, m.Seats)
 }
 
 // Update updates a membership row in the database.
 func (m *Membership) Update(client *sqlx.DB) error {
-	return client.Get(m, "UPDATE Membership SET name = $2, description = $3, price = $4 WHERE id = $1 RETURNING id, name, description, price;", m.ID, m.Name, m.Description, m.Price)
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	return client.Get(m, `
+		UPDATE Membership SET name = $2, description = $3, price = $4, duration = $5, seasonStart = $6, seasonEnd = $7, gracePeriodDays = $8, seats = $9
+		WHERE id = $1
+		RETURNING `+membershipColumns+`;
+	`, m.ID, m.Name, m.Description, m.Price, m.Duration, m.SeasonStart, m.SeasonEnd, m.GracePeriodDays, m.Seats)
 }
 
 // GetMembershipsByCommunity returns all of a communities' memberships.
 func GetMembershipsByCommunity(communityID int, client *sqlx.DB) ([]Membership, error) {
 	memberships := []Membership{}
-	if err := client.Select(&memberships, "SELECT id, name, description, price FROM Membership WHERE communityID = $1 ORDER BY price;", communityID); err != nil {
+	if err := client.Select(&memberships, "SELECT "+membershipColumns+" FROM Membership WHERE communityID = $1 ORDER BY price;", communityID); err != nil {
 		return nil, err
 	}
 	return memberships, nil
@@ -42,8 +189,12 @@ func GetMembershipsByCommunity(communityID int, client *sqlx.DB) ([]Membership,
 
 // GetMembershipByID returns the requested membership.
 func GetMembershipByID(membershipID int, client *sqlx.DB) (*Membership, error) {
+	return getMembershipByID(membershipID, client)
+}
+
+func getMembershipByID(membershipID int, q sqlx.Queryer) (*Membership, error) {
 	m := &Membership{}
-	if err := client.Get(m, "SELECT id, name, description, price FROM Membership WHERE id = $1;", membershipID); err == sql.ErrNoRows {
+	if err := sqlx.Get(q, m, "SELECT "+membershipColumns+", Membership.communityID FROM Membership WHERE id = $1;", membershipID); err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
 		return nil, err
@@ -51,13 +202,23 @@ func GetMembershipByID(membershipID int, client *sqlx.DB) (*Membership, error) {
 	return m, nil
 }
 
-// GetMembershipsByAccount returns all memberships that an account has.
+// GetMembershipsByAccount returns all memberships that an account has, including those
+// that it has a seat of, along with their expiration and status. Seats that the account
+// was invited to, but has not accepted, have the invited status.
 func GetMembershipsByAccount(accountID int, client *sqlx.DB) ([]Membership, error) {
 	memberships := []Membership{}
 	if err := client.Select(&memberships, `
-		SELECT Membership.id, Membership.name, Membership.description, Membership.price, Membership.communityID, AccountMembership.expiration FROM Membership
+		SELECT `+membershipColumns+`, Membership.communityID, AccountMembership.expiration, `+membershipStatus+` AS status, NULL AS primaryAccountID FROM Membership
 		INNER JOIN AccountMembership ON (Membership.id = AccountMembership.membershipID)
-		WHERE AccountMembership.accountID = $1 ORDER BY Membership.price;
+		WHERE AccountMembership.accountID = $1
+		UNION ALL
+		SELECT `+membershipColumns+`, Membership.communityID, AccountMembership.expiration,
+			CASE WHEN MembershipSeat.acceptedAt IS NULL THEN 'invited' ELSE `+membershipStatus+` END AS status, MembershipSeat.primaryAccountID
+		FROM Membership
+		INNER JOIN AccountMembership ON (Membership.id = AccountMembership.membershipID)
+		INNER JOIN MembershipSeat ON (AccountMembership.accountID = MembershipSeat.primaryAccountID AND AccountMembership.membershipID = MembershipSeat.membershipID)
+		WHERE MembershipSeat.accountID = $1
+		ORDER BY price;
 	`, accountID); err != nil {
 		return nil, err
 	}
@@ -72,3 +233,20 @@ func DeleteMembership(id int, client *sqlx.DB) error {
 	}
 	return nil
 }
+
+// membershipExpiration returns the expiration of a new account membership. Requested
+// expirations entered by administrators are kept, and otherwise the expiration is
+// computed from the membership's plan.
+func membershipExpiration(membershipID int, requested common.JSONNullTime, now time.Time, q sqlx.Queryer) (common.JSONNullTime, error) {
+	if requested.Valid {
+		return requested, nil
+	}
+	m, err := getMembershipByID(membershipID, q)
+	if err != nil {
+		return requested, err
+	}
+	if m == nil {
+		return requested, errMembershipNotFound
+	}
+	return m.ExpirationAt(now, common.JSONNullTime{})
+}
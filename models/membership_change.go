@@ -0,0 +1,322 @@
+package models
+
+import (
+	"database/sql"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/common"
+)
+
+const (
+	// MembershipChangeUpgrade changes move an account to a more expensive membership.
+	MembershipChangeUpgrade = "upgrade"
+	// MembershipChangeDowngrade changes move an account to a less expensive membership.
+	MembershipChangeDowngrade = "downgrade"
+	// MembershipChangeSwitch changes move an account to a membership with the same price.
+	MembershipChangeSwitch = "switch"
+	// MembershipChangeTransfer changes move a membership to another account.
+	MembershipChangeTransfer = "transfer"
+)
+
+// MembershipChange is an entry in the history of an account's memberships. Membership names
+// are recorded when the change is made, so the history is kept when memberships are deleted.
+// Transfers are in the history of the account that received the membership, and
+// FromAccountID is the account that it was transferred from.
+type MembershipChange struct {
+	ID                 int                 `json:"id"`
+	AccountID          int                 `json:"accountID"`
+	FromAccountID      *int                `json:"fromAccountID"`
+	FromMembershipID   *int                `json:"fromMembershipID"`
+	ToMembershipID     *int                `json:"toMembershipID"`
+	FromMembershipName string              `json:"fromMembershipName"`
+	ToMembershipName   string              `json:"toMembershipName"`
+	Kind               string              `json:"kind"`
+	PreviousExpiration common.JSONNullTime `json:"previousExpiration"`
+	Expiration         common.JSONNullTime `json:"expiration"`
+	ChangedBy          *int                `json:"changedBy"`
+	CreatedAt          time.Time           `json:"createdAt"`
+}
+
+// termLength returns how long a term of the membership's plan lasts, or zero for lifetime
+// memberships. Seasons last from their start until their end.
+func (m *Membership) termLength() time.Duration {
+	switch m.Duration {
+	case DurationMonthly:
+		return time.Duration(365.2425 / 12 * float64(24*time.Hour))
+	case DurationLifetime:
+		return 0
+	case DurationSeason:
+		if m.SeasonStart.Valid && m.SeasonEnd.Valid && m.SeasonEnd.Time.After(m.SeasonStart.Time) {
+			return m.SeasonEnd.Time.Sub(m.SeasonStart.Time)
+		}
+	}
+	return time.Duration(365.2425 * float64(24*time.Hour))
+}
+
+// prorate returns the expiration of an account membership that changes from membership
+// from to membership to at now. The value of the time that remains of the current
+// expiration is converted to time of the new membership at its price. Time of free
+// memberships is converted one for one, lifetime and season memberships expire as they
+// always do, and moving from a lifetime membership starts a new term.
+func prorate(from, to *Membership, current common.JSONNullTime, now time.Time) (common.JSONNullTime, error) {
+	if to.Duration == DurationLifetime || to.Duration == DurationSeason {
+		return to.ExpirationAt(now, common.JSONNullTime{})
+	}
+	if !current.Valid {
+		return to.ExpirationAt(now, common.JSONNullTime{})
+	}
+
+	remaining := current.Time.Sub(now)
+	if remaining <= 0 {
+		return current, nil
+	}
+	if from.Price <= 0 || to.Price <= 0 {
+		return current, nil
+	}
+
+	fromRate := from.Price / from.termLength().Hours()
+	toRate := to.Price / to.termLength().Hours()
+	converted := time.Duration(float64(remaining) * fromRate / toRate)
+	return common.NewJSONNullTime(now.Add(converted - converted%time.Second)), nil
+}
+
+// changeKind returns the kind of a change between the memberships.
+func changeKind(from, to *Membership) string {
+	switch {
+	case to.Price > from.Price:
+		return MembershipChangeUpgrade
+	case to.Price < from.Price:
+		return MembershipChangeDowngrade
+	}
+	return MembershipChangeSwitch
+}
+
+// ChangeMembership moves the account from one membership to another of the same community.
+// The account membership keeps its credential. Its expiration is prorated unless expiration
+// is valid, in which case it is used instead. changedBy is the account that made the change.
+func (a *Account) ChangeMembership(fromID, toID int, expiration common.JSONNullTime, changedBy int, client *sqlx.DB) (c *MembershipChange, err error) {
+	if fromID == toID {
+		return nil, errMembershipChangeSame
+	}
+
+	tx, err := client.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	from, to, err := txGetMembershipPair(fromID, toID, tx)
+	if err != nil {
+		return nil, err
+	}
+	if from.CommunityID != to.CommunityID {
+		err = errMembershipCommunityMismatch
+		return nil, err
+	}
+
+	var current common.JSONNullTime
+	if err = tx.Get(&current, "SELECT expiration FROM AccountMembership WHERE accountID = $1 AND membershipID = $2 FOR UPDATE;", a.ID, fromID); err == sql.ErrNoRows {
+		err = errAccountMembershipNotFound
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	// Holders and dependents are kept apart, so neither the account nor any of its dependents
+	// may already hold, or have a seat in, the membership that they are moved to.
+	var exists bool
+	if err = tx.Get(&exists, `
+		SELECT EXISTS(SELECT FROM AccountMembership WHERE accountID = $1 AND membershipID = $3)
+			OR EXISTS(SELECT FROM MembershipSeat WHERE accountID = $1 AND membershipID = $3)
+			OR EXISTS(
+				SELECT FROM AccountMembership
+				WHERE membershipID = $3 AND accountID IN (SELECT accountID FROM MembershipSeat WHERE primaryAccountID = $1 AND membershipID = $2)
+			);
+	`, a.ID, fromID, toID); err != nil {
+		return nil, err
+	}
+	if exists {
+		err = errAlreadyMember
+		return nil, err
+	}
+
+	var dependents int
+	if err = tx.Get(&dependents, "SELECT COUNT(*) FROM MembershipSeat WHERE primaryAccountID = $1 AND membershipID = $2;", a.ID, fromID); err != nil {
+		return nil, err
+	}
+	if dependents > to.Seats-1 {
+		err = errSeatsExceeded
+		return nil, err
+	}
+
+	if !expiration.Valid {
+		if expiration, err = prorate(from, to, current, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = tx.Exec("UPDATE AccountMembership SET membershipID = $3, expiration = $4 WHERE accountID = $1 AND membershipID = $2;", a.ID, fromID, toID, expiration); err != nil {
+		return nil, err
+	}
+	// Dependents keep their seats, and reminders of the previous membership no longer apply.
+	if _, err = tx.Exec("UPDATE MembershipSeat SET membershipID = $3 WHERE primaryAccountID = $1 AND membershipID = $2;", a.ID, fromID, toID); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec("DELETE FROM MembershipReminder WHERE accountID = $1 AND membershipID = $2;", a.ID, fromID); err != nil {
+		return nil, err
+	}
+	a.Expiration = expiration
+
+	c = &MembershipChange{
+		AccountID:          a.ID,
+		FromMembershipID:   &from.ID,
+		ToMembershipID:     &to.ID,
+		FromMembershipName: from.Name,
+		ToMembershipName:   to.Name,
+		Kind:               changeKind(from, to),
+		PreviousExpiration: current,
+		Expiration:         expiration,
+		ChangedBy:          &changedBy,
+	}
+	err = c.txCreate(tx)
+	return c, err
+}
+
+// TransferMembership moves the account's membership to the account with the email, which
+// is created when it does not exist, in which case created is true. The account membership
+// keeps its expiration, and is given a new credential so that the previous holder's cards
+// stop working. changedBy is the account that made the transfer.
+func (a *Account) TransferMembership(membershipID int, recipient *Account, changedBy int, client *sqlx.DB) (c *MembershipChange, created bool, err error) {
+	recipient.Email = strings.ToLower(strings.TrimSpace(recipient.Email))
+	if _, err := mail.ParseAddress(recipient.Email); err != nil {
+		return nil, false, errTransferEmailInvalid
+	}
+
+	tx, err := client.Beginx()
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	m, err := getMembershipByID(membershipID, tx)
+	if err != nil {
+		return nil, false, err
+	}
+	if m == nil {
+		err = errMembershipNotFound
+		return nil, false, err
+	}
+
+	var expiration common.JSONNullTime
+	if err = tx.Get(&expiration, "SELECT expiration FROM AccountMembership WHERE accountID = $1 AND membershipID = $2 FOR UPDATE;", a.ID, membershipID); err == sql.ErrNoRows {
+		err = errAccountMembershipNotFound
+		return nil, false, err
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	err = tx.Get(recipient, "SELECT id, email, firstName, lastName, isSuper FROM Account WHERE LOWER(email) = $1;", recipient.Email)
+	if err == sql.ErrNoRows {
+		created = true
+		err = tx.Get(recipient, "INSERT INTO Account (email, firstName, lastName) VALUES ($1, $2, $3) RETURNING id, email, firstName, lastName, isSuper;", recipient.Email, recipient.FirstName, recipient.LastName)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if recipient.ID == a.ID {
+		err = errTransferToSelf
+		return nil, false, err
+	}
+
+	// Dependents of the holder can be given the membership, in which case they give up their
+	// seat, but dependents of other holders already have it.
+	var exists bool
+	if err = tx.Get(&exists, `
+		SELECT EXISTS(SELECT FROM AccountMembership WHERE accountID = $1 AND membershipID = $2)
+		OR EXISTS(SELECT FROM MembershipSeat WHERE accountID = $1 AND membershipID = $2 AND primaryAccountID != $3);
+	`, recipient.ID, membershipID, a.ID); err != nil {
+		return nil, false, err
+	}
+	if exists {
+		err = errAlreadyMember
+		return nil, false, err
+	}
+
+	if _, err = tx.Exec(`
+		UPDATE AccountMembership SET accountID = $3, credentialID = md5(random()::TEXT || clock_timestamp()::TEXT)
+		WHERE accountID = $1 AND membershipID = $2;
+	`, a.ID, membershipID, recipient.ID); err != nil {
+		return nil, false, err
+	}
+	if _, err = tx.Exec("DELETE FROM MembershipSeat WHERE accountID = $1 AND membershipID = $2;", recipient.ID, membershipID); err != nil {
+		return nil, false, err
+	}
+	if _, err = tx.Exec("UPDATE MembershipSeat SET primaryAccountID = $3 WHERE primaryAccountID = $1 AND membershipID = $2;", a.ID, membershipID, recipient.ID); err != nil {
+		return nil, false, err
+	}
+	recipient.Expiration = expiration
+
+	c = &MembershipChange{
+		AccountID:          recipient.ID,
+		FromAccountID:      &a.ID,
+		FromMembershipID:   &m.ID,
+		ToMembershipID:     &m.ID,
+		FromMembershipName: m.Name,
+		ToMembershipName:   m.Name,
+		Kind:               MembershipChangeTransfer,
+		PreviousExpiration: expiration,
+		Expiration:         expiration,
+		ChangedBy:          &changedBy,
+	}
+	err = c.txCreate(tx)
+	return c, created, err
+}
+
+func txGetMembershipPair(fromID, toID int, tx *sqlx.Tx) (*Membership, *Membership, error) {
+	from, err := getMembershipByID(fromID, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := getMembershipByID(toID, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if from == nil || to == nil {
+		return nil, nil, errMembershipNotFound
+	}
+	return from, to, nil
+}
+
+func (c *MembershipChange) txCreate(tx *sqlx.Tx) error {
+	return tx.Get(c, `
+		INSERT INTO MembershipChange (accountID, fromAccountID, fromMembershipID, toMembershipID, fromMembershipName, toMembershipName, kind, previousExpiration, expiration, changedBy)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING *;
+	`, c.AccountID, c.FromAccountID, c.FromMembershipID, c.ToMembershipID, c.FromMembershipName, c.ToMembershipName, c.Kind, c.PreviousExpiration, c.Expiration, c.ChangedBy)
+}
+
+// GetMembershipChangesByAccount returns the history of the account's memberships with the
+// newest changes first, including memberships that it transferred to other accounts.
+func GetMembershipChangesByAccount(accountID int, client *sqlx.DB) ([]MembershipChange, error) {
+	changes := []MembershipChange{}
+	if err := client.Select(&changes, "SELECT * FROM MembershipChange WHERE accountID = $1 OR fromAccountID = $1 ORDER BY createdAt DESC, id DESC;", accountID); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jteppinette/peragrin-api/common"
+)
+
+func TestProrate(t *testing.T) {
+	now := time.Date(2017, 10, 31, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	seasonEnd := common.NewJSONNullTime(now.AddDate(0, 3, 0))
+
+	basic := &Membership{Duration: DurationAnnual, Price: 50}
+	premium := &Membership{Duration: DurationAnnual, Price: 100}
+	monthly := &Membership{Duration: DurationMonthly, Price: 10}
+	free := &Membership{Duration: DurationAnnual}
+	lifetime := &Membership{Duration: DurationLifetime, Price: 500}
+	season := &Membership{Duration: DurationSeason, Price: 30, SeasonStart: common.NewJSONNullTime(now.AddDate(0, -3, 0)), SeasonEnd: seasonEnd}
+
+	for _, c := range []struct {
+		name     string
+		from, to *Membership
+		current  common.JSONNullTime
+		want     common.JSONNullTime
+	}{
+		{"upgrade halves remaining time", basic, premium, common.NewJSONNullTime(now.Add(100 * day)), common.NewJSONNullTime(now.Add(50 * day))},
+		{"downgrade doubles remaining time", premium, basic, common.NewJSONNullTime(now.Add(100 * day)), common.NewJSONNullTime(now.Add(200 * day))},
+		{"equal rates keep remaining time", &Membership{Duration: DurationAnnual, Price: 120}, monthly, common.NewJSONNullTime(now.Add(100 * day)), common.NewJSONNullTime(now.Add(100 * day))},
+		{"free keeps remaining time", free, premium, common.NewJSONNullTime(now.Add(100 * day)), common.NewJSONNullTime(now.Add(100 * day))},
+		{"expired keeps expiration", basic, premium, common.NewJSONNullTime(now.Add(-day)), common.NewJSONNullTime(now.Add(-day))},
+		{"from lifetime starts a term", lifetime, premium, common.JSONNullTime{}, common.NewJSONNullTime(now.AddDate(1, 0, 0))},
+		{"to lifetime never expires", basic, lifetime, common.NewJSONNullTime(now.Add(100 * day)), common.JSONNullTime{}},
+		{"to season ends with season", basic, season, common.NewJSONNullTime(now.Add(300 * day)), seasonEnd},
+	} {
+		got, err := prorate(c.from, c.to, c.current, now)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if !sameExpiration(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got.Time, c.want.Time)
+		}
+	}
+
+	ended := &Membership{Duration: DurationSeason, SeasonEnd: common.NewJSONNullTime(now.Add(-day))}
+	if _, err := prorate(basic, ended, common.NewJSONNullTime(now.Add(day)), now); err != errSeasonEnded {
+		t.Errorf("got %v, want %v", err, errSeasonEnded)
+	}
+}
+
+func TestChangeKind(t *testing.T) {
+	basic, premium := &Membership{Price: 50}, &Membership{Price: 100}
+	for _, c := range []struct {
+		from, to *Membership
+		want     string
+	}{
+		{basic, premium, MembershipChangeUpgrade},
+		{premium, basic, MembershipChangeDowngrade},
+		{basic, &Membership{Price: 50}, MembershipChangeSwitch},
+	} {
+		if got := changeKind(c.from, c.to); got != c.want {
+			t.Errorf("%v to %v: got %s, want %s", c.from.Price, c.to.Price, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/common"
+	"github.com/jteppinette/peragrin-api/wallet"
+)
+
+// MembershipCredential is the proof of an account membership that is shown on membership
+// cards. Its token is the credential's id signed with the token secret, so that tokens can
+// not be forged, and it is encoded in the cards' QR codes. Removing the account membership
+// revokes the credential.
+type MembershipCredential struct {
+	AccountID    int                 `json:"accountID"`
+	MembershipID int                 `json:"membershipID"`
+	CredentialID string              `json:"-"`
+	Expiration   common.JSONNullTime `json:"expiration"`
+	Status       string              `json:"status"`
+
+	FirstName             string `json:"firstName"`
+	LastName              string `json:"lastName"`
+	MembershipName        string `json:"membershipName"`
+	CommunityID           int    `json:"communityID"`
+	CommunityName         string `json:"communityName"`
+	CommunityPrimaryColor string `json:"communityPrimaryColor"`
+	CommunityLocale       string `json:"communityLocale"`
+	CommunityTimezone     string `json:"communityTimezone"`
+
+	Token string `json:"token,omitempty"`
+}
+
+// CredentialVerification is the result of verifying a membership credential. Credentials
+// are valid while their account membership is active or in its grace period.
+type CredentialVerification struct {
+	Valid      bool                  `json:"valid"`
+	Credential *MembershipCredential `json:"credential"`
+}
+
+// membershipCredentialQuery selects membership credentials, and must be followed by a WHERE clause.
+const membershipCredentialQuery = `
+	SELECT
+		AccountMembership.accountID, AccountMembership.membershipID, AccountMembership.credentialID, AccountMembership.expiration,
+		` + membershipStatus + ` AS status,
+		Account.firstName, Account.lastName, Membership.name AS membershipName,
+		Community.id AS communityID, Community.name AS communityName, Community.primaryColor AS communityPrimaryColor,
+		Community.locale AS communityLocale, Community.timezone AS communityTimezone
+	FROM AccountMembership
+	INNER JOIN Account ON (AccountMembership.accountID = Account.id)
+	INNER JOIN Membership ON (AccountMembership.membershipID = Membership.id)
+	INNER JOIN Community ON (Membership.communityID = Community.id)
+`
+
+// credentialToken returns the credential id signed with the secret.
+func credentialToken(id, secret string) string {
+	return signToken("membership-credential", id, secret)
+}
+
+// parseCredentialToken returns the credential id of a token that was signed with the secret.
+func parseCredentialToken(token, secret string) (string, error) {
+	id, ok := parseSignedToken("membership-credential", token, secret)
+	if !ok {
+		return "", errCredentialInvalid
+	}
+	return id, nil
+}
+
+// signToken returns the id followed by its HMAC for the purpose, so that tokens signed for
+// one purpose can not be used for another.
+func signToken(purpose, id, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose + ":" + id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseSignedToken returns the id of a token that signToken returned for the purpose and secret.
+func parseSignedToken(purpose, token, secret string) (string, bool) {
+	i := strings.LastIndex(token, ".")
+	if i <= 0 || !hmac.Equal([]byte(signToken(purpose, token[:i], secret)), []byte(token)) {
+		return "", false
+	}
+	return token[:i], true
+}
+
+// GetMembershipCredential returns the credential of an account membership, or nil if the
+// account does not have the membership.
+func GetMembershipCredential(accountID, membershipID int, secret string, client *sqlx.DB) (*MembershipCredential, error) {
+	c := &MembershipCredential{}
+	if err := client.Get(c, membershipCredentialQuery+"WHERE AccountMembership.accountID = $1 AND AccountMembership.membershipID = $2;", accountID, membershipID); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	c.Token = credentialToken(c.CredentialID, secret)
+	return c, nil
+}
+
+// VerifyMembershipCredential returns the credential of a token, or nil if the token was not
+// signed with the secret or its credential was revoked.
+func VerifyMembershipCredential(token, secret string, client *sqlx.DB) (*MembershipCredential, error) {
+	id, err := parseCredentialToken(strings.TrimSpace(token), secret)
+	if err != nil {
+		return nil, nil
+	}
+	c := &MembershipCredential{}
+	if err := client.Get(c, membershipCredentialQuery+"WHERE AccountMembership.credentialID = $1;", id); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Valid determines if the credential's account membership is honored. Memberships are not
+// honored before their season starts or after their grace period ends.
+func (c *MembershipCredential) Valid() bool {
+	return c.Status == MembershipStatusActive || c.Status == MembershipStatusGrace
+}
+
+// Pass returns the credential's membership card.
+func (c *MembershipCredential) Pass() wallet.Pass {
+	community := Community{Timezone: c.CommunityTimezone}
+	p := wallet.Pass{
+		SerialNumber: c.CredentialID,
+		Organization: c.CommunityName,
+		Title:        c.MembershipName,
+		Member:       strings.TrimSpace(c.FirstName + " " + c.LastName),
+		Expired:      !c.Valid(),
+		Barcode:      c.Token,
+		Color:        c.CommunityPrimaryColor,
+		Locale:       c.CommunityLocale,
+		Location:     community.Location(),
+	}
+	if c.Expiration.Valid {
+		p.Expiration = c.Expiration.Time
+	}
+	return p
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jteppinette/peragrin-api/common"
+)
+
+func TestCredentialToken(t *testing.T) {
+	token := credentialToken("0123abcd", "secret")
+	if id, err := parseCredentialToken(token, "secret"); err != nil || id != "0123abcd" {
+		t.Errorf("got %q, %v", id, err)
+	}
+	for _, forged := range []string{token + "x", "0123abce" + token[8:], "0123abcd", "", "." + token[9:]} {
+		if _, err := parseCredentialToken(forged, "secret"); err != errCredentialInvalid {
+			t.Errorf("%q: got %v, want %v", forged, err, errCredentialInvalid)
+		}
+	}
+	if _, err := parseCredentialToken(token, "other"); err != errCredentialInvalid {
+		t.Errorf("got %v, want %v", err, errCredentialInvalid)
+	}
+}
+
+func TestMembershipCredentialPass(t *testing.T) {
+	expiration := time.Date(2018, 1, 1, 5, 0, 0, 0, time.UTC)
+	c := MembershipCredential{
+		CredentialID: "0123abcd", Token: "0123abcd.sig", Status: MembershipStatusGrace, Expiration: common.NewJSONNullTime(expiration),
+		FirstName: "Jane", LastName: "Doe", MembershipName: "Gold", CommunityName: "Downtown", CommunityTimezone: "America/New_York",
+	}
+	p := c.Pass()
+	if p.Member != "Jane Doe" || p.Barcode != "0123abcd.sig" || p.Expired || !p.Expiration.Equal(expiration) || p.Location.String() != "America/New_York" {
+		t.Errorf("got %+v", p)
+	}
+
+	c.Status, c.Expiration, c.LastName = MembershipStatusExpired, common.JSONNullTime{}, ""
+	if p := c.Pass(); !p.Expired || !p.Expiration.IsZero() || p.Member != "Jane" {
+		t.Errorf("got %+v", p)
+	}
+}
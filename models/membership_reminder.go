@@ -0,0 +1,262 @@
+package models
+
+import (
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/mail"
+)
+
+const (
+	// ReminderKind30Days reminders are sent 30 days before an account membership expires.
+	ReminderKind30Days = "30-days"
+	// ReminderKind7Days reminders are sent 7 days before an account membership expires.
+	ReminderKind7Days = "7-days"
+	// ReminderKind1Day reminders are sent 1 day before an account membership expires.
+	ReminderKind1Day = "1-day"
+	// ReminderKindExpired notices are sent when an account membership expires.
+	ReminderKindExpired = "expired"
+
+	// expiredNoticeWindow is how long after an expiration its notice is still sent, e.g.
+	// when reminders were not running. Older expirations are never noticed.
+	expiredNoticeWindow = 7 * 24 * time.Hour
+)
+
+// ReminderKinds are every kind of reminder, in the order that they are sent.
+var ReminderKinds = []string{ReminderKind30Days, ReminderKind7Days, ReminderKind1Day, ReminderKindExpired}
+
+// DefaultReminderTemplates are used by communities that have not customized a reminder.
+var DefaultReminderTemplates = map[string]mail.Template{
+	ReminderKind30Days: {
+		Subject: "Your {{.Membership}} membership expires in 30 days",
+		Body:    "Hi {{.FirstName}},\n\nYour {{.Community}} {{.Membership}} membership expires on {{.Expiration}}. Renew at {{.RenewURL}} to keep your benefits.",
+	},
+	ReminderKind7Days: {
+		Subject: "Your {{.Membership}} membership expires in 7 days",
+		Body:    "Hi {{.FirstName}},\n\nYour {{.Community}} {{.Membership}} membership expires on {{.Expiration}}. Renew at {{.RenewURL}} to keep your benefits.",
+	},
+	ReminderKind1Day: {
+		Subject: "Your {{.Membership}} membership expires tomorrow",
+		Body:    "Hi {{.FirstName}},\n\nYour {{.Community}} {{.Membership}} membership expires on {{.Expiration}}. Renew at {{.RenewURL}} to keep your benefits.",
+	},
+	ReminderKindExpired: {
+		Subject: "Your {{.Membership}} membership has expired",
+		Body:    "Hi {{.FirstName}},\n\nYour {{.Community}} {{.Membership}} membership expired on {{.Expiration}}. Renew at {{.RenewURL}} to continue redeeming promotions.",
+	},
+}
+
+// MembershipReminder is a reminder that is due for an account membership. A reminder is
+// identified by its account, membership, kind and expiration, so renewed memberships are
+// reminded again about their new expiration.
+type MembershipReminder struct {
+	AccountID    int       `json:"accountID"`
+	MembershipID int       `json:"membershipID"`
+	Kind         string    `json:"kind"`
+	Expiration   time.Time `json:"expiration"`
+
+	Email             string `json:"email"`
+	FirstName         string `json:"firstName"`
+	LastName          string `json:"lastName"`
+	MembershipName    string `json:"membershipName"`
+	CommunityID       int    `json:"communityID"`
+	CommunityName     string `json:"communityName"`
+	CommunitySlug     string `json:"communitySlug"`
+	CommunityTimezone string `json:"communityTimezone"`
+}
+
+// ReminderData is the data that reminder templates are rendered with. The expiration is
+// formatted in the community's time zone.
+type ReminderData struct {
+	FirstName  string
+	LastName   string
+	Email      string
+	Membership string
+	Community  string
+	Expiration string
+	Days       int
+	RenewURL   string
+}
+
+// ReminderTemplate is a community's template for a kind of reminder. Custom is false for
+// the default templates.
+type ReminderTemplate struct {
+	CommunityID int    `json:"communityID"`
+	Kind        string `json:"kind"`
+	mail.Template
+	Custom bool `json:"custom"`
+}
+
+// reminderKind returns the kind of reminder that is due at now for an expiration, or an
+// empty string when none is. Only the latest reminder is due, so that members are not sent
+// several reminders at once when reminders were not running.
+func reminderKind(expiration, now time.Time) string {
+	remaining := expiration.Sub(now)
+	switch {
+	case remaining <= 0:
+		if remaining > -expiredNoticeWindow {
+			return ReminderKindExpired
+		}
+		return ""
+	case remaining <= 24*time.Hour:
+		return ReminderKind1Day
+	case remaining <= 7*24*time.Hour:
+		return ReminderKind7Days
+	case remaining <= 30*24*time.Hour:
+		return ReminderKind30Days
+	}
+	return ""
+}
+
+// GetDueMembershipReminders returns the reminders that are due at now and have not been sent.
+// Lifetime memberships are never reminded.
+func GetDueMembershipReminders(now time.Time, client *sqlx.DB) ([]MembershipReminder, error) {
+	candidates := []MembershipReminder{}
+	if err := client.Select(&candidates, `
+		SELECT
+			AccountMembership.accountID, AccountMembership.membershipID, AccountMembership.expiration,
+			Account.email, Account.firstName, Account.lastName, Membership.name AS membershipName,
+			Community.id AS communityID, Community.name AS communityName, Community.slug AS communitySlug, Community.timezone AS communityTimezone
+		FROM AccountMembership
+		INNER JOIN Account ON (AccountMembership.accountID = Account.id)
+		INNER JOIN Membership ON (AccountMembership.membershipID = Membership.id)
+		INNER JOIN Community ON (Membership.communityID = Community.id)
+		WHERE AccountMembership.expiration BETWEEN $1::TIMESTAMPTZ - $2 * INTERVAL '1 second' AND $1::TIMESTAMPTZ + INTERVAL '30 days'
+		ORDER BY AccountMembership.expiration;
+	`, now, expiredNoticeWindow.Seconds()); err != nil {
+		return nil, err
+	}
+
+	due := []MembershipReminder{}
+	for _, r := range candidates {
+		if r.Kind = reminderKind(r.Expiration, now); r.Kind == "" {
+			continue
+		}
+		var sent bool
+		if err := client.Get(&sent, `
+			SELECT EXISTS (SELECT 1 FROM MembershipReminder WHERE accountID = $1 AND membershipID = $2 AND kind = $3 AND expiration = $4);
+		`, r.AccountID, r.MembershipID, r.Kind, r.Expiration); err != nil {
+			return nil, err
+		}
+		if !sent {
+			due = append(due, r)
+		}
+	}
+	return due, nil
+}
+
+// Claim records that the reminder is being sent. It returns false when the reminder was
+// already claimed, e.g. by another instance of the api, in which case it must not be sent.
+func (r *MembershipReminder) Claim(client *sqlx.DB) (bool, error) {
+	result, err := client.Exec(`
+		INSERT INTO MembershipReminder (accountID, membershipID, kind, expiration) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING;
+	`, r.AccountID, r.MembershipID, r.Kind, r.Expiration)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// Release removes the claim of a reminder that could not be sent, so that it is retried.
+func (r *MembershipReminder) Release(client *sqlx.DB) error {
+	_, err := client.Exec(`
+		DELETE FROM MembershipReminder WHERE accountID = $1 AND membershipID = $2 AND kind = $3 AND expiration = $4;
+	`, r.AccountID, r.MembershipID, r.Kind, r.Expiration)
+	return err
+}
+
+// Data returns the data that the reminder's template is rendered with.
+func (r *MembershipReminder) Data(appDomain string, now time.Time) ReminderData {
+	community := Community{ID: r.CommunityID, Name: r.CommunityName, Slug: r.CommunitySlug, Timezone: r.CommunityTimezone}
+	return ReminderData{
+		FirstName:  r.FirstName,
+		LastName:   r.LastName,
+		Email:      r.Email,
+		Membership: r.MembershipName,
+		Community:  community.Name,
+		Expiration: r.Expiration.In(community.Location()).Format("January 2, 2006"),
+		Days:       int(math.Max(0, math.Ceil(r.Expiration.Sub(now).Hours()/24))),
+		RenewURL:   community.MapURL(appDomain),
+	}
+}
+
+// Validate checks the template's kind and that its subject and body are valid templates
+// that can be rendered with reminder data.
+func (t *ReminderTemplate) Validate() error {
+	if !isReminderKind(t.Kind) {
+		return errReminderKindInvalid
+	}
+	if t.Subject == "" || t.Body == "" {
+		return errReminderTemplateRequired
+	}
+	if err := t.Parse(); err != nil {
+		return errReminderTemplateInvalid
+	}
+	if _, _, err := t.Render(ReminderData{}); err != nil {
+		return errReminderTemplateInvalid
+	}
+	return nil
+}
+
+func isReminderKind(kind string) bool {
+	for _, k := range ReminderKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Save creates or replaces the community's template for the kind of reminder.
+func (t *ReminderTemplate) Save(client *sqlx.DB) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if _, err := client.Exec(`
+		INSERT INTO MembershipReminderTemplate (communityID, kind, subject, body) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (communityID, kind) DO UPDATE SET subject = EXCLUDED.subject, body = EXCLUDED.body;
+	`, t.CommunityID, t.Kind, t.Subject, t.Body); err != nil {
+		return err
+	}
+	t.Custom = true
+	return nil
+}
+
+// DeleteReminderTemplate resets the community's template for the kind of reminder to the default.
+func DeleteReminderTemplate(communityID int, kind string, client *sqlx.DB) error {
+	_, err := client.Exec("DELETE FROM MembershipReminderTemplate WHERE communityID = $1 AND kind = $2;", communityID, kind)
+	return err
+}
+
+// GetReminderTemplate returns the community's template for the kind of reminder.
+func GetReminderTemplate(communityID int, kind string, client *sqlx.DB) (ReminderTemplate, error) {
+	if !isReminderKind(kind) {
+		return ReminderTemplate{}, errReminderKindInvalid
+	}
+	t := ReminderTemplate{CommunityID: communityID, Kind: kind, Template: DefaultReminderTemplates[kind]}
+	err := client.Get(&t.Template, "SELECT subject, body FROM MembershipReminderTemplate WHERE communityID = $1 AND kind = $2;", communityID, kind)
+	if err == sql.ErrNoRows {
+		return t, nil
+	} else if err != nil {
+		return t, err
+	}
+	t.Custom = true
+	return t, nil
+}
+
+// GetReminderTemplatesByCommunity returns the community's template for every kind of reminder.
+func GetReminderTemplatesByCommunity(communityID int, client *sqlx.DB) ([]ReminderTemplate, error) {
+	templates := []ReminderTemplate{}
+	for _, kind := range ReminderKinds {
+		t, err := GetReminderTemplate(communityID, kind, client)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jteppinette/peragrin-api/mail"
+)
+
+func TestReminderKind(t *testing.T) {
+	now := time.Date(2017, 10, 30, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expiration time.Time
+		expected   string
+	}{
+		{now.AddDate(0, 0, 31), ""},
+		{now.AddDate(0, 0, 30), ReminderKind30Days},
+		{now.AddDate(0, 0, 8), ReminderKind30Days},
+		{now.AddDate(0, 0, 7), ReminderKind7Days},
+		{now.Add(25 * time.Hour), ReminderKind7Days},
+		{now.Add(time.Hour), ReminderKind1Day},
+		{now, ReminderKindExpired},
+		{now.AddDate(0, 0, -6), ReminderKindExpired},
+		{now.AddDate(0, 0, -8), ""},
+	}
+	for _, test := range tests {
+		if actual := reminderKind(test.expiration, now); actual != test.expected {
+			t.Errorf("expected %q for %s, got %q", test.expected, test.expiration, actual)
+		}
+	}
+}
+
+func TestReminderTemplate(t *testing.T) {
+	now := time.Date(2017, 10, 30, 12, 0, 0, 0, time.UTC)
+	r := MembershipReminder{
+		Kind: ReminderKind7Days, Expiration: time.Date(2017, 11, 6, 3, 0, 0, 0, time.UTC), FirstName: "Ann",
+		MembershipName: "Gold", CommunityID: 3, CommunityName: "Carytown", CommunitySlug: "carytown", CommunityTimezone: "America/New_York",
+	}
+	data := r.Data("http://app", now)
+	if data.Expiration != "November 5, 2017" || data.Days != 7 || data.RenewURL != "http://app/#/map?community=3&slug=carytown" {
+		t.Errorf("unexpected data: %+v", data)
+	}
+
+	for kind, template := range DefaultReminderTemplates {
+		rt := ReminderTemplate{Kind: kind, Template: template}
+		if err := rt.Validate(); err != nil {
+			t.Errorf("expected default %s template to be valid, got %v", kind, err)
+		}
+	}
+
+	subject, body, err := mail.Template{Subject: "{{.Membership}}\r\nBcc: everyone", Body: "{{.Days}} days"}.Render(data)
+	if err != nil || subject != "Gold Bcc: everyone" || body != "7 days" {
+		t.Errorf("unexpected render: %q, %q, %v", subject, body, err)
+	}
+
+	tests := []struct {
+		template ReminderTemplate
+		expected error
+	}{
+		{ReminderTemplate{Kind: "14-days", Template: mail.Template{Subject: "a", Body: "b"}}, errReminderKindInvalid},
+		{ReminderTemplate{Kind: ReminderKindExpired, Template: mail.Template{Subject: "a"}}, errReminderTemplateRequired},
+		{ReminderTemplate{Kind: ReminderKindExpired, Template: mail.Template{Subject: "{{.Membership", Body: "b"}}, errReminderTemplateInvalid},
+		{ReminderTemplate{Kind: ReminderKindExpired, Template: mail.Template{Subject: "{{.Price}}", Body: "b"}}, errReminderTemplateInvalid},
+	}
+	for _, test := range tests {
+		if err := test.template.Validate(); err != test.expected {
+			t.Errorf("expected %v for %+v, got %v", test.expected, test.template, err)
+		}
+	}
+}
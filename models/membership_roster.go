@@ -0,0 +1,375 @@
+package models
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/common"
+)
+
+const (
+	// RosterActionCreate rows create an account that joins the membership.
+	RosterActionCreate = "create"
+	// RosterActionAttach rows join an existing account to the membership.
+	RosterActionAttach = "attach"
+	// RosterActionUpdate rows change the expiration of an existing member.
+	RosterActionUpdate = "update"
+	// RosterActionUnchanged rows are existing members whose expiration does not change.
+	RosterActionUnchanged = "unchanged"
+	// RosterActionRemove removals remove members that are not in the roster.
+	RosterActionRemove = "remove"
+)
+
+// RosterFields are the fields of a roster row that can be read from csv columns.
+var RosterFields = []string{"email", "firstName", "lastName", "expiration"}
+
+// rosterDateFormats are the formats that roster expirations can be written in. Dates
+// without a time expire at the end of the day in the community's time zone.
+var rosterDateFormats = []string{"2006-01-02", "01/02/2006", "1/2/2006"}
+
+// RosterRow is a member of an imported roster, what applying the roster does to it, and any
+// problems that were found while processing it. Expiration is the expiration that the member
+// will have, and PreviousExpiration is the one that an existing member has.
+type RosterRow struct {
+	Row                int                 `json:"row"`
+	Email              string              `json:"email"`
+	FirstName          string              `json:"firstName"`
+	LastName           string              `json:"lastName"`
+	Expiration         common.JSONNullTime `json:"expiration"`
+	Action             string              `json:"action"`
+	AccountID          int                 `json:"accountID,omitempty"`
+	PreviousExpiration common.JSONNullTime `json:"previousExpiration"`
+	Errors             []string            `json:"errors"`
+
+	// requested is whether the roster provided the expiration.
+	requested bool
+}
+
+func (row *RosterRow) errorf(format string, args ...interface{}) {
+	row.Errors = append(row.Errors, fmt.Sprintf(format, args...))
+}
+
+// RosterImport is the reconciliation of a membership with an imported roster. When
+// RemoveMissing is set, members that are not in the roster are removed from the membership.
+type RosterImport struct {
+	DryRun        bool `json:"dryRun"`
+	RemoveMissing bool `json:"removeMissing"`
+	Valid         bool `json:"valid"`
+
+	Rows     []RosterRow    `json:"rows"`
+	Removals []RosterRow    `json:"removals"`
+	Summary  map[string]int `json:"summary"`
+}
+
+// ParseRosterCSV reads a roster from a csv file whose first record is a header. Columns maps
+// roster fields to the header of the column that they are read from. Fields that are not
+// mapped are read from the column with the field's name, ignoring case, spaces and
+// underscores, so that "First Name" is read as firstName. Expirations are written as
+// 2006-01-02 or 01/02/2006 in loc, or are empty to use the membership's plan.
+func ParseRosterCSV(reader io.Reader, columns map[string]string, loc *time.Location) (*RosterImport, error) {
+	r := csv.NewReader(reader)
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	indexes := map[string]int{}
+	for _, field := range RosterFields {
+		name, mapped := columns[field]
+		if !mapped {
+			name = field
+		}
+		for i, h := range header {
+			if normalizeRosterColumn(h) == normalizeRosterColumn(name) {
+				indexes[field] = i
+				break
+			}
+		}
+		if _, ok := indexes[field]; !ok && mapped {
+			return nil, fmt.Errorf("column not found: %q", name)
+		}
+	}
+	for field := range columns {
+		if !isRosterField(field) {
+			return nil, fmt.Errorf("unknown roster field: %q", field)
+		}
+	}
+	if _, ok := indexes["email"]; !ok {
+		return nil, errRosterEmailColumnRequired
+	}
+
+	result := &RosterImport{Rows: []RosterRow{}}
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		get := func(field string) string {
+			if i, ok := indexes[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := RosterRow{Row: line, Email: get("email"), FirstName: get("firstName"), LastName: get("lastName")}
+		if v := get("expiration"); v != "" {
+			if row.Expiration, err = parseRosterDate(v, loc); err != nil {
+				row.errorf("invalid expiration: %q", v)
+			}
+			row.requested = true
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// NewRosterImport returns a roster of the accounts, whose expirations are used when they are set.
+func NewRosterImport(accounts Accounts) *RosterImport {
+	result := &RosterImport{Rows: []RosterRow{}}
+	for i, a := range accounts {
+		result.Rows = append(result.Rows, RosterRow{
+			Row:        i + 1,
+			Email:      strings.TrimSpace(a.Email),
+			FirstName:  a.FirstName,
+			LastName:   a.LastName,
+			Expiration: a.Expiration,
+			requested:  a.Expiration.Valid,
+		})
+	}
+	return result
+}
+
+func normalizeRosterColumn(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.TrimSpace(name)))
+}
+
+func isRosterField(field string) bool {
+	for _, f := range RosterFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRosterDate(value string, loc *time.Location) (common.JSONNullTime, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return common.NewJSONNullTime(t), nil
+	}
+	for _, format := range rosterDateFormats {
+		if t, err := time.ParseInLocation(format, value, loc); err == nil {
+			return common.NewJSONNullTime(t.AddDate(0, 0, 1).Add(-time.Second)), nil
+		}
+	}
+	return common.JSONNullTime{}, errRosterDateInvalid
+}
+
+// Reconcile compares the roster with the membership's accounts and determines what applying
+// it does to every row, and sets the Valid field.
+func (ri *RosterImport) Reconcile(m *Membership, client *sqlx.DB) error {
+	emails := []string{}
+	for i := range ri.Rows {
+		ri.Rows[i].Email = strings.ToLower(ri.Rows[i].Email)
+		emails = append(emails, ri.Rows[i].Email)
+	}
+
+	accounts := map[string]int{}
+	if len(emails) > 0 {
+		existing, err := GetAccountsByEmails(emails, client)
+		if err != nil {
+			return err
+		}
+		for _, a := range existing {
+			accounts[a.Email] = a.ID
+		}
+	}
+
+	members, err := GetAccountsByMembership(m.ID, client)
+	if err != nil {
+		return err
+	}
+	for i := range members {
+		members[i].Email = strings.ToLower(members[i].Email)
+	}
+
+	emails = []string{}
+	if err := client.Select(&emails, `
+		SELECT LOWER(Account.email) FROM MembershipSeat
+		INNER JOIN Account ON (MembershipSeat.accountID = Account.id)
+		WHERE MembershipSeat.membershipID = $1;
+	`, m.ID); err != nil {
+		return err
+	}
+	dependents := map[string]bool{}
+	for _, email := range emails {
+		dependents[email] = true
+	}
+
+	ri.reconcile(m, accounts, members, dependents, time.Now())
+	return nil
+}
+
+// reconcile determines the action of every row given the ids of the existing accounts by
+// email, the membership's current members and the emails of its dependents, who can not
+// be added to the membership while they have a seat of it.
+func (ri *RosterImport) reconcile(m *Membership, accounts map[string]int, members Accounts, dependents map[string]bool, now time.Time) {
+	current := map[string]Account{}
+	for _, a := range members {
+		current[a.Email] = a
+	}
+
+	ri.Valid = true
+	seen := map[string]int{}
+	for i := range ri.Rows {
+		row := &ri.Rows[i]
+		row.Email = strings.ToLower(row.Email)
+
+		if row.Email == "" {
+			row.errorf("email required")
+		} else if _, err := mail.ParseAddress(row.Email); err != nil {
+			row.errorf("invalid email: %q", row.Email)
+		} else if first, ok := seen[row.Email]; ok {
+			row.errorf("duplicate email: %q is also in row %d", row.Email, first)
+		} else {
+			seen[row.Email] = row.Row
+		}
+
+		if member, ok := current[row.Email]; ok {
+			row.AccountID = member.ID
+			row.PreviousExpiration = member.Expiration
+			switch {
+			case !row.requested:
+				row.Expiration, row.Action = member.Expiration, RosterActionUnchanged
+			case sameExpiration(row.Expiration, member.Expiration):
+				row.Action = RosterActionUnchanged
+			default:
+				row.Action = RosterActionUpdate
+			}
+		} else {
+			row.Action = RosterActionCreate
+			if id, ok := accounts[row.Email]; ok {
+				row.AccountID, row.Action = id, RosterActionAttach
+			}
+			if dependents[row.Email] {
+				row.errorf("%q is a dependent of the membership", row.Email)
+			}
+			if !row.requested {
+				expiration, err := m.ExpirationAt(now, common.JSONNullTime{})
+				if err != nil {
+					row.errorf("%s", err.Error())
+				}
+				row.Expiration = expiration
+			}
+		}
+
+		if row.Errors == nil {
+			row.Errors = []string{}
+		}
+		if len(row.Errors) > 0 {
+			row.Action = ""
+			ri.Valid = false
+		}
+	}
+
+	ri.Removals = []RosterRow{}
+	if ri.RemoveMissing {
+		for _, a := range members {
+			if _, ok := seen[a.Email]; ok {
+				continue
+			}
+			ri.Removals = append(ri.Removals, RosterRow{
+				Email: a.Email, FirstName: a.FirstName, LastName: a.LastName, AccountID: a.ID,
+				PreviousExpiration: a.Expiration, Action: RosterActionRemove, Errors: []string{},
+			})
+		}
+		sort.Slice(ri.Removals, func(i, j int) bool { return ri.Removals[i].Email < ri.Removals[j].Email })
+	}
+
+	ri.Summary = map[string]int{RosterActionCreate: 0, RosterActionAttach: 0, RosterActionUpdate: 0, RosterActionUnchanged: 0, RosterActionRemove: len(ri.Removals)}
+	for _, row := range ri.Rows {
+		if row.Action != "" {
+			ri.Summary[row.Action]++
+		}
+	}
+}
+
+func sameExpiration(a, b common.JSONNullTime) bool {
+	return a.Valid == b.Valid && (!a.Valid || a.Time.Equal(b.Time))
+}
+
+// Apply changes the membership's accounts to match the roster in a single transaction.
+// Nothing is changed if the roster is not valid or is a dry run. The ids of created
+// accounts are set on their rows.
+func (ri *RosterImport) Apply(membershipID int, client *sqlx.DB) error {
+	if !ri.Valid {
+		return errRosterInvalid
+	}
+	if ri.DryRun {
+		return nil
+	}
+
+	tx, err := client.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	for i := range ri.Rows {
+		row := &ri.Rows[i]
+		switch row.Action {
+		case RosterActionCreate:
+			if err = tx.Get(&row.AccountID, "INSERT INTO Account (email, firstName, lastName) VALUES ($1, $2, $3) RETURNING id;", row.Email, row.FirstName, row.LastName); err != nil {
+				return err
+			}
+			fallthrough
+		case RosterActionAttach:
+			if _, err = tx.Exec("INSERT INTO AccountMembership (accountID, membershipID, expiration) VALUES ($1, $2, $3);", row.AccountID, membershipID, row.Expiration); err != nil {
+				return err
+			}
+		case RosterActionUpdate:
+			if _, err = tx.Exec("UPDATE AccountMembership SET expiration = $3 WHERE accountID = $1 AND membershipID = $2;", row.AccountID, membershipID, row.Expiration); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, removal := range ri.Removals {
+		if _, err = tx.Exec("DELETE FROM AccountMembership WHERE accountID = $1 AND membershipID = $2;", removal.AccountID, membershipID); err != nil {
+			return err
+		}
+		if err = txRemoveDependents(removal.AccountID, membershipID, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Created returns the accounts that were created by applying the roster.
+func (ri *RosterImport) Created() Accounts {
+	accounts := Accounts{}
+	for _, row := range ri.Rows {
+		if row.Action == RosterActionCreate && row.AccountID != 0 {
+			accounts = append(accounts, Account{ID: row.AccountID, Email: row.Email, FirstName: row.FirstName, LastName: row.LastName, Expiration: row.Expiration})
+		}
+	}
+	return accounts
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jteppinette/peragrin-api/common"
+)
+
+func TestParseRosterCSV(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	data := `E-mail Address,First Name,last_name,Expires
+jane@example.com, Jane, Doe, 2018-01-31
+john@example.com,John,Smith,
+bob@example.com,Bob,,01/31/2018
+bad@example.com,Bad,,tomorrow
+`
+	result, err := ParseRosterCSV(strings.NewReader(data), map[string]string{"email": "e-mail address", "expiration": "Expires"}, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 4 {
+		t.Fatalf("got %d rows, want 4", len(result.Rows))
+	}
+
+	endOfJanuary := time.Date(2018, 1, 31, 23, 59, 59, 0, loc)
+	jane := result.Rows[0]
+	if jane.Row != 2 || jane.Email != "jane@example.com" || jane.FirstName != "Jane" || jane.LastName != "Doe" || !jane.Expiration.Time.Equal(endOfJanuary) || !jane.requested {
+		t.Errorf("got %+v", jane)
+	}
+	if john := result.Rows[1]; john.Expiration.Valid || john.requested {
+		t.Errorf("got %+v", john)
+	}
+	if bob := result.Rows[2]; !bob.Expiration.Time.Equal(endOfJanuary) {
+		t.Errorf("got %+v", bob)
+	}
+	if bad := result.Rows[3]; len(bad.Errors) != 1 {
+		t.Errorf("got errors %v, want 1", bad.Errors)
+	}
+
+	if _, err := ParseRosterCSV(strings.NewReader("name\nJane\n"), nil, loc); err != errRosterEmailColumnRequired {
+		t.Errorf("got %v, want %v", err, errRosterEmailColumnRequired)
+	}
+	if _, err := ParseRosterCSV(strings.NewReader("email\n"), map[string]string{"email": "mail"}, loc); err == nil {
+		t.Error("expected a missing mapped column to be an error")
+	}
+	if _, err := ParseRosterCSV(strings.NewReader("email\n"), map[string]string{"phone": "email"}, loc); err == nil {
+		t.Error("expected an unknown field to be an error")
+	}
+}
+
+func TestRosterReconcile(t *testing.T) {
+	now := time.Date(2017, 10, 31, 0, 0, 0, 0, time.UTC)
+	m := &Membership{ID: 1, Duration: DurationAnnual}
+	current := common.NewJSONNullTime(now.AddDate(0, 6, 0))
+	later := common.NewJSONNullTime(now.AddDate(2, 0, 0))
+
+	ri := NewRosterImport(Accounts{
+		{Email: "New@Example.com"},
+		{Email: "existing@example.com", Expiration: later},
+		{Email: "member@example.com"},
+		{Email: "renewed@example.com", Expiration: later},
+		{Email: "same@example.com", Expiration: current},
+	})
+	ri.RemoveMissing = true
+	members := Accounts{
+		{ID: 3, Email: "member@example.com", Expiration: current},
+		{ID: 4, Email: "renewed@example.com", Expiration: current},
+		{ID: 5, Email: "same@example.com", Expiration: current},
+		{ID: 6, Email: "gone@example.com", Expiration: current},
+	}
+	ri.reconcile(m, map[string]int{"existing@example.com": 2, "member@example.com": 3}, members, map[string]bool{}, now)
+
+	if !ri.Valid {
+		t.Fatalf("got invalid roster %+v", ri.Rows)
+	}
+	for i, want := range []struct {
+		action     string
+		accountID  int
+		expiration common.JSONNullTime
+	}{
+		{RosterActionCreate, 0, common.NewJSONNullTime(now.AddDate(1, 0, 0))},
+		{RosterActionAttach, 2, later},
+		{RosterActionUnchanged, 3, current},
+		{RosterActionUpdate, 4, later},
+		{RosterActionUnchanged, 5, current},
+	} {
+		row := ri.Rows[i]
+		if row.Action != want.action || row.AccountID != want.accountID || !sameExpiration(row.Expiration, want.expiration) {
+			t.Errorf("%s: got %s of %d until %v, want %s of %d until %v", row.Email, row.Action, row.AccountID, row.Expiration.Time, want.action, want.accountID, want.expiration.Time)
+		}
+	}
+	if ri.Rows[0].Email != "new@example.com" {
+		t.Errorf("got email %q, want it lower cased", ri.Rows[0].Email)
+	}
+	if len(ri.Removals) != 1 || ri.Removals[0].AccountID != 6 {
+		t.Errorf("got removals %+v", ri.Removals)
+	}
+	want := map[string]int{RosterActionCreate: 1, RosterActionAttach: 1, RosterActionUpdate: 1, RosterActionUnchanged: 2, RosterActionRemove: 1}
+	for action, n := range want {
+		if ri.Summary[action] != n {
+			t.Errorf("got %d %s rows, want %d", ri.Summary[action], action, n)
+		}
+	}
+}
+
+func TestRosterReconcileErrors(t *testing.T) {
+	now := time.Date(2017, 10, 31, 0, 0, 0, 0, time.UTC)
+	m := &Membership{ID: 1, Duration: DurationSeason}
+
+	ri := NewRosterImport(Accounts{{Email: ""}, {Email: "not an email"}, {Email: "a@example.com"}, {Email: "A@example.com"}, {Email: "c@example.com"}})
+	ri.reconcile(m, map[string]int{"c@example.com": 3}, Accounts{{ID: 1, Email: "b@example.com"}}, map[string]bool{"c@example.com": true}, now)
+
+	if ri.Valid {
+		t.Fatal("got valid roster")
+	}
+	for i, n := range []int{2, 2, 1, 2, 2} {
+		if len(ri.Rows[i].Errors) != n {
+			t.Errorf("row %d: got errors %v, want %d", ri.Rows[i].Row, ri.Rows[i].Errors, n)
+		}
+	}
+	if ri.Rows[0].Action != "" || len(ri.Removals) != 0 {
+		t.Errorf("got action %q and %d removals", ri.Rows[0].Action, len(ri.Removals))
+	}
+	if err := ri.Apply(1, nil); err != errRosterInvalid {
+		t.Errorf("got %v, want %v", err, errRosterInvalid)
+	}
+}
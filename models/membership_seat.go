@@ -0,0 +1,210 @@
+package models
+
+import (
+	"database/sql"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/common"
+)
+
+// MembershipSeat is a seat of an account membership that its primary holder gave to a
+// dependent. Seats do not have an expiration of their own, so dependents can redeem the
+// community's promotions for as long as the primary holder is a member. Seats that were
+// given to existing accounts are invitations until the dependent accepts them.
+type MembershipSeat struct {
+	MembershipID     int                 `json:"membershipID"`
+	PrimaryAccountID int                 `json:"primaryAccountID"`
+	AccountID        int                 `json:"accountID"`
+	Email            string              `json:"email"`
+	FirstName        string              `json:"firstName"`
+	LastName         string              `json:"lastName"`
+	CreatedAt        time.Time           `json:"createdAt"`
+	AcceptedAt       common.JSONNullTime `json:"acceptedAt"`
+}
+
+// MembershipSeats are the seats of an account membership. Seats includes the seat of the
+// primary holder.
+type MembershipSeats struct {
+	Seats      int                 `json:"seats"`
+	Available  int                 `json:"available"`
+	Expiration common.JSONNullTime `json:"expiration"`
+	Dependents []MembershipSeat    `json:"dependents"`
+}
+
+const membershipSeatColumns = `
+	MembershipSeat.membershipID, MembershipSeat.primaryAccountID, MembershipSeat.accountID, MembershipSeat.createdAt,
+	MembershipSeat.acceptedAt, Account.email, Account.firstName, Account.lastName
+`
+
+// availableSeats returns how many dependents can be added to an account membership of a
+// membership with seats that already has the dependents. Memberships can be given fewer
+// seats than their account memberships use, in which case no seats are available.
+func availableSeats(seats, dependents int) int {
+	if available := seats - 1 - dependents; available > 0 {
+		return available
+	}
+	return 0
+}
+
+// AddDependent gives a seat of the account's membership to the account with the email, which
+// is created when it does not exist, in which case created is true. Seats of created accounts
+// are accepted, while existing accounts are invited and must accept the seat themselves.
+// Accounts can only have a membership once, either as its holder or as a dependent.
+func (a *Account) AddDependent(membershipID int, dependent *Account, client *sqlx.DB) (seat *MembershipSeat, created bool, err error) {
+	dependent.Email = strings.ToLower(strings.TrimSpace(dependent.Email))
+	if _, err := mail.ParseAddress(dependent.Email); err != nil {
+		return nil, false, errDependentEmailInvalid
+	}
+
+	tx, err := client.Beginx()
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	m, err := getMembershipByID(membershipID, tx)
+	if err != nil {
+		return nil, false, err
+	}
+	if m == nil {
+		err = errMembershipNotFound
+		return nil, false, err
+	}
+
+	// The account membership is locked so that concurrent invitations can not take more seats
+	// than the membership has.
+	var expiration common.JSONNullTime
+	if err = tx.Get(&expiration, "SELECT expiration FROM AccountMembership WHERE accountID = $1 AND membershipID = $2 FOR UPDATE;", a.ID, membershipID); err == sql.ErrNoRows {
+		err = errAccountMembershipNotFound
+		return nil, false, err
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	var dependents int
+	if err = tx.Get(&dependents, "SELECT COUNT(*) FROM MembershipSeat WHERE primaryAccountID = $1 AND membershipID = $2;", a.ID, membershipID); err != nil {
+		return nil, false, err
+	}
+	if availableSeats(m.Seats, dependents) == 0 {
+		err = ErrSeatsFull
+		return nil, false, err
+	}
+
+	err = tx.Get(dependent, "SELECT id, email, firstName, lastName, isSuper FROM Account WHERE LOWER(email) = $1;", dependent.Email)
+	if err == sql.ErrNoRows {
+		created = true
+		err = tx.Get(dependent, "INSERT INTO Account (email, firstName, lastName) VALUES ($1, $2, $3) RETURNING id, email, firstName, lastName, isSuper;", dependent.Email, dependent.FirstName, dependent.LastName)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if dependent.ID == a.ID {
+		err = errDependentIsHolder
+		return nil, false, err
+	}
+
+	var exists bool
+	if err = tx.Get(&exists, `
+		SELECT EXISTS(SELECT FROM AccountMembership WHERE accountID = $1 AND membershipID = $2)
+		OR EXISTS(SELECT FROM MembershipSeat WHERE accountID = $1 AND membershipID = $2);
+	`, dependent.ID, membershipID); err != nil {
+		return nil, false, err
+	}
+	if exists {
+		err = errAlreadyMember
+		return nil, false, err
+	}
+
+	seat = &MembershipSeat{Email: dependent.Email, FirstName: dependent.FirstName, LastName: dependent.LastName}
+	if err = tx.Get(seat, `
+		INSERT INTO MembershipSeat (membershipID, primaryAccountID, accountID, acceptedAt)
+		VALUES ($1, $2, $3, CASE WHEN $4 THEN NOW() END)
+		RETURNING membershipID, primaryAccountID, accountID, createdAt, acceptedAt;
+	`, membershipID, a.ID, dependent.ID, created); err != nil {
+		return nil, false, err
+	}
+	dependent.Expiration = expiration
+	return seat, created, nil
+}
+
+// AcceptSeat accepts the account's invitation to a seat of the primary holder's membership.
+// Accounts that have the membership themselves can not accept a seat of it.
+func (a *Account) AcceptSeat(membershipID, primaryAccountID int, client *sqlx.DB) (*MembershipSeat, error) {
+	seat := &MembershipSeat{Email: a.Email, FirstName: a.FirstName, LastName: a.LastName}
+	if err := client.Get(seat, `
+		UPDATE MembershipSeat SET acceptedAt = NOW()
+		WHERE membershipID = $1 AND primaryAccountID = $2 AND accountID = $3 AND acceptedAt IS NULL
+		AND NOT EXISTS(SELECT FROM AccountMembership WHERE accountID = $3 AND membershipID = $1)
+		RETURNING membershipID, primaryAccountID, accountID, createdAt, acceptedAt;
+	`, membershipID, primaryAccountID, a.ID); err == sql.ErrNoRows {
+		return nil, errSeatInvitationNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return seat, nil
+}
+
+// RemoveDependent takes the seat of the account's membership away from the dependent.
+func (a *Account) RemoveDependent(membershipID, dependentID int, client *sqlx.DB) error {
+	result, err := client.Exec("DELETE FROM MembershipSeat WHERE primaryAccountID = $1 AND membershipID = $2 AND accountID = $3;", a.ID, membershipID, dependentID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errDependentNotFound
+	}
+	return nil
+}
+
+// isDependent reports whether the account has a seat of the membership.
+func isDependent(accountID, membershipID int, q sqlx.Queryer) (bool, error) {
+	var exists bool
+	err := sqlx.Get(q, &exists, "SELECT EXISTS(SELECT FROM MembershipSeat WHERE accountID = $1 AND membershipID = $2);", accountID, membershipID)
+	return exists, err
+}
+
+// txRemoveDependents takes every seat of the account's membership away from its dependents.
+// It is used when the account membership is removed.
+func txRemoveDependents(accountID, membershipID int, tx *sqlx.Tx) error {
+	_, err := tx.Exec("DELETE FROM MembershipSeat WHERE primaryAccountID = $1 AND membershipID = $2;", accountID, membershipID)
+	return err
+}
+
+// GetMembershipSeats returns the seats of the account's membership, or nil if the account
+// does not have the membership.
+func GetMembershipSeats(accountID, membershipID int, client *sqlx.DB) (*MembershipSeats, error) {
+	seats := &MembershipSeats{Dependents: []MembershipSeat{}}
+	if err := client.Get(seats, `
+		SELECT Membership.seats, AccountMembership.expiration FROM AccountMembership
+		INNER JOIN Membership ON (AccountMembership.membershipID = Membership.id)
+		WHERE AccountMembership.accountID = $1 AND AccountMembership.membershipID = $2;
+	`, accountID, membershipID); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := client.Select(&seats.Dependents, `
+		SELECT `+membershipSeatColumns+` FROM MembershipSeat
+		INNER JOIN Account ON (MembershipSeat.accountID = Account.id)
+		WHERE MembershipSeat.primaryAccountID = $1 AND MembershipSeat.membershipID = $2
+		ORDER BY MembershipSeat.createdAt;
+	`, accountID, membershipID); err != nil {
+		return nil, err
+	}
+	seats.Available = availableSeats(seats.Seats, len(seats.Dependents))
+	return seats, nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import "testing"
+
+func TestAvailableSeats(t *testing.T) {
+	tests := []struct {
+		seats, dependents, expected int
+	}{
+		{1, 0, 0},
+		{4, 0, 3},
+		{4, 2, 1},
+		{4, 3, 0},
+		{2, 3, 0},
+	}
+	for _, test := range tests {
+		if available := availableSeats(test.seats, test.dependents); available != test.expected {
+			t.Errorf("expected %d available seats of %d with %d dependents, got %d", test.expected, test.seats, test.dependents, available)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jteppinette/peragrin-api/common"
+)
+
+func TestMembershipValidate(t *testing.T) {
+	start := time.Date(2017, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2017, 9, 30, 0, 0, 0, 0, time.UTC)
+
+	m := Membership{}
+	if err := m.Validate(); err != nil || m.Duration != DurationAnnual {
+		t.Errorf("expected default annual duration, got %q, %v", m.Duration, err)
+	}
+	if m.Seats != 1 {
+		t.Errorf("expected default of 1 seat, got %d", m.Seats)
+	}
+
+	tests := []struct {
+		membership Membership
+		expected   error
+	}{
+		{Membership{Duration: DurationMonthly}, nil},
+		{Membership{Duration: DurationLifetime, GracePeriodDays: 30}, nil},
+		{Membership{Duration: DurationSeason, SeasonStart: common.NewJSONNullTime(start), SeasonEnd: common.NewJSONNullTime(end)}, nil},
+		{Membership{Duration: DurationSeason, SeasonStart: common.NewJSONNullTime(end), SeasonEnd: common.NewJSONNullTime(start)}, errSeasonInvalid},
+		{Membership{Duration: DurationSeason}, errSeasonInvalid},
+		{Membership{Duration: "weekly"}, errDurationInvalid},
+		{Membership{Duration: DurationAnnual, GracePeriodDays: -1}, errGracePeriodInvalid},
+		{Membership{Duration: DurationAnnual, Seats: 4}, nil},
+		{Membership{Duration: DurationAnnual, Seats: -1}, errSeatsInvalid},
+	}
+	for _, test := range tests {
+		if err := test.membership.Validate(); err != test.expected {
+			t.Errorf("expected %v for %+v, got %v", test.expected, test.membership, err)
+		}
+	}
+}
+
+func TestMembershipExpirationAt(t *testing.T) {
+	now := time.Date(2017, 10, 28, 12, 0, 0, 0, time.UTC)
+	end := time.Date(2017, 12, 31, 0, 0, 0, 0, time.UTC)
+	none := common.JSONNullTime{}
+
+	tests := []struct {
+		membership Membership
+		current    common.JSONNullTime
+		expected   common.JSONNullTime
+	}{
+		{Membership{Duration: DurationMonthly}, none, common.NewJSONNullTime(now.AddDate(0, 1, 0))},
+		{Membership{Duration: DurationAnnual}, none, common.NewJSONNullTime(now.AddDate(1, 0, 0))},
+		{Membership{Duration: DurationLifetime}, common.NewJSONNullTime(now), none},
+		{Membership{Duration: DurationSeason, SeasonEnd: common.NewJSONNullTime(end)}, none, common.NewJSONNullTime(end)},
+		// Early renewals extend the current expiration.
+		{Membership{Duration: DurationAnnual}, common.NewJSONNullTime(now.AddDate(0, 0, 10)), common.NewJSONNullTime(now.AddDate(1, 0, 10))},
+		// Renewals within the grace period extend the current expiration.
+		{Membership{Duration: DurationMonthly, GracePeriodDays: 7}, common.NewJSONNullTime(now.AddDate(0, 0, -3)), common.NewJSONNullTime(now.AddDate(0, 1, -3))},
+		// Renewals after the grace period start from now.
+		{Membership{Duration: DurationMonthly, GracePeriodDays: 7}, common.NewJSONNullTime(now.AddDate(0, 0, -30)), common.NewJSONNullTime(now.AddDate(0, 1, 0))},
+	}
+	for _, test := range tests {
+		actual, err := test.membership.ExpirationAt(now, test.current)
+		if err != nil {
+			t.Errorf("unexpected error for %+v: %v", test.membership, err)
+			continue
+		}
+		if actual.Valid != test.expected.Valid || !actual.Time.Equal(test.expected.Time) {
+			t.Errorf("expected %v for %+v, got %v", test.expected, test.membership, actual)
+		}
+	}
+
+	m := Membership{Duration: DurationSeason, SeasonEnd: common.NewJSONNullTime(now.AddDate(0, 0, -1))}
+	if _, err := m.ExpirationAt(now, none); err != errSeasonEnded {
+		t.Errorf("expected %v, got %v", errSeasonEnded, err)
+	}
+
+	m = Membership{Duration: DurationSeason, SeasonStart: common.NewJSONNullTime(now.AddDate(0, 0, 3)), SeasonEnd: common.NewJSONNullTime(end)}
+	if status := m.statusAt(now); status != MembershipStatusUpcoming {
+		t.Errorf("expected a season that has not started to be upcoming, got %q", status)
+	}
+	if status := m.statusAt(now.AddDate(0, 0, 3)); status != MembershipStatusActive {
+		t.Errorf("expected a season that started to be active, got %q", status)
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		t        time.Time
+		months   int
+		expected time.Time
+	}{
+		{time.Date(2017, 1, 31, 12, 0, 0, 0, time.UTC), 1, time.Date(2017, 2, 28, 12, 0, 0, 0, time.UTC)},
+		{time.Date(2016, 1, 31, 12, 0, 0, 0, time.UTC), 1, time.Date(2016, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{time.Date(2017, 3, 31, 0, 0, 0, 0, time.UTC), 1, time.Date(2017, 4, 30, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2017, 12, 15, 0, 0, 0, 0, time.UTC), 1, time.Date(2018, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2016, 2, 29, 0, 0, 0, 0, time.UTC), 12, time.Date(2017, 2, 28, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		if actual := addMonths(test.t, test.months); !actual.Equal(test.expected) {
+			t.Errorf("expected %d months after %s to be %s, got %s", test.months, test.t, test.expected, actual)
+		}
+	}
+}
+
+func TestMembershipAmount(t *testing.T) {
+	for price, expected := range map[float64]int64{0: 0, 19.99: 1999, 0.295: 30, 100: 10000} {
+		if actual := (&Membership{Price: price}).Amount(); actual != expected {
+			t.Errorf("expected %v to be %d, got %d", price, expected, actual)
+		}
+	}
+}
@@ -6,10 +6,11 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	minio "github.com/minio/minio-go"
-)
 
-const bucket = "peragrin"
+	"github.com/jteppinette/peragrin-api/geocoder"
+	"github.com/jteppinette/peragrin-api/geometry"
+	"github.com/jteppinette/peragrin-api/store"
+)
 
 // Organizations is a list of organization structs.
 type Organizations []Organization
@@ -29,6 +30,20 @@ type Organization struct {
 	Category string  `json:"category"`
 	Logo     string  `json:"logo"`
 
+	// GeocodeConfidence and GeocodeSource describe how the organization's coordinates were
+	// determined. They are set when the organization is saved and cannot be changed by clients.
+	GeocodeConfidence float64 `json:"geocodeConfidence"`
+	GeocodeSource     string  `json:"geocodeSource"`
+
+	// NeedsReview is set when the organization could not be located or is outside of one
+	// of its communities. ReviewReason describes each problem that was found.
+	NeedsReview  bool   `json:"needsReview"`
+	ReviewReason string `json:"reviewReason"`
+
+	// Verified is set once ownership of this organization has been proven
+	// through an OrganizationClaim. Unverified organizations cannot create promotions.
+	Verified bool `json:"verified"`
+
 	// LogoURL is used to send the presigned Logo url to the client.
 	LogoURL string `json:"logoURL"`
 
@@ -41,26 +56,25 @@ type Organization struct {
 }
 
 // UploadLogo puts a new object in the static store.
-func (o *Organization) UploadLogo(reader io.Reader, client *minio.Client) error {
-	_, err := client.PutObject(bucket, fmt.Sprintf("logos/%d-%s", o.ID, o.Logo), reader, "application/octet-stream")
-	return err
+func (o *Organization) UploadLogo(reader io.Reader, client store.Store) error {
+	return client.Put(fmt.Sprintf("logos/%d-%s", o.ID, o.Logo), reader, "application/octet-stream")
 }
 
 // SetPresignedLogoLink sets the Logo field with a presigned get request url.
-func (o *Organization) SetPresignedLogoLink(client *minio.Client) error {
+func (o *Organization) SetPresignedLogoLink(client store.Store) error {
 	if o.Logo == "" {
 		return nil
 	}
-	url, err := client.PresignedGetObject(bucket, fmt.Sprintf("logos/%d-%s", o.ID, o.Logo), time.Second*24*60*60, nil)
+	url, err := client.Presign(fmt.Sprintf("logos/%d-%s", o.ID, o.Logo), time.Second*24*60*60)
 	if err != nil {
 		return err
 	}
-	o.LogoURL = url.String()
+	o.LogoURL = url
 	return nil
 }
 
 // SetPresignedLogoLinks sets the Logo field with a presgned get request url for each organization provided.
-func (organizations Organizations) SetPresignedLogoLinks(client *minio.Client) error {
+func (organizations Organizations) SetPresignedLogoLinks(client store.Store) error {
 	for i, o := range organizations {
 		if err := o.SetPresignedLogoLink(client); err != nil {
 			return err
@@ -71,8 +85,15 @@ func (organizations Organizations) SetPresignedLogoLinks(client *minio.Client) e
 }
 
 // CreateWithAccount persists a new organization with hours in the database and creates the
-// account - organization relationship.
-func (o *Organization) CreateWithAccount(accountID int, client *sqlx.DB) error {
+// account - organization relationship. The organization is located with the provided geocoder.
+//
+// Organizations that are created by an account are unverified until the account claims them,
+// either with a code that is sent to the organization's email address or by asking the
+// administrators of its communities, or the super users when it has none, to approve it.
+func (o *Organization) CreateWithAccount(accountID int, g geocoder.Geocoder, client *sqlx.DB) error {
+	_, locateErr := o.locate(g, nil)
+	o.review(locateErr, nil)
+
 	tx, err := client.Beginx()
 	if err != nil {
 		return err
@@ -103,8 +124,17 @@ func (o *Organization) CreateWithAccount(accountID int, client *sqlx.DB) error {
 }
 
 // CreateWithCommunity persists a new organization with hours in the database and creates the
-// community - organization relationship.
-func (o *Organization) CreateWithCommunity(communityID int, client *sqlx.DB) error {
+// community - organization relationship. The organization is located with the provided geocoder,
+// and it must be inside of the community's boundary unless it could not be located.
+func (o *Organization) CreateWithCommunity(communityID int, g geocoder.Geocoder, client *sqlx.DB) error {
+	_, locateErr := o.locate(g, nil)
+	if locateErr == nil {
+		if err := checkBoundary(communityID, o.Lon, o.Lat, client); err != nil {
+			return err
+		}
+	}
+	o.review(locateErr, nil)
+
 	tx, err := client.Beginx()
 	if err != nil {
 		return err
@@ -141,23 +171,41 @@ func (o *Organization) CreateWithCommunity(communityID int, client *sqlx.DB) err
 
 func (o *Organization) txCreate(tx *sqlx.Tx) error {
 	return tx.Get(o, `
-		INSERT INTO Organization (name, street, city, state, country, zip, lon, lat, email, phone, website, category, logo)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		INSERT INTO Organization (name, street, city, state, country, zip, lon, lat, email, phone, website, category, logo, geocodeConfidence, geocodeSource, needsReview, reviewReason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 		RETURNING *;
-	`, o.Name, o.Street, o.City, o.State, o.Country, o.Zip, o.Lon, o.Lat, o.Email, o.Phone, o.Website, o.Category, "")
+	`, o.Name, o.Street, o.City, o.State, o.Country, o.Zip, o.Lon, o.Lat, o.Email, o.Phone, o.Website, o.Category, "", o.GeocodeConfidence, o.GeocodeSource, o.NeedsReview, o.ReviewReason)
 }
 
 func (o *Organization) txUpdate(tx *sqlx.Tx) error {
 	return tx.Get(o, `
 		UPDATE Organization
-		SET name = $2, street = $3, city = $4, state = $5, country = $6, zip = $7, lon = $8, lat = $9, email = $10, phone = $11, website = $12, category = $13, logo = $14
+		SET name = $2, street = $3, city = $4, state = $5, country = $6, zip = $7, lon = $8, lat = $9, email = $10, phone = $11, website = $12, category = $13, logo = $14,
+			geocodeConfidence = $15, geocodeSource = $16, needsReview = $17, reviewReason = $18
 		WHERE id = $1
 		RETURNING *;
-	`, o.ID, o.Name, o.Street, o.City, o.State, o.Country, o.Zip, o.Lon, o.Lat, o.Email, o.Phone, o.Website, o.Category, o.Logo)
+	`, o.ID, o.Name, o.Street, o.City, o.State, o.Country, o.Zip, o.Lon, o.Lat, o.Email, o.Phone, o.Website, o.Category, o.Logo, o.GeocodeConfidence, o.GeocodeSource, o.NeedsReview, o.ReviewReason)
 }
 
-// Update updates the fields of a given organization.
-func (o *Organization) Update(client *sqlx.DB) error {
+// Update updates the fields of a given organization. The organization is located again with the
+// provided geocoder when its address changed, and it is flagged for review when it was moved
+// outside of any of its communities.
+func (o *Organization) Update(g geocoder.Geocoder, client *sqlx.DB) error {
+	previous, err := GetOrganizationByID(o.ID, client)
+	if err != nil {
+		return err
+	}
+	moved, locateErr := o.locate(g, &previous)
+	if moved || locateErr != nil {
+		communities, err := GetCommunitiesByOrganization(o.ID, client)
+		if err != nil {
+			return err
+		}
+		o.review(locateErr, communities)
+	} else {
+		o.NeedsReview, o.ReviewReason = previous.NeedsReview, previous.ReviewReason
+	}
+
 	tx, err := client.Beginx()
 	if err != nil {
 		return err
@@ -195,12 +243,83 @@ func GetOrganizationByID(id int, client *sqlx.DB) (Organization, error) {
 // GetOrganizationsByCommunity returns all organizations that are a member of the given community.
 func GetOrganizationsByCommunity(communityID int, client *sqlx.DB) (Organizations, error) {
 	organizations := Organizations{}
-	if err := client.Select(&organizations, "SELECT Organization.*, CommunityOrganization.isAdministrator FROM Organization INNER JOIN CommunityOrganization ON (Organization.id = CommunityOrganization.organizationID) WHERE communityID = $1;", communityID); err != nil {
+	if err := client.Select(&organizations, "SELECT Organization.*, CommunityOrganization.isAdministrator FROM Organization INNER JOIN CommunityOrganization ON (Organization.id = CommunityOrganization.organizationID) WHERE communityID = $1 AND CommunityOrganization.status = 'approved';", communityID); err != nil {
 		return nil, err
 	}
 	return organizations, nil
 }
 
+// GetOrganizationsByBounds returns all organizations that are a member of the given
+// community and are located within the provided bounds.
+func GetOrganizationsByBounds(communityID int, b geometry.Bounds, client *sqlx.DB) (Organizations, error) {
+	organizations := Organizations{}
+	if err := client.Select(&organizations, `
+		SELECT Organization.*, CommunityOrganization.isAdministrator FROM Organization
+		INNER JOIN CommunityOrganization ON (Organization.id = CommunityOrganization.organizationID)
+		WHERE communityID = $1 AND CommunityOrganization.status = 'approved'
+		AND Organization.lon BETWEEN $2 AND $4 AND Organization.lat BETWEEN $3 AND $5;
+	`, communityID, b.MinLon, b.MinLat, b.MaxLon, b.MaxLat); err != nil {
+		return nil, err
+	}
+	return organizations, nil
+}
+
+// organizationBatchSize is how many organizations EachOrganizationByCommunity holds in memory.
+const organizationBatchSize = 100
+
+// EachOrganizationByCommunity calls fn with every organization that is a member of the given
+// community along with its hours. Organizations are read from the database in batches, and
+// the hours of each batch are read with it, so large communities are never held in memory.
+// Iteration stops at the first error returned by fn.
+func EachOrganizationByCommunity(communityID int, client *sqlx.DB, fn func(Organization) error) error {
+	rows, err := client.Queryx("SELECT Organization.*, CommunityOrganization.isAdministrator FROM Organization INNER JOIN CommunityOrganization ON (Organization.id = CommunityOrganization.organizationID) WHERE communityID = $1 AND CommunityOrganization.status = 'approved' ORDER BY Organization.name;", communityID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batch := make(Organizations, 0, organizationBatchSize)
+	each := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		ids := make([]int, len(batch))
+		for i, organization := range batch {
+			ids[i] = organization.ID
+		}
+		hours, err := GetHoursByOrganizations(ids, client)
+		if err != nil {
+			return err
+		}
+		for _, organization := range batch {
+			if organization.Hours = hours[organization.ID]; organization.Hours == nil {
+				organization.Hours = Hours{}
+			}
+			if err := fn(organization); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for rows.Next() {
+		organization := Organization{}
+		if err := rows.StructScan(&organization); err != nil {
+			return err
+		}
+		if batch = append(batch, organization); len(batch) == organizationBatchSize {
+			if err := each(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return each()
+}
+
 // GetOrganizationsByAccount returns all organizations that are operated by the given account.
 func GetOrganizationsByAccount(accountID int, client *sqlx.DB) (Organizations, error) {
 	organizations := Organizations{}
@@ -209,3 +328,18 @@ func GetOrganizationsByAccount(accountID int, client *sqlx.DB) (Organizations, e
 	}
 	return organizations, nil
 }
+
+// GetOrganizationsForReview returns the members of the given community that have been
+// flagged for review.
+func GetOrganizationsForReview(communityID int, client *sqlx.DB) (Organizations, error) {
+	organizations := Organizations{}
+	if err := client.Select(&organizations, `
+		SELECT Organization.*, CommunityOrganization.isAdministrator FROM Organization
+		INNER JOIN CommunityOrganization ON (Organization.id = CommunityOrganization.organizationID)
+		WHERE communityID = $1 AND CommunityOrganization.status = 'approved' AND Organization.needsReview
+		ORDER BY Organization.name;
+	`, communityID); err != nil {
+		return nil, err
+	}
+	return organizations, nil
+}
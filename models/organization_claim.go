@@ -0,0 +1,231 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/jteppinette/peragrin-api/common"
+	"github.com/jteppinette/peragrin-api/mail"
+)
+
+const (
+	// ClaimMethodEmail claims are verified by a code that is sent to the organization's email address.
+	ClaimMethodEmail = "email"
+	// ClaimMethodAdmin claims are verified by a community administrator or super user.
+	ClaimMethodAdmin = "admin"
+
+	ClaimStatusPending  = "pending"
+	ClaimStatusApproved = "approved"
+	ClaimStatusRejected = "rejected"
+
+	claimCodeExpiration  = time.Hour * 24
+	claimCodeMaxAttempts = 5
+
+	// Organizations receive at most claimRateLimit claims per claimRateWindow, so that their
+	// email address and administrators can not be flooded with codes and notifications.
+	claimRateLimit  = 3
+	claimRateWindow = time.Hour
+)
+
+// OrganizationClaim represents a request by an account to become
+// an operator of an organization that they do not yet operate.
+type OrganizationClaim struct {
+	ID             int                 `json:"id"`
+	OrganizationID int                 `json:"organizationID"`
+	AccountID      int                 `json:"accountID"`
+	Method         string              `json:"method"`
+	Status         string              `json:"status"`
+	Note           string              `json:"note"`
+	Code           string              `json:"-"`
+	Attempts       int                 `json:"-"`
+	CreatedAt      time.Time           `json:"createdAt"`
+	ResolvedAt     common.JSONNullTime `json:"resolvedAt"`
+
+	// Account is only populated when listing the claims of an organization.
+	Account *Account `json:"account,omitempty" db:"-"`
+}
+
+// Create persists a new pending claim in the database. If this is an email claim,
+// then a verification code will be generated and sent to the organization's
+// email address. Claims are rejected with ErrClaimRateLimited once the organization
+// has received too many recent claims.
+func (oc *OrganizationClaim) Create(organization Organization, client *sqlx.DB, mailClient *mail.Config) error {
+	if oc.Method != ClaimMethodEmail && oc.Method != ClaimMethodAdmin {
+		return errClaimMethodNotSupported
+	}
+	if oc.Method == ClaimMethodEmail && organization.Email == "" {
+		return errOrganizationEmailRequired
+	}
+
+	var code, hash string
+	if oc.Method == ClaimMethodEmail {
+		n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+		if err != nil {
+			return err
+		}
+		code = fmt.Sprintf("%06d", n.Int64())
+		b, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		hash = string(b)
+	}
+
+	if err := oc.create(organization.ID, hash, client); err != nil {
+		return err
+	}
+
+	if oc.Method == ClaimMethodEmail {
+		return mailClient.Send([]string{organization.Email}, fmt.Sprintf("%s Ownership Verification", organization.Name), fmt.Sprintf("Your verification code is %s. This code will expire in 24 hours.", code))
+	}
+	return nil
+}
+
+// create inserts the claim unless the organization has received claimRateLimit claims in the
+// last claimRateWindow. The organization is locked while the recent claims are counted, so
+// concurrent claims can not exceed the limit.
+func (oc *OrganizationClaim) create(organizationID int, hash string, client *sqlx.DB) (err error) {
+	tx, err := client.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	if _, err = tx.Exec("SELECT FROM Organization WHERE id = $1 FOR UPDATE;", organizationID); err != nil {
+		return err
+	}
+	var recent int
+	if err = tx.Get(&recent, "SELECT COUNT(*) FROM OrganizationClaim WHERE organizationID = $1 AND createdAt > $2;", organizationID, time.Now().Add(-claimRateWindow)); err != nil {
+		return err
+	}
+	if recent >= claimRateLimit {
+		err = ErrClaimRateLimited
+		return err
+	}
+
+	err = tx.Get(oc, `
+		INSERT INTO OrganizationClaim (organizationID, accountID, method, note, code)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *;
+	`, organizationID, oc.AccountID, oc.Method, oc.Note, hash)
+	return err
+}
+
+// Verify compares the provided code against the claim's verification code. If the code
+// matches, then the claim will be approved. Every guess uses one of the claim's attempts
+// before the code is compared, so concurrent guesses can not exceed claimCodeMaxAttempts.
+func (oc *OrganizationClaim) Verify(code string, client *sqlx.DB) error {
+	if oc.Method != ClaimMethodEmail {
+		return errClaimMethodNotSupported
+	}
+	if oc.Status != ClaimStatusPending {
+		return errClaimNotPending
+	}
+
+	if err := client.Get(oc, `
+		UPDATE OrganizationClaim SET attempts = attempts + 1
+		WHERE id = $1 AND status = $2 AND attempts < $3 AND createdAt > $4
+		RETURNING *;
+	`, oc.ID, ClaimStatusPending, claimCodeMaxAttempts, time.Now().Add(-claimCodeExpiration)); err == sql.ErrNoRows {
+		return errClaimCodeExpired
+	} else if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(oc.Code), []byte(code)); err != nil {
+		return errClaimCodeInvalid
+	}
+
+	return oc.Approve(client)
+}
+
+// Approve marks the claim as approved, connects the claiming account to the organization,
+// and marks the organization as verified.
+func (oc *OrganizationClaim) Approve(client *sqlx.DB) error {
+	tx, err := client.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = tx.Get(oc, "UPDATE OrganizationClaim SET status = $2, resolvedAt = NOW() WHERE id = $1 AND status = $3 RETURNING *;", oc.ID, ClaimStatusApproved, ClaimStatusPending)
+	if err == sql.ErrNoRows {
+		err = errClaimNotPending
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO AccountOrganization (accountID, organizationID)
+		SELECT $1, $2 WHERE NOT EXISTS(SELECT FROM AccountOrganization WHERE accountID = $1 AND organizationID = $2);
+	`, oc.AccountID, oc.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE Organization SET verified = TRUE WHERE id = $1;", oc.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Reject marks the claim as rejected.
+func (oc *OrganizationClaim) Reject(client *sqlx.DB) error {
+	err := client.Get(oc, "UPDATE OrganizationClaim SET status = $2, resolvedAt = NOW() WHERE id = $1 AND status = $3 RETURNING *;", oc.ID, ClaimStatusRejected, ClaimStatusPending)
+	if err == sql.ErrNoRows {
+		return errClaimNotPending
+	}
+	return err
+}
+
+// GetOrganizationClaimByID returns the requested claim. If the claim does not
+// belong to the provided organization, then nil will be returned.
+func GetOrganizationClaimByID(organizationID, id int, client *sqlx.DB) (*OrganizationClaim, error) {
+	oc := &OrganizationClaim{}
+	if err := client.Get(oc, "SELECT * FROM OrganizationClaim WHERE id = $1 AND organizationID = $2;", id, organizationID); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return oc, nil
+}
+
+// GetOrganizationClaimsByOrganization returns all claims for the given organization
+// with the claiming account populated.
+func GetOrganizationClaimsByOrganization(organizationID int, client *sqlx.DB) ([]OrganizationClaim, error) {
+	claims := []OrganizationClaim{}
+	if err := client.Select(&claims, "SELECT * FROM OrganizationClaim WHERE organizationID = $1 ORDER BY createdAt DESC;", organizationID); err != nil {
+		return nil, err
+	}
+	for i, claim := range claims {
+		account, err := GetAccountByID(claim.AccountID, client)
+		if err != nil {
+			return nil, err
+		}
+		claims[i].Account = account
+	}
+	return claims, nil
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/geocoder"
+)
+
+// GeocodeSourceClient is the geocode source of organizations whose coordinates were
+// provided by the client rather than looked up from their address.
+const GeocodeSourceClient = "client"
+
+// locate normalizes the organization's address and makes sure that it has coordinates. The
+// address is geocoded when the organization has no coordinates, or when its address changed
+// since previous was saved but its coordinates did not. Coordinates that were moved by the
+// client are kept as they are. Previous is nil for new organizations.
+//
+// It reports whether the organization's coordinates differ from previous. An error is returned
+// when the address could not be geocoded, in which case the coordinates are left unchanged.
+func (o *Organization) locate(g geocoder.Geocoder, previous *Organization) (bool, error) {
+	o.Address = o.Address.Normalize()
+	o.GeocodeConfidence, o.GeocodeSource = 0, ""
+
+	changed := true
+	if previous != nil {
+		o.GeocodeConfidence, o.GeocodeSource = previous.GeocodeConfidence, previous.GeocodeSource
+		if o.Lon == 0 && o.Lat == 0 {
+			o.Lon, o.Lat = previous.Lon, previous.Lat
+		}
+		changed = !o.Address.Equal(previous.Address)
+	}
+	missing := o.Lon == 0 && o.Lat == 0
+	moved := previous == nil || o.Lon != previous.Lon || o.Lat != previous.Lat
+
+	switch {
+	case missing:
+	case moved:
+		o.GeocodeConfidence, o.GeocodeSource = 1, GeocodeSourceClient
+		return true, nil
+	case !changed:
+		return false, nil
+	}
+
+	result, err := o.Address.Geocode(g)
+	if err != nil {
+		return moved, err
+	}
+	o.Lon, o.Lat = result.Lon, result.Lat
+	o.GeocodeConfidence, o.GeocodeSource = result.Confidence, result.Source
+	o.Address = o.Address.fill(Address(result.Address)).Normalize()
+	return previous == nil || o.Lon != previous.Lon || o.Lat != previous.Lat, nil
+}
+
+// review sets the organization's review flag. Organizations are flagged when they could not be
+// located, or when they are outside of the boundary of an approved community.
+func (o *Organization) review(locateErr error, communities Communities) {
+	reasons := []string{}
+	if locateErr != nil {
+		reasons = append(reasons, fmt.Sprintf("address could not be located: %s", locateErr.Error()))
+	}
+	for _, c := range communities {
+		if c.JoinStatus != nil && *c.JoinStatus != JoinStatusApproved {
+			continue
+		}
+		if ok, err := c.Contains(o.Lon, o.Lat); err == nil && !ok {
+			reasons = append(reasons, outsideReason(c))
+		}
+	}
+	o.NeedsReview, o.ReviewReason = len(reasons) > 0, strings.Join(reasons, "; ")
+}
+
+func outsideReason(c Community) string {
+	return fmt.Sprintf("outside of the %s community boundary", c.Name)
+}
+
+// ResolveReview clears the organization's review flag.
+func (o *Organization) ResolveReview(client *sqlx.DB) error {
+	return client.Get(o, "UPDATE Organization SET needsReview = FALSE, reviewReason = '' WHERE id = $1 RETURNING *;", o.ID)
+}
+
+// FlagOrganizationsOutside flags every member organization that is outside of the community's
+// boundary for review.
+func (c *Community) FlagOrganizationsOutside(client *sqlx.DB) error {
+	organizations, err := GetOrganizationsByCommunity(c.ID, client)
+	if err != nil {
+		return err
+	}
+	outside, err := c.OrganizationsOutside(organizations)
+	if err != nil {
+		return err
+	}
+	for _, o := range outside {
+		if _, err := client.Exec(`
+			UPDATE Organization
+			SET needsReview = TRUE, reviewReason = CASE WHEN reviewReason = '' THEN $2 ELSE reviewReason || '; ' || $2 END
+			WHERE id = $1 AND POSITION($2 IN reviewReason) = 0;
+		`, o.ID, outsideReason(*c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/jteppinette/peragrin-api/geocoder"
+)
+
+func TestAddressNormalize(t *testing.T) {
+	tests := []struct {
+		address  Address
+		expected Address
+	}{
+		{
+			Address{Street: " 123  north Main street. ", City: " Richmond ", State: "virginia", Country: "United States", Zip: "23220"},
+			Address{Street: "123 N Main St", City: "Richmond", State: "VA", Country: "US", Zip: "23220"},
+		},
+		{
+			Address{Street: "9 North St", State: "va", Country: "usa"},
+			Address{Street: "9 North St", State: "VA", Country: "US"},
+		},
+		{
+			Address{Street: "1 Court Avenue East", State: "Ontario", Country: "Canada", Zip: "k1a 0b1"},
+			Address{Street: "1 Court Ave E", State: "ON", Country: "CA", Zip: "K1A 0B1"},
+		},
+		{
+			Address{Street: "Unter den Linden", State: "Berlin", Country: "Germany"},
+			Address{Street: "Unter den Linden", State: "Berlin", Country: "Germany"},
+		},
+	}
+	for _, test := range tests {
+		if actual := test.address.Normalize(); actual != test.expected {
+			t.Errorf("expected %+v, got %+v", test.expected, actual)
+		}
+	}
+}
+
+func TestOrganizationLocate(t *testing.T) {
+	main := Address{Street: "123 Main St", City: "Richmond", State: "VA", Country: "US"}
+	broad := Address{Street: "1 Broad St", City: "Richmond", State: "VA", Country: "US"}
+	g := geocoder.NewFake(map[geocoder.Address]geocoder.Result{
+		geocoder.Address(main):  {Lon: -77.4, Lat: 37.5, Confidence: 0.9, Source: "fake", Address: geocoder.Address{Zip: "23220"}},
+		geocoder.Address(broad): {Lon: -77.5, Lat: 37.6, Confidence: 0.8, Source: "fake"},
+	})
+
+	o := Organization{Address: Address{Street: "123 main street", City: "Richmond", State: "Virginia", Country: "USA"}}
+	if moved, err := o.locate(g, nil); err != nil || !moved {
+		t.Fatalf("expected new organization to be geocoded, got %t, %v", moved, err)
+	}
+	if o.Lon != -77.4 || o.Lat != 37.5 || o.GeocodeConfidence != 0.9 || o.GeocodeSource != "fake" || o.Zip != "23220" {
+		t.Errorf("unexpected geocoded organization: %+v", o)
+	}
+
+	previous := o
+	o.Lon, o.Lat = 0, 0
+	if moved, err := o.locate(g, &previous); err != nil || moved {
+		t.Errorf("expected unchanged address to keep its location, got %t, %v", moved, err)
+	}
+	if o.Lon != -77.4 || o.GeocodeSource != "fake" {
+		t.Errorf("expected previous location to be kept: %+v", o)
+	}
+
+	o.Address = broad
+	if moved, err := o.locate(g, &previous); err != nil || !moved || o.Lon != -77.5 || o.GeocodeConfidence != 0.8 {
+		t.Errorf("expected changed address to be geocoded, got %t, %v, %+v", moved, err, o)
+	}
+
+	o = previous
+	o.Lon, o.Lat = -77.45, 37.55
+	if moved, err := o.locate(g, &previous); err != nil || !moved || o.GeocodeSource != GeocodeSourceClient || o.GeocodeConfidence != 1 {
+		t.Errorf("expected client coordinates to be kept, got %t, %v, %+v", moved, err, o)
+	}
+
+	o = Organization{Address: Address{Street: "unknown"}}
+	if _, err := o.locate(g, nil); err != errGeocodeNotFound {
+		t.Errorf("expected %v, got %v", errGeocodeNotFound, err)
+	}
+	o.review(errGeocodeNotFound, nil)
+	if !o.NeedsReview || o.ReviewReason == "" {
+		t.Errorf("expected unlocated organization to need review: %+v", o)
+	}
+}
@@ -0,0 +1,362 @@
+package models
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/geocoder"
+	"github.com/jteppinette/peragrin-api/geometry"
+)
+
+// OrganizationImportRow represents a single organization parsed from an import file
+// along with any problems that were found while processing it.
+type OrganizationImportRow struct {
+	Row          int          `json:"row"`
+	Organization Organization `json:"organization"`
+	Geocoded     bool         `json:"geocoded"`
+	Errors       []string     `json:"errors"`
+}
+
+func (row *OrganizationImportRow) errorf(format string, args ...interface{}) {
+	row.Errors = append(row.Errors, fmt.Sprintf(format, args...))
+}
+
+// OrganizationImport is the result of processing an organization import file.
+type OrganizationImport struct {
+	DryRun  bool                    `json:"dryRun"`
+	Valid   bool                    `json:"valid"`
+	Created int                     `json:"created"`
+	Rows    []OrganizationImportRow `json:"rows"`
+}
+
+// ParseOrganizationsCSV reads organizations from a csv file. The first record must be a
+// header naming the columns. Supported columns are name, street, city, state, country, zip,
+// lon, lat, email, phone, website, category, and hours. Hours are written as semicolon
+// separated "weekday:start-close" entries, e.g. "monday:900-1700; tuesday:900-1700".
+func ParseOrganizationsCSV(reader io.Reader) (*OrganizationImport, error) {
+	r := csv.NewReader(reader)
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errImportNameColumnRequired
+	}
+
+	result := &OrganizationImport{Rows: []OrganizationImportRow{}}
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := OrganizationImportRow{Row: line}
+		row.Organization = Organization{
+			Name:     get("name"),
+			Address:  Address{Street: get("street"), City: get("city"), State: get("state"), Country: get("country"), Zip: get("zip")},
+			Email:    get("email"),
+			Phone:    get("phone"),
+			Website:  get("website"),
+			Category: get("category"),
+		}
+
+		if lon, lat := get("lon"), get("lat"); lon != "" || lat != "" {
+			if row.Organization.Lon, err = strconv.ParseFloat(lon, 64); err != nil {
+				row.errorf("invalid lon: %q", lon)
+			}
+			if row.Organization.Lat, err = strconv.ParseFloat(lat, 64); err != nil {
+				row.errorf("invalid lat: %q", lat)
+			}
+		}
+
+		if row.Organization.Hours, err = parseHours(get("hours")); err != nil {
+			row.errorf("invalid hours: %s", err.Error())
+		}
+
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// ParseOrganizationsGeoJSON reads organizations from a GeoJSON FeatureCollection. Each
+// feature's properties use the same names as the organization's JSON representation,
+// and its geometry, if present, must be a Point.
+func ParseOrganizationsGeoJSON(reader io.Reader) (*OrganizationImport, error) {
+	collection := struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Type     string `json:"type"`
+			Geometry *struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+			Properties json.RawMessage `json:"properties"`
+		} `json:"features"`
+	}{}
+	if err := json.NewDecoder(reader).Decode(&collection); err != nil {
+		return nil, err
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, errImportFeatureCollectionRequired
+	}
+
+	result := &OrganizationImport{Rows: []OrganizationImportRow{}}
+	for i, feature := range collection.Features {
+		row := OrganizationImportRow{Row: i + 1}
+
+		if feature.Type != "Feature" {
+			row.errorf("invalid feature type: %q", feature.Type)
+		}
+		if len(feature.Properties) != 0 {
+			if err := json.Unmarshal(feature.Properties, &row.Organization); err != nil {
+				row.errorf("invalid properties: %s", err.Error())
+			}
+		}
+		if feature.Geometry != nil {
+			coordinates := []float64{}
+			if feature.Geometry.Type != "Point" || json.Unmarshal(feature.Geometry.Coordinates, &coordinates) != nil || len(coordinates) < 2 {
+				row.errorf("geometry must be a Point")
+			} else {
+				row.Organization.Lon = coordinates[0]
+				row.Organization.Lat = coordinates[1]
+			}
+		}
+
+		// Identifiers and server managed fields are never imported.
+		row.Organization.ID = 0
+		row.Organization.Logo = ""
+		row.Organization.Verified = false
+		row.Organization.IsAdministrator = nil
+
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// parseHours parses semicolon separated "weekday:start-close" entries. The weekday may be
+// provided as a name, such as monday or mon, or as a number where sunday is 0.
+func parseHours(value string) (Hours, error) {
+	hours := Hours{}
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expected weekday:start-close, got %q", entry)
+		}
+		weekday, err := parseWeekday(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		times := strings.SplitN(parts[1], "-", 2)
+		if len(times) != 2 {
+			return nil, fmt.Errorf("expected start-close, got %q", parts[1])
+		}
+		start, err := strconv.Atoi(strings.TrimSpace(times[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid start: %q", times[0])
+		}
+		end, err := strconv.Atoi(strings.TrimSpace(times[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid close: %q", times[1])
+		}
+		hours = append(hours, Hour{weekday, start, end})
+	}
+	return hours, nil
+}
+
+func parseWeekday(value string) (time.Weekday, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 0 || n > 6 {
+			return 0, fmt.Errorf("invalid weekday: %q", value)
+		}
+		return time.Weekday(n), nil
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if value == name || value == name[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday: %q", value)
+}
+
+// Validate checks every row for problems, including organizations that are outside
+// of the provided community's boundary, and sets the Valid field. Rows without coordinates
+// are located and checked against the boundary by Geocode, so Validate should be called first.
+func (oi *OrganizationImport) Validate(community Community) {
+	boundary := importBoundary(community)
+	for i := range oi.Rows {
+		row := &oi.Rows[i]
+		o := &row.Organization
+
+		if o.Name == "" {
+			row.errorf("name required")
+		}
+		if o.Category != "" {
+			if category, ok := NormalizeCategory(o.Category); ok {
+				o.Category = category
+			} else {
+				row.errorf("invalid category: %q", o.Category)
+			}
+		}
+		if o.Email != "" {
+			if _, err := mail.ParseAddress(o.Email); err != nil {
+				row.errorf("invalid email: %q", o.Email)
+			}
+		}
+		if !row.missingLocation() {
+			row.validateLocation(boundary)
+		}
+		for _, hour := range o.Hours {
+			if hour.Weekday < time.Sunday || hour.Weekday > time.Saturday {
+				row.errorf("invalid weekday: %d", hour.Weekday)
+			}
+			if hour.Start < 0 || hour.Close < 0 {
+				row.errorf("invalid hours: %d-%d", hour.Start, hour.Close)
+			}
+		}
+	}
+	oi.setValid()
+}
+
+// PendingGeocodes returns the number of valid rows that were not provided a location, which
+// is the number of lookups that Geocode will make.
+func (oi *OrganizationImport) PendingGeocodes() int {
+	n := 0
+	for _, row := range oi.Rows {
+		if len(row.Errors) == 0 && row.missingLocation() {
+			n++
+		}
+	}
+	return n
+}
+
+// Geocode normalizes the address of every valid row, looks up the coordinates of every
+// valid row that was not provided a location, and checks those coordinates against the
+// provided community's boundary. Rows that Validate rejected are skipped so they do not
+// spend the geocoder's rate limit.
+func (oi *OrganizationImport) Geocode(g geocoder.Geocoder, community Community) {
+	boundary := importBoundary(community)
+	for i := range oi.Rows {
+		row := &oi.Rows[i]
+		if len(row.Errors) > 0 {
+			continue
+		}
+		missing := row.missingLocation()
+		if _, err := row.Organization.locate(g, nil); err != nil {
+			row.errorf("geocode: %s", err.Error())
+			continue
+		}
+		row.Geocoded = row.Organization.GeocodeSource != GeocodeSourceClient
+		if missing {
+			row.validateLocation(boundary)
+		}
+	}
+	oi.setValid()
+}
+
+// importBoundary returns the community's boundary, or nil when it has none or it cannot be parsed.
+func importBoundary(community Community) geometry.MultiPolygon {
+	boundary, err := community.ParseBoundary()
+	if err != nil {
+		return nil
+	}
+	return boundary
+}
+
+func (oi *OrganizationImport) setValid() {
+	oi.Valid = true
+	for i := range oi.Rows {
+		row := &oi.Rows[i]
+		if row.Errors == nil {
+			row.Errors = []string{}
+		}
+		if len(row.Errors) > 0 {
+			oi.Valid = false
+		}
+	}
+}
+
+func (row *OrganizationImportRow) missingLocation() bool {
+	return row.Organization.Lon == 0 && row.Organization.Lat == 0
+}
+
+func (row *OrganizationImportRow) validateLocation(boundary geometry.MultiPolygon) {
+	o := &row.Organization
+	if o.Lon < -180 || o.Lon > 180 || o.Lat < -90 || o.Lat > 90 {
+		row.errorf("coordinates out of range: %f, %f", o.Lon, o.Lat)
+	} else if boundary != nil && !boundary.Contains(geometry.Point{o.Lon, o.Lat}) {
+		row.errorf("coordinates outside community boundary: %f, %f", o.Lon, o.Lat)
+	}
+}
+
+// CreateWithCommunity persists every imported organization with its hours and joins
+// it to the provided community in a single transaction. Nothing is persisted if the
+// import is not valid or is a dry run.
+func (oi *OrganizationImport) CreateWithCommunity(communityID int, client *sqlx.DB) error {
+	if !oi.Valid {
+		return errImportInvalid
+	}
+	if oi.DryRun {
+		return nil
+	}
+
+	tx, err := client.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	for i := range oi.Rows {
+		o := &oi.Rows[i].Organization
+		err = o.txCreate(tx)
+		if err != nil {
+			return err
+		}
+		err = o.Hours.txSet(o.ID, tx)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO CommunityOrganization (organizationID, communityID, isAdministrator) VALUES ($1, $2, FALSE);", o.ID, communityID)
+		if err != nil {
+			return err
+		}
+	}
+
+	oi.Created = len(oi.Rows)
+	return nil
+}
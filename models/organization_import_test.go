@@ -0,0 +1,102 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jteppinette/peragrin-api/geocoder"
+)
+
+func TestParseOrganizationsCSV(t *testing.T) {
+	result, err := ParseOrganizationsCSV(strings.NewReader(`Name,Street,City,Lon,Lat,Category,Email,Hours
+Cafe,1 Main St,Richmond,-77.43,37.54,restaurant,cafe@example.com,mon:900-1700; 2:900-1700
+,2 Main St,Richmond,-77.43,37.54,Bakery,not-an-email,
+Theater,3 Main St,Richmond,-200,37.54,Entertainment,,someday:1-2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.Validate(Community{})
+
+	if result.Valid {
+		t.Error("expected import to be invalid")
+	}
+	if len(result.Rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(result.Rows))
+	}
+
+	cafe := result.Rows[0]
+	if len(cafe.Errors) != 0 {
+		t.Errorf("expected row 2 to be valid, got %v", cafe.Errors)
+	}
+	if cafe.Organization.Category != Restaurant {
+		t.Errorf("expected category to be normalized to %s, got %s", Restaurant, cafe.Organization.Category)
+	}
+	if len(cafe.Organization.Hours) != 2 || cafe.Organization.Hours[0].Weekday != time.Monday || cafe.Organization.Hours[1].Weekday != time.Tuesday {
+		t.Errorf("expected monday and tuesday hours, got %v", cafe.Organization.Hours)
+	}
+
+	for i, expected := range []int{3, 2} {
+		row := result.Rows[i+1]
+		if len(row.Errors) != expected {
+			t.Errorf("expected row %d to have %d errors, got %v", row.Row, expected, row.Errors)
+		}
+	}
+}
+
+func TestParseOrganizationsGeoJSON(t *testing.T) {
+	result, err := ParseOrganizationsGeoJSON(strings.NewReader(`{
+		"type": "FeatureCollection",
+		"features": [
+			{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-77.43, 37.54]}, "properties": {"id": 10, "name": "Cafe", "hours": [{"weekday": 1, "start": 900, "close": 1700}]}},
+			{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}, "properties": {"name": "Line"}}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.Validate(Community{})
+
+	cafe := result.Rows[0].Organization
+	if cafe.ID != 0 || cafe.Name != "Cafe" || cafe.Lon != -77.43 || cafe.Lat != 37.54 || len(cafe.Hours) != 1 {
+		t.Errorf("unexpected organization: %+v", cafe)
+	}
+	if result.Valid || len(result.Rows[1].Errors) == 0 {
+		t.Error("expected non point geometry to be rejected")
+	}
+}
+
+func TestOrganizationImportGeocode(t *testing.T) {
+	main := Address{Street: "123 Main St", City: "Richmond", State: "VA", Country: "US"}
+	g := geocoder.NewFake(map[geocoder.Address]geocoder.Result{
+		geocoder.Address(main): {Lon: -77.4, Lat: 37.5, Confidence: 0.9, Source: "fake"},
+	})
+
+	result, err := ParseOrganizationsCSV(strings.NewReader(`Name,Street,City,State,Country,Lon,Lat
+Cafe,123 Main St,Richmond,VA,US,,
+,1 Broad St,Richmond,VA,US,,
+Theater,3 Main St,Richmond,VA,US,-77.43,37.54
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.Validate(Community{})
+	if n := result.PendingGeocodes(); n != 1 {
+		t.Errorf("expected 1 pending geocode, got %d", n)
+	}
+
+	result.Geocode(g, Community{})
+	if cafe := result.Rows[0]; !cafe.Geocoded || cafe.Organization.Lon != -77.4 || len(cafe.Errors) != 0 {
+		t.Errorf("expected row 2 to be geocoded, got %+v", cafe)
+	}
+	if invalid := result.Rows[1]; invalid.Geocoded || len(invalid.Errors) != 1 {
+		t.Errorf("expected invalid row 3 to be skipped, got %+v", invalid)
+	}
+	if theater := result.Rows[2]; theater.Geocoded || len(theater.Errors) != 0 {
+		t.Errorf("expected row 4 to keep its location, got %+v", theater)
+	}
+	if result.Valid {
+		t.Error("expected import to be invalid")
+	}
+}
@@ -0,0 +1,325 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/common"
+	"github.com/jteppinette/peragrin-api/payment"
+)
+
+const (
+	// CheckoutStatusPending checkout sessions are waiting for the member to pay.
+	CheckoutStatusPending = "pending"
+	// CheckoutStatusCompleted checkout sessions have been paid and their account joined the membership.
+	CheckoutStatusCompleted = "completed"
+	// CheckoutStatusExpired checkout sessions were abandoned before they were paid.
+	CheckoutStatusExpired = "expired"
+	// CheckoutStatusFailed checkout sessions could not be created by the payment gateway.
+	CheckoutStatusFailed = "failed"
+)
+
+const (
+	// PaymentKindPayment ledger entries are money received from a member.
+	PaymentKindPayment = "payment"
+	// PaymentKindRefund ledger entries are money returned to a member. Their amount is negative.
+	PaymentKindRefund = "refund"
+)
+
+// CheckoutSession is a purchase of a membership that is paid through the payment gateway.
+// Amounts are in the smallest unit of the currency, e.g. cents.
+type CheckoutSession struct {
+	ID               int                 `json:"id"`
+	CommunityID      int                 `json:"communityID"`
+	MembershipID     *int                `json:"membershipID"`
+	AccountID        *int                `json:"accountID"`
+	Email            string              `json:"email"`
+	FirstName        string              `json:"firstName"`
+	LastName         string              `json:"lastName"`
+	Amount           int64               `json:"amount"`
+	Currency         string              `json:"currency"`
+	Status           string              `json:"status"`
+	GatewaySessionID *string             `json:"gatewaySessionID"`
+	URL              string              `json:"url"`
+	CreatedAt        time.Time           `json:"createdAt"`
+	CompletedAt      common.JSONNullTime `json:"completedAt"`
+}
+
+// Payment is an entry in a community's payments ledger. Refunds reference the payment
+// that they refunded, and Refunded is the total that has been refunded of a payment.
+type Payment struct {
+	ID                int       `json:"id"`
+	CommunityID       int       `json:"communityID"`
+	MembershipID      *int      `json:"membershipID"`
+	AccountID         *int      `json:"accountID"`
+	CheckoutSessionID *int      `json:"checkoutSessionID"`
+	RefundedPaymentID *int      `json:"refundedPaymentID"`
+	Kind              string    `json:"kind"`
+	Email             string    `json:"email"`
+	Amount            int64     `json:"amount"`
+	Currency          string    `json:"currency"`
+	GatewayReference  string    `json:"gatewayReference"`
+	CreatedAt         time.Time `json:"createdAt"`
+
+	Refunded int64 `json:"refunded"`
+}
+
+// refundedAmount is a sql expression for the total that has been refunded of a payment.
+const refundedAmount = "COALESCE((SELECT -SUM(Refund.amount) FROM Payment AS Refund WHERE Refund.refundedPaymentID = Payment.id), 0)"
+
+// Amount returns the membership's price in the smallest unit of its currency.
+func (m *Membership) Amount() int64 {
+	return int64(math.Floor(m.Price*100 + 0.5))
+}
+
+// Checkout starts a purchase of the membership by the session's email. The session is
+// saved before the payment gateway is asked for a checkout page, so that the gateway's
+// events can always be matched to it.
+func (m *Membership) Checkout(s *CheckoutSession, currency, successURL, cancelURL string, g payment.Gateway, client *sqlx.DB) error {
+	if m.Amount() <= 0 {
+		return errMembershipFree
+	}
+	if _, err := m.ExpirationAt(time.Now(), common.JSONNullTime{}); err != nil {
+		return err
+	}
+	s.Email = strings.ToLower(strings.TrimSpace(s.Email))
+	if _, err := mail.ParseAddress(s.Email); err != nil {
+		return errCheckoutEmailInvalid
+	}
+
+	if err := client.Get(s, `
+		INSERT INTO CheckoutSession (communityID, membershipID, email, firstName, lastName, amount, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *;
+	`, m.CommunityID, m.ID, s.Email, s.FirstName, s.LastName, m.Amount(), currency); err != nil {
+		return err
+	}
+
+	session, err := g.CreateCheckoutSession(payment.CheckoutParams{
+		Reference:   strconv.Itoa(s.ID),
+		Amount:      s.Amount,
+		Currency:    s.Currency,
+		Description: m.Name,
+		Email:       s.Email,
+		SuccessURL:  successURL,
+		CancelURL:   cancelURL,
+	})
+	if err != nil {
+		client.Exec("UPDATE CheckoutSession SET status = $2 WHERE id = $1;", s.ID, CheckoutStatusFailed)
+		return err
+	}
+	return client.Get(s, "UPDATE CheckoutSession SET gatewaySessionID = $2, url = $3 WHERE id = $1 RETURNING *;", s.ID, session.ID, session.URL)
+}
+
+// Complete handles a completed checkout event. The session's email joins the membership,
+// or renews it when the account already has it, and the payment is added to the ledger.
+// Accounts are created for emails that do not have one, in which case created is true.
+// Events for sessions that are unknown, e.g. ones created by another application with the
+// same gateway account, or that were already completed are ignored and return a nil account.
+// Events whose amount or currency differ from the session's are rejected.
+func (s *CheckoutSession) Complete(event payment.Event, client *sqlx.DB) (a *Account, created bool, err error) {
+	tx, err := client.Beginx()
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	if err = tx.Get(s, "SELECT * FROM CheckoutSession WHERE gatewaySessionID = $1 FOR UPDATE;", event.SessionID); err == sql.ErrNoRows {
+		err = nil
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	if s.Status == CheckoutStatusCompleted {
+		return nil, false, nil
+	}
+	// The session's price is only granted when the member paid it.
+	if event.Amount != s.Amount || !strings.EqualFold(event.Currency, s.Currency) {
+		err = errCheckoutAmountMismatch
+		return nil, false, err
+	}
+
+	a = &Account{}
+	err = tx.Get(a, "SELECT id, email, firstName, lastName, isSuper FROM Account WHERE LOWER(email) = $1;", s.Email)
+	if err == sql.ErrNoRows {
+		created = true
+		err = tx.Get(a, "INSERT INTO Account (email, firstName, lastName) VALUES ($1, $2, $3) RETURNING id, email, firstName, lastName, isSuper;", s.Email, s.FirstName, s.LastName)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The membership may have been deleted while the member was paying, in which case the
+	// payment is still recorded so that it can be refunded.
+	if s.MembershipID != nil {
+		if err = a.txRenewMembership(*s.MembershipID, true, time.Now(), tx); err != nil {
+			return nil, false, err
+		}
+	}
+
+	reference := event.PaymentID
+	if reference == "" {
+		reference = event.SessionID
+	}
+	if _, err = tx.Exec(`
+		INSERT INTO Payment (communityID, membershipID, accountID, checkoutSessionID, kind, email, amount, currency, gatewayReference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (gatewayReference) DO NOTHING;
+	`, s.CommunityID, s.MembershipID, a.ID, s.ID, PaymentKindPayment, s.Email, event.Amount, s.Currency, reference); err != nil {
+		return nil, false, err
+	}
+
+	err = tx.Get(s, "UPDATE CheckoutSession SET status = $2, accountID = $3, completedAt = NOW() WHERE id = $1 RETURNING *;", s.ID, CheckoutStatusCompleted, a.ID)
+	return a, created, err
+}
+
+// ExpireCheckout marks the pending checkout session of an expired checkout event as expired.
+func ExpireCheckout(event payment.Event, client *sqlx.DB) error {
+	_, err := client.Exec("UPDATE CheckoutSession SET status = $3 WHERE gatewaySessionID = $1 AND status = $2;", event.SessionID, CheckoutStatusPending, CheckoutStatusExpired)
+	return err
+}
+
+// RecordRefunds adds the refunds of a refund event to the ledger. Refunds that were made
+// through this api are already in the ledger, and payments that are not in the ledger
+// are ignored.
+func RecordRefunds(event payment.Event, client *sqlx.DB) error {
+	tx, err := client.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	p := Payment{}
+	if err = tx.Get(&p, "SELECT * FROM Payment WHERE gatewayReference = $1 AND kind = $2 FOR UPDATE;", event.PaymentID, PaymentKindPayment); err == sql.ErrNoRows {
+		err = nil
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, refund := range event.Refunds {
+		if err = p.txRecordRefund(refund, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Refund returns amount of the payment to the member through the payment gateway, and adds
+// the refund to the ledger. A zero amount refunds everything that has not been refunded yet.
+// When revoke is true, the member's account membership is removed. The payment is not locked
+// while the gateway is called. Refunds that start from the same refunded total share an
+// idempotency key instead, so concurrent refunds are only made once by the gateway.
+func (p *Payment) Refund(amount int64, revoke bool, g payment.Gateway, client *sqlx.DB) (*Payment, error) {
+	if err := client.Get(p, "SELECT *, "+refundedAmount+" AS refunded FROM Payment WHERE id = $1 AND kind = $2;", p.ID, PaymentKindPayment); err == sql.ErrNoRows {
+		return nil, errPaymentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	remaining := p.Amount - p.Refunded
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		return nil, errRefundAmountInvalid
+	}
+
+	// The key is the same when a failed refund is retried, so the member is only refunded once.
+	r, err := g.Refund(p.GatewayReference, amount, fmt.Sprintf("payment-%d-refund-%d", p.ID, p.Refunded))
+	if err != nil {
+		return nil, err
+	}
+	return p.recordRefund(r, revoke, client)
+}
+
+// recordRefund adds a refund that the gateway made to the ledger, and when revoke is true,
+// removes the member's account membership.
+func (p *Payment) recordRefund(r payment.Refund, revoke bool, client *sqlx.DB) (refund *Payment, err error) {
+	tx, err := client.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	if err = tx.Get(p, "SELECT * FROM Payment WHERE id = $1 FOR UPDATE;", p.ID); err != nil {
+		return nil, err
+	}
+	if err = p.txRecordRefund(r, tx); err != nil {
+		return nil, err
+	}
+	if err = tx.Get(&p.Refunded, "SELECT "+refundedAmount+" FROM Payment WHERE id = $1;", p.ID); err != nil {
+		return nil, err
+	}
+
+	if revoke && p.AccountID != nil && p.MembershipID != nil {
+		if _, err = tx.Exec("DELETE FROM AccountMembership WHERE accountID = $1 AND membershipID = $2;", *p.AccountID, *p.MembershipID); err != nil {
+			return nil, err
+		}
+		if err = txRemoveDependents(*p.AccountID, *p.MembershipID, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	refund = &Payment{}
+	err = tx.Get(refund, "SELECT * FROM Payment WHERE gatewayReference = $1;", r.ID)
+	return refund, err
+}
+
+// txRecordRefund adds a refund of the payment to the ledger unless it is already there.
+func (p *Payment) txRecordRefund(r payment.Refund, tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		INSERT INTO Payment (communityID, membershipID, accountID, refundedPaymentID, kind, email, amount, currency, gatewayReference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (gatewayReference) DO NOTHING;
+	`, p.CommunityID, p.MembershipID, p.AccountID, p.ID, PaymentKindRefund, p.Email, -r.Amount, p.Currency, r.ID)
+	return err
+}
+
+// GetPaymentByID returns the requested payment, or nil if it does not exist.
+func GetPaymentByID(id int, client *sqlx.DB) (*Payment, error) {
+	p := &Payment{}
+	if err := client.Get(p, "SELECT *, "+refundedAmount+" AS refunded FROM Payment WHERE id = $1;", id); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetPaymentsByCommunity returns a community's payments ledger with the newest entries first.
+func GetPaymentsByCommunity(communityID int, client *sqlx.DB) ([]Payment, error) {
+	payments := []Payment{}
+	if err := client.Select(&payments, "SELECT *, "+refundedAmount+" AS refunded FROM Payment WHERE communityID = $1 ORDER BY createdAt DESC, id DESC;", communityID); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
@@ -29,7 +29,7 @@ func (p *Post) Save(client *sqlx.DB) error {
 // member of the provided community.
 func GetPostsByCommunity(communityID int, client *sqlx.DB) (Posts, error) {
 	posts := Posts{}
-	if err := client.Select(&posts, "SELECT Post.* FROM Post INNER JOIN CommunityOrganization ON (Post.organizationID = CommunityOrganization.organizationID) WHERE communityID = $1 ORDER BY createdAt DESC;", communityID); err != nil {
+	if err := client.Select(&posts, "SELECT Post.* FROM Post INNER JOIN CommunityOrganization ON (Post.organizationID = CommunityOrganization.organizationID) WHERE communityID = $1 AND CommunityOrganization.status = 'approved' ORDER BY createdAt DESC;", communityID); err != nil {
 		return nil, err
 	}
 	return posts, nil
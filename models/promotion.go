@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/jteppinette/peragrin-api/common"
 	"github.com/lib/pq"
@@ -20,12 +22,42 @@ type Promotion struct {
 	IsSingleUse    bool                `json:"isSingleUse"`
 	Communities    pq.Int64Array       `json:"communities"`
 
+	// AccountLimit is how many times an account can redeem this promotion per
+	// AccountLimitPeriod, or ever when the period is empty. TotalLimit and DailyLimit cap the
+	// redemptions of every account. Limits of zero are unlimited, and single use promotions
+	// can be redeemed once by each account.
+	AccountLimit       int    `json:"accountLimit"`
+	AccountLimitPeriod string `json:"accountLimitPeriod"`
+	TotalLimit         int    `json:"totalLimit"`
+	DailyLimit         int    `json:"dailyLimit"`
+
+	// StartsAt and Expiration bound when this promotion can be redeemed. Expirations at
+	// midnight UTC are dates, and last until the end of that day. Promotions with
+	// Windows can only be redeemed during them, and never on their BlackoutDates, which are
+	// formatted as 2006-01-02. Windows and dates are in the organization's time zone.
+	StartsAt      common.JSONNullTime `json:"startsAt"`
+	Windows       Hours               `json:"windows"`
+	BlackoutDates pq.StringArray      `json:"blackoutDates"`
+
 	// Redemptions is the number of times this promotion has been redeemed.
 	Redemptions int `json:"redemptions,omitempty"`
+
+	// Remaining is how many more times this promotion can be redeemed today, and
+	// AccountRemaining is how many more times the requesting account can redeem it in the
+	// current period. They are only set in listings of limited promotions.
+	Remaining        *int `json:"remaining,omitempty"`
+	AccountRemaining *int `json:"accountRemaining,omitempty"`
+
+	// Available is set in listings to whether this promotion can be redeemed now.
+	Available *bool `json:"available,omitempty"`
 }
 
 // Save creates or updates a promotion in the database based on the existence of an id.
 func (p *Promotion) Save(client *sqlx.DB) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
 	tx, err := client.Beginx()
 	if err != nil {
 		return err
@@ -40,7 +72,11 @@ func (p *Promotion) Save(client *sqlx.DB) error {
 	}()
 
 	if p.ID != 0 {
-		err = tx.Get(p, "UPDATE Promotion SET name = $2, description = $3, exclusions = $4, expiration = $5, isSingleUse = $6 WHERE id = $1 RETURNING *;", p.ID, p.Name, p.Description, p.Exclusions, p.Expiration, p.IsSingleUse)
+		err = tx.Get(p, `
+			UPDATE Promotion SET name = $2, description = $3, exclusions = $4, expiration = $5, isSingleUse = $6,
+			accountLimit = $7, accountLimitPeriod = $8, totalLimit = $9, dailyLimit = $10, startsAt = $11, blackoutDates = $12
+			WHERE id = $1 RETURNING *;
+		`, p.ID, p.Name, p.Description, p.Exclusions, p.Expiration, p.IsSingleUse, p.AccountLimit, p.AccountLimitPeriod, p.TotalLimit, p.DailyLimit, p.StartsAt, p.BlackoutDates)
 		if err != nil {
 			return err
 		}
@@ -49,12 +85,19 @@ func (p *Promotion) Save(client *sqlx.DB) error {
 			return err
 		}
 	} else {
-		err = tx.Get(p, "INSERT INTO Promotion (organizationID, name, description, exclusions, expiration, isSingleUse) VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;", p.OrganizationID, p.Name, p.Description, p.Exclusions, p.Expiration, p.IsSingleUse)
+		err = tx.Get(p, `
+			INSERT INTO Promotion (organizationID, name, description, exclusions, expiration, isSingleUse, accountLimit, accountLimitPeriod, totalLimit, dailyLimit, startsAt, blackoutDates)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING *;
+		`, p.OrganizationID, p.Name, p.Description, p.Exclusions, p.Expiration, p.IsSingleUse, p.AccountLimit, p.AccountLimitPeriod, p.TotalLimit, p.DailyLimit, p.StartsAt, p.BlackoutDates)
 		if err != nil {
 			return err
 		}
 	}
 
+	if err = p.Windows.txReplace("PromotionWindow", "promotionID", p.ID, tx); err != nil {
+		return err
+	}
+
 	if len(p.Communities) == 0 {
 		return nil
 	}
@@ -105,18 +148,66 @@ func DeletePromotion(id int, client *sqlx.DB) error {
 	return nil
 }
 
-// GetPromotionsByOrganization returns all promotions for a given organization.
-func GetPromotionsByOrganization(organizationID int, client *sqlx.DB) (Promotions, error) {
-	promotions := Promotions{}
-	if err := client.Select(&promotions, `
-		SELECT Promotion.*, COUNT(AccountPromotion) AS redemptions, (
+// GetPromotionsByOrganization returns all promotions for a given organization along with their
+// windows, how many more times they can be redeemed and whether they are available at now.
+// Remaining redemptions of the account are included unless accountID is zero.
+func GetPromotionsByOrganization(organizationID, accountID int, now time.Time, client *sqlx.DB) (Promotions, error) {
+	loc, err := organizationLocation(organizationID, client)
+	if err != nil {
+		return nil, err
+	}
+	now = now.In(loc)
+
+	rows := []struct {
+		Promotion
+		RedemptionsToday int
+	}{}
+	if err := client.Select(&rows, `
+		SELECT Promotion.*, COUNT(AccountPromotion) AS redemptions,
+		COUNT(AccountPromotion) FILTER (WHERE AccountPromotion.consumedAt >= $2) AS redemptionsToday, (
 			SELECT ARRAY(SELECT communityID FROM CommunityPromotion WHERE promotionID = Promotion.id)
 		) as communities
 		FROM Promotion LEFT OUTER JOIN AccountPromotion ON (Promotion.id = AccountPromotion.promotionID)
 		WHERE Promotion.organizationID = $1 GROUP BY Promotion.id;
-	`, organizationID); err != nil {
+	`, organizationID, periodStart(PromotionPeriodDay, now)); err != nil {
 		return nil, err
 	}
+
+	redemptions := []AccountPromotion{}
+	if accountID != 0 {
+		if err := client.Select(&redemptions, `
+			SELECT AccountPromotion.* FROM AccountPromotion
+			INNER JOIN Promotion ON (AccountPromotion.promotionID = Promotion.id)
+			WHERE Promotion.organizationID = $1 AND AccountPromotion.accountID = $2;
+		`, organizationID, accountID); err != nil {
+			return nil, err
+		}
+	}
+
+	windows, err := getPromotionWindowsByOrganization(organizationID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	promotions := Promotions{}
+	for _, row := range rows {
+		p := row.Promotion
+		if p.Windows = windows[p.ID]; p.Windows == nil {
+			p.Windows = Hours{}
+		}
+		u := promotionUsage{Total: p.Redemptions, Today: row.RedemptionsToday}
+		_, period := p.accountLimit()
+		start := periodStart(period, now)
+		for _, redemption := range redemptions {
+			if redemption.PromotionID == p.ID && !redemption.ConsumedAt.Before(start) {
+				u.Account++
+			}
+		}
+		p.setRemaining(u, accountID != 0)
+		available := p.availableAt(now) == nil && (p.Remaining == nil || *p.Remaining > 0) && (p.AccountRemaining == nil || *p.AccountRemaining > 0)
+		p.Available = &available
+		promotions = append(promotions, p)
+	}
 	return promotions, nil
 }
 
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// PromotionPeriodDay limits the redemptions of an account per calendar day.
+	PromotionPeriodDay = "day"
+	// PromotionPeriodWeek limits the redemptions of an account per week, starting on Monday.
+	PromotionPeriodWeek = "week"
+	// PromotionPeriodMonth limits the redemptions of an account per calendar month.
+	PromotionPeriodMonth = "month"
+	// PromotionPeriodYear limits the redemptions of an account per calendar year.
+	PromotionPeriodYear = "year"
+)
+
+// promotionUsage is how many times a promotion has been redeemed in total, today and by an
+// account in the current period of the promotion's account limit.
+type promotionUsage struct {
+	Total   int
+	Today   int
+	Account int
+}
+
+// Validate checks the promotion's redemption limits and schedule.
+func (p *Promotion) Validate() error {
+	if p.AccountLimit < 0 || p.TotalLimit < 0 || p.DailyLimit < 0 {
+		return errPromotionLimitInvalid
+	}
+	switch p.AccountLimitPeriod {
+	case "", PromotionPeriodDay, PromotionPeriodWeek, PromotionPeriodMonth, PromotionPeriodYear:
+	default:
+		return errPromotionPeriodInvalid
+	}
+	return p.validateSchedule()
+}
+
+// accountLimit returns how many times an account can redeem the promotion per period.
+// Single use promotions can be redeemed once ever.
+func (p *Promotion) accountLimit() (int, string) {
+	if p.IsSingleUse {
+		return 1, ""
+	}
+	return p.AccountLimit, p.AccountLimitPeriod
+}
+
+// periodStart returns the start of the period that contains now in now's location, or the
+// zero time for periods that never end.
+func periodStart(period string, now time.Time) time.Time {
+	year, month, day := now.Date()
+	switch period {
+	case PromotionPeriodDay:
+		return time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	case PromotionPeriodWeek:
+		return time.Date(year, month, day-(int(now.Weekday())+6)%7, 0, 0, 0, 0, now.Location())
+	case PromotionPeriodMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+	case PromotionPeriodYear:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Time{}
+}
+
+// checkLimits returns the error of the first limit that another redemption of the promotion
+// would exceed.
+func (p *Promotion) checkLimits(u promotionUsage) error {
+	if limit, _ := p.accountLimit(); limit > 0 && u.Account >= limit {
+		if p.IsSingleUse {
+			return ErrPromotionRedeemed
+		}
+		return ErrPromotionAccountLimit
+	}
+	if p.TotalLimit > 0 && u.Total >= p.TotalLimit {
+		return ErrPromotionSoldOut
+	}
+	if p.DailyLimit > 0 && u.Today >= p.DailyLimit {
+		return ErrPromotionDailyLimit
+	}
+	return nil
+}
+
+// setRemaining sets how many more times the promotion can be redeemed today, and when
+// account is true, how many more times the account can redeem it.
+func (p *Promotion) setRemaining(u promotionUsage, account bool) {
+	p.Remaining, p.AccountRemaining = nil, nil
+	if p.TotalLimit > 0 || p.DailyLimit > 0 {
+		remaining := -1
+		if p.TotalLimit > 0 {
+			remaining = p.TotalLimit - u.Total
+		}
+		if p.DailyLimit > 0 && (remaining < 0 || p.DailyLimit-u.Today < remaining) {
+			remaining = p.DailyLimit - u.Today
+		}
+		if remaining < 0 {
+			remaining = 0
+		}
+		p.Remaining = &remaining
+	}
+	if limit, _ := p.accountLimit(); account && limit > 0 {
+		remaining := limit - u.Account
+		if remaining < 0 {
+			remaining = 0
+		}
+		p.AccountRemaining = &remaining
+	}
+}
+
+// Redeem records that the account redeemed the promotion at now unless the promotion is not
+// available at now or the redemption would exceed one of its limits. The promotion is locked
+// until the redemption is recorded, so concurrent redemptions can not exceed its limits.
+// Days, periods and windows are in the time zone of the organization's first community.
+func (ap *AccountPromotion) Redeem(now time.Time, client *sqlx.DB) (err error) {
+	tx, err := client.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	p := Promotion{}
+	if err = tx.Get(&p, "SELECT * FROM Promotion WHERE id = $1 FOR UPDATE;", ap.PromotionID); err == sql.ErrNoRows {
+		err = errPromotionNotFound
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	loc, err := organizationLocation(p.OrganizationID, tx)
+	if err != nil {
+		return err
+	}
+	now = now.In(loc)
+
+	if p.Windows, err = getPromotionWindows(p.ID, tx); err != nil {
+		return err
+	}
+	if err = p.availableAt(now); err != nil {
+		return err
+	}
+
+	_, period := p.accountLimit()
+
+	u := promotionUsage{}
+	if err = tx.Get(&u, `
+		SELECT
+			COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE consumedAt >= $2) AS today,
+			COUNT(*) FILTER (WHERE accountID = $3 AND consumedAt >= $4) AS account
+		FROM AccountPromotion WHERE promotionID = $1;
+	`, p.ID, periodStart(PromotionPeriodDay, now), ap.AccountID, periodStart(period, now)); err != nil {
+		return err
+	}
+	if err = p.checkLimits(u); err != nil {
+		return err
+	}
+
+	err = tx.Get(ap, `
+		INSERT INTO AccountPromotion (accountID, promotionID)
+		VALUES ($1, $2)
+		RETURNING *;
+	`, ap.AccountID, ap.PromotionID)
+	return err
+}
+
+// organizationLocation returns the time zone of the organization's first community, or UTC
+// if the organization is not a member of a community. Pending and rejected join requests
+// do not make an organization a member.
+func organizationLocation(organizationID int, q sqlx.Queryer) (*time.Location, error) {
+	c := Community{}
+	if err := sqlx.Get(q, &c, `
+		SELECT Community.timezone FROM Community
+		INNER JOIN CommunityOrganization ON (Community.id = CommunityOrganization.communityID)
+		WHERE CommunityOrganization.organizationID = $1 AND CommunityOrganization.status = 'approved'
+		ORDER BY Community.id LIMIT 1;
+	`, organizationID); err == sql.ErrNoRows {
+		return time.UTC, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return c.Location(), nil
+}
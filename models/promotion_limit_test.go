@@ -0,0 +1,100 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPromotionValidate(t *testing.T) {
+	tests := []struct {
+		promotion Promotion
+		expected  error
+	}{
+		{Promotion{}, nil},
+		{Promotion{AccountLimit: 2, AccountLimitPeriod: PromotionPeriodWeek, TotalLimit: 100, DailyLimit: 10}, nil},
+		{Promotion{AccountLimit: -1}, errPromotionLimitInvalid},
+		{Promotion{DailyLimit: -1}, errPromotionLimitInvalid},
+		{Promotion{AccountLimit: 1, AccountLimitPeriod: "hour"}, errPromotionPeriodInvalid},
+	}
+	for _, test := range tests {
+		if err := test.promotion.Validate(); err != test.expected {
+			t.Errorf("expected %v for %+v, got %v", test.expected, test.promotion, err)
+		}
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Thursday, November 2nd.
+	now := time.Date(2017, 11, 2, 18, 30, 0, 0, loc)
+
+	tests := []struct {
+		period   string
+		expected time.Time
+	}{
+		{"", time.Time{}},
+		{PromotionPeriodDay, time.Date(2017, 11, 2, 0, 0, 0, 0, loc)},
+		{PromotionPeriodWeek, time.Date(2017, 10, 30, 0, 0, 0, 0, loc)},
+		{PromotionPeriodMonth, time.Date(2017, 11, 1, 0, 0, 0, 0, loc)},
+		{PromotionPeriodYear, time.Date(2017, 1, 1, 0, 0, 0, 0, loc)},
+	}
+	for _, test := range tests {
+		if start := periodStart(test.period, now); !start.Equal(test.expected) {
+			t.Errorf("expected %q period to start at %v, got %v", test.period, test.expected, start)
+		}
+	}
+
+	sunday := time.Date(2017, 11, 5, 12, 0, 0, 0, loc)
+	if start := periodStart(PromotionPeriodWeek, sunday); !start.Equal(time.Date(2017, 10, 30, 0, 0, 0, 0, loc)) {
+		t.Errorf("expected weeks to start on monday, got %v", start)
+	}
+}
+
+func TestPromotionCheckLimits(t *testing.T) {
+	tests := []struct {
+		promotion Promotion
+		usage     promotionUsage
+		expected  error
+	}{
+		{Promotion{}, promotionUsage{Total: 1000, Today: 100, Account: 50}, nil},
+		{Promotion{IsSingleUse: true}, promotionUsage{}, nil},
+		{Promotion{IsSingleUse: true, AccountLimit: 5}, promotionUsage{Account: 1}, ErrPromotionRedeemed},
+		{Promotion{AccountLimit: 2, AccountLimitPeriod: PromotionPeriodDay}, promotionUsage{Account: 1}, nil},
+		{Promotion{AccountLimit: 2, AccountLimitPeriod: PromotionPeriodDay}, promotionUsage{Account: 2}, ErrPromotionAccountLimit},
+		{Promotion{TotalLimit: 10}, promotionUsage{Total: 10}, ErrPromotionSoldOut},
+		{Promotion{DailyLimit: 3}, promotionUsage{Total: 10, Today: 3}, ErrPromotionDailyLimit},
+	}
+	for _, test := range tests {
+		if err := test.promotion.checkLimits(test.usage); err != test.expected {
+			t.Errorf("expected %v for %+v with %+v, got %v", test.expected, test.promotion, test.usage, err)
+		}
+	}
+}
+
+func TestPromotionSetRemaining(t *testing.T) {
+	p := Promotion{}
+	p.setRemaining(promotionUsage{Total: 5}, true)
+	if p.Remaining != nil || p.AccountRemaining != nil {
+		t.Errorf("expected unlimited promotions to not have remaining redemptions, got %v, %v", p.Remaining, p.AccountRemaining)
+	}
+
+	p = Promotion{TotalLimit: 10, DailyLimit: 4, IsSingleUse: true}
+	p.setRemaining(promotionUsage{Total: 8, Today: 1, Account: 1}, true)
+	if p.Remaining == nil || *p.Remaining != 2 {
+		t.Errorf("expected 2 remaining redemptions, got %v", p.Remaining)
+	}
+	if p.AccountRemaining == nil || *p.AccountRemaining != 0 {
+		t.Errorf("expected 0 remaining redemptions of the account, got %v", p.AccountRemaining)
+	}
+
+	p.setRemaining(promotionUsage{Total: 2, Today: 4}, false)
+	if p.Remaining == nil || *p.Remaining != 0 {
+		t.Errorf("expected 0 remaining redemptions today, got %v", p.Remaining)
+	}
+	if p.AccountRemaining != nil {
+		t.Errorf("expected anonymous listings to not have remaining redemptions of the account, got %v", *p.AccountRemaining)
+	}
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// validateSchedule checks the promotion's windows and blackout dates.
+func (p *Promotion) validateSchedule() error {
+	if p.StartsAt.Valid && p.Expiration.Valid && !p.Expiration.Time.After(p.StartsAt.Time) {
+		return errPromotionScheduleInvalid
+	}
+	if p.Windows == nil {
+		p.Windows = Hours{}
+	}
+	for _, w := range p.Windows {
+		if w.Weekday < time.Sunday || w.Weekday > time.Saturday || !validClock(w.Start) || !validClock(w.Close) || w.Start == w.Close {
+			return errPromotionWindowInvalid
+		}
+	}
+	if p.BlackoutDates == nil {
+		p.BlackoutDates = pq.StringArray{}
+	}
+	for _, date := range p.BlackoutDates {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return errPromotionBlackoutDateInvalid
+		}
+	}
+	return nil
+}
+
+// validClock reports whether the value is a time of day written as hours and minutes, e.g.
+// 1630 for 4:30 PM. 2400 is the end of the day.
+func validClock(value int) bool {
+	return value >= 0 && value <= 2400 && value%100 < 60
+}
+
+// availableAt returns why the promotion can not be redeemed at now, which must be in the time
+// zone of the promotion's organization, or nil if it can be.
+func (p *Promotion) availableAt(now time.Time) error {
+	if p.StartsAt.Valid && now.Before(p.StartsAt.Time) {
+		return ErrPromotionNotStarted
+	}
+	if p.Expiration.Valid && !now.Before(p.expiresAt(now.Location())) {
+		return ErrPromotionExpired
+	}
+	today := now.Format("2006-01-02")
+	for _, date := range p.BlackoutDates {
+		if date == today {
+			return ErrPromotionBlackedOut
+		}
+	}
+	if len(p.Windows) > 0 && !p.Windows.IsOpen(now) {
+		return ErrPromotionOutsideWindow
+	}
+	return nil
+}
+
+// expiresAt returns when the promotion expires. Expirations at midnight UTC are dates, as
+// promotions stored them before they were scheduled, and last until the end of that day in loc.
+func (p *Promotion) expiresAt(loc *time.Location) time.Time {
+	t := p.Expiration.Time.UTC()
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+	}
+	return p.Expiration.Time
+}
+
+// getPromotionWindows returns the windows of a promotion.
+func getPromotionWindows(promotionID int, q sqlx.Queryer) (Hours, error) {
+	windows := Hours{}
+	if err := sqlx.Select(q, &windows, "SELECT weekday, start, close FROM PromotionWindow WHERE promotionID = $1 ORDER BY weekday, start;", promotionID); err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// getPromotionWindowsByOrganization returns the windows of every promotion of the
+// organization keyed by promotion id.
+func getPromotionWindowsByOrganization(organizationID int, client *sqlx.DB) (map[int]Hours, error) {
+	rows := []struct {
+		PromotionID int
+		Hour
+	}{}
+	if err := client.Select(&rows, `
+		SELECT PromotionWindow.promotionID, PromotionWindow.weekday, PromotionWindow.start, PromotionWindow.close FROM PromotionWindow
+		INNER JOIN Promotion ON (PromotionWindow.promotionID = Promotion.id)
+		WHERE Promotion.organizationID = $1
+		ORDER BY PromotionWindow.promotionID, PromotionWindow.weekday, PromotionWindow.start;
+	`, organizationID); err != nil {
+		return nil, err
+	}
+	windows := map[int]Hours{}
+	for _, row := range rows {
+		windows[row.PromotionID] = append(windows[row.PromotionID], row.Hour)
+	}
+	return windows, nil
+}
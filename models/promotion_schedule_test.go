@@ -0,0 +1,87 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/jteppinette/peragrin-api/common"
+)
+
+func TestPromotionValidateSchedule(t *testing.T) {
+	start := time.Date(2017, 11, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	p := Promotion{}
+	if err := p.Validate(); err != nil || p.Windows == nil || p.BlackoutDates == nil {
+		t.Errorf("expected empty windows and blackout dates, got %v, %v, %v", p.Windows, p.BlackoutDates, err)
+	}
+
+	tests := []struct {
+		promotion Promotion
+		expected  error
+	}{
+		{Promotion{StartsAt: common.NewJSONNullTime(start), Expiration: common.NewJSONNullTime(end)}, nil},
+		{Promotion{StartsAt: common.NewJSONNullTime(end), Expiration: common.NewJSONNullTime(start)}, errPromotionScheduleInvalid},
+		{Promotion{Windows: Hours{{time.Monday, 1600, 1800}, {time.Friday, 2200, 200}}}, nil},
+		{Promotion{Windows: Hours{{time.Monday, 1600, 1600}}}, errPromotionWindowInvalid},
+		{Promotion{Windows: Hours{{time.Monday, 1660, 1800}}}, errPromotionWindowInvalid},
+		{Promotion{Windows: Hours{{7, 1600, 1800}}}, errPromotionWindowInvalid},
+		{Promotion{BlackoutDates: pq.StringArray{"2017-12-25"}}, nil},
+		{Promotion{BlackoutDates: pq.StringArray{"12/25/2017"}}, errPromotionBlackoutDateInvalid},
+	}
+	for _, test := range tests {
+		if err := test.promotion.Validate(); err != test.expected {
+			t.Errorf("expected %v for %+v, got %v", test.expected, test.promotion, err)
+		}
+	}
+}
+
+func TestPromotionAvailableAt(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	weekdays := Hours{}
+	for weekday := time.Monday; weekday <= time.Friday; weekday++ {
+		weekdays = append(weekdays, Hour{weekday, 1600, 1800})
+	}
+	happyHour := Promotion{
+		StartsAt:      common.NewJSONNullTime(time.Date(2017, 11, 1, 0, 0, 0, 0, loc)),
+		Expiration:    common.NewJSONNullTime(time.Date(2018, 1, 1, 0, 0, 0, 0, loc)),
+		Windows:       weekdays,
+		BlackoutDates: pq.StringArray{"2017-11-23"},
+	}
+
+	tests := []struct {
+		time     time.Time
+		expected error
+	}{
+		{time.Date(2017, 11, 6, 16, 30, 0, 0, loc), nil},
+		{time.Date(2017, 11, 6, 21, 30, 0, 0, time.UTC), nil},
+		{time.Date(2017, 11, 6, 18, 0, 0, 0, loc), ErrPromotionOutsideWindow},
+		{time.Date(2017, 11, 4, 16, 30, 0, 0, loc), ErrPromotionOutsideWindow},
+		{time.Date(2017, 11, 23, 16, 30, 0, 0, loc), ErrPromotionBlackedOut},
+		{time.Date(2017, 10, 30, 16, 30, 0, 0, loc), ErrPromotionNotStarted},
+		{time.Date(2018, 1, 1, 16, 30, 0, 0, loc), ErrPromotionExpired},
+	}
+	for _, test := range tests {
+		if err := happyHour.availableAt(test.time.In(loc)); err != test.expected {
+			t.Errorf("expected %v at %s, got %v", test.expected, test.time, err)
+		}
+	}
+
+	dated := Promotion{Expiration: common.NewJSONNullTime(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))}
+	if err := dated.availableAt(time.Date(2018, 1, 1, 23, 30, 0, 0, loc)); err != nil {
+		t.Errorf("expected a dated expiration to last until the end of the day, got %v", err)
+	}
+	if err := dated.availableAt(time.Date(2018, 1, 2, 0, 0, 0, 0, loc)); err != ErrPromotionExpired {
+		t.Errorf("expected a dated expiration to end at midnight, got %v", err)
+	}
+
+	if err := (&Promotion{}).availableAt(time.Now()); err != nil {
+		t.Errorf("expected promotions without a schedule to always be available, got %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// VisitMethodOperator visits were recorded by an operator who scanned the member's credential.
+	VisitMethodOperator = "operator"
+	// VisitMethodMember visits were recorded by a member who scanned the organization's check-in code.
+	VisitMethodMember = "member"
+
+	// visitWindow is how long a visit lasts. Check-ins during a visit return it instead of
+	// recording another, so that scanning a code twice is not counted as two visits.
+	visitWindow = time.Hour
+)
+
+const (
+	// TrafficIntervalDay reports count visits per day.
+	TrafficIntervalDay = "day"
+	// TrafficIntervalWeek reports count visits per week, starting on mondays.
+	TrafficIntervalWeek = "week"
+	// TrafficIntervalMonth reports count visits per month.
+	TrafficIntervalMonth = "month"
+
+	// maxTrafficReportDays is the longest period that a report can cover.
+	maxTrafficReportDays = 366 * 2
+	// defaultTrafficReportDays is the period that a report covers when it is not provided.
+	defaultTrafficReportDays = 30
+)
+
+// Visit is a check-in of an account membership at an organization.
+type Visit struct {
+	ID             int       `json:"id"`
+	AccountID      int       `json:"accountID"`
+	MembershipID   int       `json:"membershipID"`
+	OrganizationID int       `json:"organizationID"`
+	CommunityID    int       `json:"communityID"`
+	Method         string    `json:"method"`
+	OperatorID     *int      `json:"operatorID"`
+	CreatedAt      time.Time `json:"createdAt"`
+
+	// OrganizationName and MembershipName are only populated in an account's visit history.
+	OrganizationName string `json:"organizationName,omitempty"`
+	MembershipName   string `json:"membershipName,omitempty"`
+}
+
+// CheckIn is a visit that was recorded by scanning a membership credential, together with
+// the credential so that the operator can see who checked in.
+type CheckIn struct {
+	Visit      *Visit                `json:"visit"`
+	Credential *MembershipCredential `json:"credential"`
+}
+
+// TrafficReport counts the visits of an organization or community from From until To.
+// Buckets start at midnight in the report's time zone, and Visitors are distinct accounts.
+type TrafficReport struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Interval string    `json:"interval"`
+	Timezone string    `json:"timezone"`
+
+	Visits   int             `json:"visits"`
+	Visitors int             `json:"visitors"`
+	Buckets  []TrafficBucket `json:"buckets"`
+
+	// Organizations is only populated in community reports.
+	Organizations []OrganizationTraffic `json:"organizations,omitempty"`
+}
+
+// TrafficBucket counts the visits of an interval of a traffic report.
+type TrafficBucket struct {
+	Start    time.Time `json:"start"`
+	Visits   int       `json:"visits"`
+	Visitors int       `json:"visitors"`
+}
+
+// OrganizationTraffic counts the visits of an organization in a community traffic report.
+type OrganizationTraffic struct {
+	OrganizationID   int    `json:"organizationID"`
+	OrganizationName string `json:"organizationName"`
+	Visits           int    `json:"visits"`
+	Visitors         int    `json:"visitors"`
+}
+
+// OrganizationCheckInCode returns the code that members scan to check in at the organization.
+// It is the organization's id signed with the secret, so that codes can not be forged.
+func OrganizationCheckInCode(organizationID int, secret string) string {
+	return signToken("organization-check-in", strconv.Itoa(organizationID), secret)
+}
+
+// parseOrganizationCheckInCode returns the organization id of a code that was signed with the secret.
+func parseOrganizationCheckInCode(code, secret string) (int, error) {
+	id, ok := parseSignedToken("organization-check-in", code, secret)
+	if !ok {
+		return 0, errCheckInCodeInvalid
+	}
+	organizationID, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, errCheckInCodeInvalid
+	}
+	return organizationID, nil
+}
+
+// CheckInCredential records a visit of a credential's account membership at the organization,
+// which must be a member of the membership's community. The credential must not be expired.
+func CheckInCredential(organizationID int, c *MembershipCredential, operatorID int, client *sqlx.DB) (*Visit, error) {
+	if !c.Valid() {
+		return nil, errCredentialExpired
+	}
+	var member bool
+	if err := client.Get(&member, `
+		SELECT EXISTS(
+			SELECT FROM CommunityOrganization
+			WHERE organizationID = $1 AND communityID = $2 AND status = $3
+		);
+	`, organizationID, c.CommunityID, JoinStatusApproved); err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, errOrganizationNotInCommunity
+	}
+	return recordVisit(&Visit{
+		AccountID:      c.AccountID,
+		MembershipID:   c.MembershipID,
+		OrganizationID: organizationID,
+		CommunityID:    c.CommunityID,
+		Method:         VisitMethodOperator,
+		OperatorID:     &operatorID,
+	}, client)
+}
+
+// CheckInMember records a visit of the account at the organization of a check-in code. The
+// visit is of the account's active membership in one of the organization's communities, or
+// of membershipID when it is not zero.
+func CheckInMember(accountID int, code string, membershipID int, secret string, client *sqlx.DB) (*Visit, error) {
+	organizationID, err := parseOrganizationCheckInCode(strings.TrimSpace(code), secret)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Visit{AccountID: accountID, OrganizationID: organizationID, Method: VisitMethodMember}
+	if err := client.Get(v, `
+		SELECT AccountMembership.membershipID, Membership.communityID
+		FROM AccountMembership
+		INNER JOIN Membership ON (AccountMembership.membershipID = Membership.id)
+		INNER JOIN CommunityOrganization ON (Membership.communityID = CommunityOrganization.communityID)
+		WHERE AccountMembership.accountID = $1 AND CommunityOrganization.organizationID = $2
+		AND CommunityOrganization.status = $3
+		AND ($4 = 0 OR AccountMembership.membershipID = $4) AND `+activeMembership+`
+		ORDER BY AccountMembership.expiration DESC NULLS FIRST, AccountMembership.membershipID
+		LIMIT 1;
+	`, accountID, organizationID, JoinStatusApproved, membershipID); err == sql.ErrNoRows {
+		return nil, errActiveMembershipRequired
+	} else if err != nil {
+		return nil, err
+	}
+	return recordVisit(v, client)
+}
+
+// recordVisit inserts the visit unless the account membership already visited the
+// organization during the visit window, in which case the earlier visit is returned.
+// Check-ins of an account membership are serialized by locking it, so that concurrent
+// scans do not both record a visit.
+func recordVisit(v *Visit, client *sqlx.DB) (*Visit, error) {
+	tx, err := client.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	if _, err = tx.Exec("SELECT FROM AccountMembership WHERE accountID = $1 AND membershipID = $2 FOR UPDATE;", v.AccountID, v.MembershipID); err != nil {
+		return nil, err
+	}
+	err = tx.Get(v, `
+		INSERT INTO Visit (accountID, membershipID, organizationID, communityID, method, operatorID)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE NOT EXISTS (
+			SELECT FROM Visit WHERE accountID = $1 AND membershipID = $2 AND organizationID = $3 AND createdAt > NOW() - $7 * INTERVAL '1 second'
+		)
+		RETURNING *;
+	`, v.AccountID, v.MembershipID, v.OrganizationID, v.CommunityID, v.Method, v.OperatorID, visitWindow.Seconds())
+	if err == sql.ErrNoRows {
+		err = tx.Get(v, `
+			SELECT * FROM Visit WHERE accountID = $1 AND membershipID = $2 AND organizationID = $3
+			ORDER BY createdAt DESC LIMIT 1;
+		`, v.AccountID, v.MembershipID, v.OrganizationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetVisitsByAccount returns the account's visit history with the newest visits first.
+func GetVisitsByAccount(accountID int, client *sqlx.DB) ([]Visit, error) {
+	visits := []Visit{}
+	if err := client.Select(&visits, `
+		SELECT Visit.*, Organization.name AS organizationName, Membership.name AS membershipName
+		FROM Visit
+		INNER JOIN Organization ON (Visit.organizationID = Organization.id)
+		INNER JOIN Membership ON (Visit.membershipID = Membership.id)
+		WHERE Visit.accountID = $1
+		ORDER BY Visit.createdAt DESC;
+	`, accountID); err != nil {
+		return nil, err
+	}
+	return visits, nil
+}
+
+// NewTrafficReport returns a report of the visits in the period from the from date until,
+// and including, the to date. Dates are formatted as 2006-01-02 in the location. The report
+// covers the 30 days until now when the dates are not provided, and counts visits per day
+// when the interval is not provided.
+func NewTrafficReport(from, to, interval string, loc *time.Location, now time.Time) (*TrafficReport, error) {
+	r := &TrafficReport{Interval: interval, Timezone: loc.String()}
+	if r.Interval == "" {
+		r.Interval = TrafficIntervalDay
+	}
+	if r.Interval != TrafficIntervalDay && r.Interval != TrafficIntervalWeek && r.Interval != TrafficIntervalMonth {
+		return nil, errTrafficIntervalInvalid
+	}
+
+	now = now.In(loc)
+	r.To = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	if to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, loc)
+		if err != nil {
+			return nil, errTrafficPeriodInvalid
+		}
+		r.To = t.AddDate(0, 0, 1)
+	}
+	r.From = r.To.AddDate(0, 0, -defaultTrafficReportDays)
+	if from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, loc)
+		if err != nil {
+			return nil, errTrafficPeriodInvalid
+		}
+		r.From = t
+	}
+
+	if !r.From.Before(r.To) || r.To.Sub(r.From) > maxTrafficReportDays*24*time.Hour+time.Hour {
+		return nil, errTrafficPeriodInvalid
+	}
+	return r, nil
+}
+
+// load counts the report's visits whose column is id.
+func (r *TrafficReport) load(column string, id int, client *sqlx.DB) error {
+	r.Buckets = []TrafficBucket{}
+	if err := client.Select(&r.Buckets, `
+		SELECT
+			date_trunc($4, Visit.createdAt AT TIME ZONE $5) AT TIME ZONE $5 AS start,
+			COUNT(*) AS visits, COUNT(DISTINCT Visit.accountID) AS visitors
+		FROM Visit
+		WHERE Visit.`+column+` = $1 AND Visit.createdAt >= $2 AND Visit.createdAt < $3
+		GROUP BY 1
+		ORDER BY 1;
+	`, id, r.From, r.To, r.Interval, r.Timezone); err != nil {
+		return err
+	}
+	for i := range r.Buckets {
+		r.Buckets[i].Start = r.Buckets[i].Start.In(r.From.Location())
+	}
+
+	totals := TrafficBucket{}
+	if err := client.Get(&totals, `
+		SELECT COUNT(*) AS visits, COUNT(DISTINCT Visit.accountID) AS visitors
+		FROM Visit
+		WHERE Visit.`+column+` = $1 AND Visit.createdAt >= $2 AND Visit.createdAt < $3;
+	`, id, r.From, r.To); err != nil {
+		return err
+	}
+	r.Visits, r.Visitors = totals.Visits, totals.Visitors
+	return nil
+}
+
+// LoadOrganization counts the organization's visits.
+func (r *TrafficReport) LoadOrganization(organizationID int, client *sqlx.DB) error {
+	return r.load("organizationID", organizationID, client)
+}
+
+// LoadCommunity counts the community's visits, in total and per organization.
+func (r *TrafficReport) LoadCommunity(communityID int, client *sqlx.DB) error {
+	if err := r.load("communityID", communityID, client); err != nil {
+		return err
+	}
+	r.Organizations = []OrganizationTraffic{}
+	return client.Select(&r.Organizations, `
+		SELECT Organization.id AS organizationID, Organization.name AS organizationName,
+			COUNT(*) AS visits, COUNT(DISTINCT Visit.accountID) AS visitors
+		FROM Visit
+		INNER JOIN Organization ON (Visit.organizationID = Organization.id)
+		WHERE Visit.communityID = $1 AND Visit.createdAt >= $2 AND Visit.createdAt < $3
+		GROUP BY Organization.id
+		ORDER BY visits DESC, Organization.name;
+	`, communityID, r.From, r.To)
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrganizationCheckInCode(t *testing.T) {
+	code := OrganizationCheckInCode(42, "secret")
+	if id, err := parseOrganizationCheckInCode(code, "secret"); err != nil || id != 42 {
+		t.Errorf("got %d, %v", id, err)
+	}
+	for _, forged := range []string{"43" + code[2:], code + "x", "42", "", "x" + code, OrganizationCheckInCode(42, "other")} {
+		if _, err := parseOrganizationCheckInCode(forged, "secret"); err != errCheckInCodeInvalid {
+			t.Errorf("%q: got %v, want %v", forged, err, errCheckInCodeInvalid)
+		}
+	}
+}
+
+func TestNewTrafficReport(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	now := time.Date(2017, 11, 1, 3, 0, 0, 0, time.UTC) // October 31 in New York.
+
+	for _, c := range []struct {
+		from, to, interval string
+		wantFrom, wantTo   time.Time
+		wantInterval       string
+		err                error
+	}{
+		{"", "", "", time.Date(2017, 10, 2, 0, 0, 0, 0, loc), time.Date(2017, 11, 1, 0, 0, 0, 0, loc), TrafficIntervalDay, nil},
+		{"2017-01-01", "2017-03-31", "month", time.Date(2017, 1, 1, 0, 0, 0, 0, loc), time.Date(2017, 4, 1, 0, 0, 0, 0, loc), TrafficIntervalMonth, nil},
+		{"2016-01-01", "2017-12-31", "week", time.Date(2016, 1, 1, 0, 0, 0, 0, loc), time.Date(2018, 1, 1, 0, 0, 0, 0, loc), TrafficIntervalWeek, nil},
+		{"", "", "hour", time.Time{}, time.Time{}, "", errTrafficIntervalInvalid},
+		{"2017-02-01", "2017-01-01", "", time.Time{}, time.Time{}, "", errTrafficPeriodInvalid},
+		{"2015-01-01", "2017-12-31", "", time.Time{}, time.Time{}, "", errTrafficPeriodInvalid},
+		{"01/01/2017", "", "", time.Time{}, time.Time{}, "", errTrafficPeriodInvalid},
+	} {
+		r, err := NewTrafficReport(c.from, c.to, c.interval, loc, now)
+		if err != c.err {
+			t.Errorf("%s - %s: got %v, want %v", c.from, c.to, err, c.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !r.From.Equal(c.wantFrom) || !r.To.Equal(c.wantTo) || r.Interval != c.wantInterval || r.Timezone != "America/New_York" {
+			t.Errorf("%s - %s: got %s - %s per %s", c.from, c.to, r.From, r.To, r.Interval)
+		}
+	}
+}
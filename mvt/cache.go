@@ -0,0 +1,71 @@
+package mvt
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache holds encoded tiles in memory for a limited time. When the cache is full,
+// the entry that expires soonest is evicted. Cache is safe for concurrent use.
+type Cache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewCache returns a cache that holds at most size tiles for the provided duration.
+func NewCache(size int, ttl time.Duration) *Cache {
+	return &Cache{size: size, ttl: ttl, entries: map[string]cacheEntry{}}
+}
+
+// Get returns the tile stored for the key, or false if it is missing or expired.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.data, true
+}
+
+// Set stores the tile for the key.
+func (c *Cache) Set(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		c.evict()
+	}
+	c.entries[key] = cacheEntry{data, time.Now().Add(c.ttl)}
+}
+
+// evict removes every expired entry, or the entry that expires soonest if none have expired.
+func (c *Cache) evict() {
+	now := time.Now()
+	oldest := ""
+	for key, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || e.expires.Before(c.entries[oldest].expires) {
+			oldest = key
+		}
+	}
+	if len(c.entries) >= c.size && oldest != "" {
+		delete(c.entries, oldest)
+	}
+}
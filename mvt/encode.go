@@ -0,0 +1,107 @@
+package mvt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// Protocol buffer wire types used by the vector tile message definitions.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// Marshal encodes the tile as a vector tile protocol buffer message.
+func (t *Tile) Marshal() []byte {
+	tile := &message{}
+	for _, l := range t.layers {
+		if len(l.features) > 0 {
+			tile.bytes(3, l.marshal())
+		}
+	}
+	return tile.Bytes()
+}
+
+func (l *Layer) marshal() []byte {
+	layer := &message{}
+	layer.uint(15, 2)
+	layer.string(1, l.Name)
+	for _, f := range l.features {
+		feature := &message{}
+		if f.id != 0 {
+			feature.uint(1, f.id)
+		}
+		feature.packed(2, f.tags)
+		feature.uint(3, uint64(f.kind))
+		feature.packed(4, f.geometry)
+		layer.bytes(2, feature.Bytes())
+	}
+	for _, k := range l.keys {
+		layer.string(3, k)
+	}
+	for _, v := range l.values {
+		value := &message{}
+		switch v := v.(type) {
+		case string:
+			value.string(1, v)
+		case float64:
+			value.key(3, wireFixed64)
+			b := make([]byte, 8)
+			binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+			value.Write(b)
+		case int64:
+			value.uint(6, uint64((v<<1)^(v>>63)))
+		case bool:
+			if v {
+				value.uint(7, 1)
+			} else {
+				value.uint(7, 0)
+			}
+		}
+		layer.bytes(4, value.Bytes())
+	}
+	layer.uint(5, Extent)
+	return layer.Bytes()
+}
+
+// message writes protocol buffer fields.
+type message struct {
+	bytes.Buffer
+}
+
+func (m *message) varint(v uint64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	m.Write(b[:binary.PutUvarint(b, v)])
+}
+
+func (m *message) key(field, wire int) {
+	m.varint(uint64(field<<3 | wire))
+}
+
+func (m *message) uint(field int, v uint64) {
+	m.key(field, wireVarint)
+	m.varint(v)
+}
+
+func (m *message) bytes(field int, b []byte) {
+	m.key(field, wireBytes)
+	m.varint(uint64(len(b)))
+	m.Write(b)
+}
+
+func (m *message) string(field int, s string) {
+	m.bytes(field, []byte(s))
+}
+
+func (m *message) packed(field int, values []uint32) {
+	if len(values) == 0 {
+		return
+	}
+	packed := &message{}
+	for _, v := range values {
+		packed.varint(uint64(v))
+	}
+	m.bytes(field, packed.Bytes())
+}
@@ -0,0 +1,10 @@
+package mvt
+
+import (
+	"errors"
+)
+
+var (
+	errInvalidZoom        = errors.New("zoom must be between 0 and 22")
+	errInvalidCoordinates = errors.New("tile coordinates out of range")
+)
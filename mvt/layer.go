@@ -0,0 +1,361 @@
+package mvt
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+
+	"github.com/jteppinette/peragrin-api/geometry"
+)
+
+// Geometry types as defined by the vector tile specification.
+const (
+	typePoint      = 1
+	typeLineString = 2
+	typePolygon    = 3
+)
+
+// Geometry commands as defined by the vector tile specification.
+const (
+	commandMoveTo    = 1
+	commandLineTo    = 2
+	commandClosePath = 7
+)
+
+// simplifyTolerance is the Douglas–Peucker tolerance, in tile units, applied to lines and polygons.
+const simplifyTolerance = 1
+
+// Layer is a named set of features within a tile. Property keys and values are
+// shared by every feature in the layer.
+type Layer struct {
+	Name string
+
+	tile       *Tile
+	features   []feature
+	keys       []string
+	keyIndex   map[string]uint32
+	values     []interface{}
+	valueIndex map[interface{}]uint32
+}
+
+type feature struct {
+	id       uint64
+	tags     []uint32
+	kind     int
+	geometry []uint32
+}
+
+// AddPoint adds a point feature to the layer. The point is ignored if it is outside of the tile.
+func (l *Layer) AddPoint(id uint64, p geometry.Point, properties map[string]interface{}) {
+	projected := l.tile.project(p)
+	if !l.tile.clipBounds().Contains(projected) {
+		return
+	}
+	l.add(id, typePoint, encodePoints([]geometry.Point{projected}), properties)
+}
+
+// AddGeoJSON adds every feature of a GeoJSON document to the layer. Geometry is clipped to
+// the tile and simplified, and geometry outside of the tile is ignored. Each feature's
+// properties are merged with the provided properties; properties that are not strings,
+// numbers, or booleans are encoded as JSON strings.
+func (l *Layer) AddGeoJSON(data []byte, properties map[string]interface{}) error {
+	object := struct {
+		Type       string                 `json:"type"`
+		Features   []json.RawMessage      `json:"features"`
+		Geometry   json.RawMessage        `json:"geometry"`
+		Geometries []json.RawMessage      `json:"geometries"`
+		Properties map[string]interface{} `json:"properties"`
+	}{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+
+	switch object.Type {
+	case "FeatureCollection":
+		for _, f := range object.Features {
+			if err := l.AddGeoJSON(f, properties); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "Feature":
+		if len(object.Geometry) == 0 || string(object.Geometry) == "null" {
+			return nil
+		}
+		merged := map[string]interface{}{}
+		for k, v := range object.Properties {
+			merged[k] = v
+		}
+		for k, v := range properties {
+			merged[k] = v
+		}
+		return l.AddGeoJSON(object.Geometry, merged)
+	case "GeometryCollection":
+		for _, g := range object.Geometries {
+			if err := l.AddGeoJSON(g, properties); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return l.addGeometry(object.Type, data, properties)
+}
+
+func (l *Layer) addGeometry(t string, data []byte, properties map[string]interface{}) error {
+	g := struct {
+		Coordinates json.RawMessage `json:"coordinates"`
+	}{}
+	if err := json.Unmarshal(data, &g); err != nil {
+		return err
+	}
+
+	var err error
+	switch t {
+	case "Point", "MultiPoint":
+		points := []geometry.Point{}
+		if t == "Point" {
+			var p geometry.Point
+			err = json.Unmarshal(g.Coordinates, &p)
+			points = append(points, p)
+		} else {
+			err = json.Unmarshal(g.Coordinates, &points)
+		}
+		projected := []geometry.Point{}
+		for _, p := range points {
+			if p := l.tile.project(p); l.tile.clipBounds().Contains(p) {
+				projected = append(projected, p)
+			}
+		}
+		if len(projected) > 0 {
+			l.add(0, typePoint, encodePoints(projected), properties)
+		}
+	case "LineString", "MultiLineString":
+		lines := [][]geometry.Point{}
+		if t == "LineString" {
+			line := []geometry.Point{}
+			err = json.Unmarshal(g.Coordinates, &line)
+			lines = append(lines, line)
+		} else {
+			err = json.Unmarshal(g.Coordinates, &lines)
+		}
+		clipped := [][]geometry.Point{}
+		for _, line := range lines {
+			for _, c := range geometry.ClipLine(l.tile.projectAll(line), l.tile.clipBounds()) {
+				clipped = append(clipped, geometry.Simplify(c, simplifyTolerance))
+			}
+		}
+		if commands := encodeLines(clipped); len(commands) > 0 {
+			l.add(0, typeLineString, commands, properties)
+		}
+	case "Polygon", "MultiPolygon":
+		polygons := [][][]geometry.Point{}
+		if t == "Polygon" {
+			polygon := [][]geometry.Point{}
+			err = json.Unmarshal(g.Coordinates, &polygon)
+			polygons = append(polygons, polygon)
+		} else {
+			err = json.Unmarshal(g.Coordinates, &polygons)
+		}
+		e := &encoder{}
+		for _, polygon := range polygons {
+			l.encodePolygon(e, polygon)
+		}
+		if len(e.commands) > 0 {
+			l.add(0, typePolygon, e.commands, properties)
+		}
+	}
+	return err
+}
+
+func (t *Tile) projectAll(points []geometry.Point) []geometry.Point {
+	projected := make([]geometry.Point, len(points))
+	for i, p := range points {
+		projected[i] = t.project(p)
+	}
+	return projected
+}
+
+// encodePolygon clips, simplifies, and writes the rings of a polygon. Nothing is
+// written if the exterior ring is outside of the tile or collapses.
+func (l *Layer) encodePolygon(e *encoder, rings [][]geometry.Point) {
+	for i, ring := range rings {
+		ring = geometry.ClipRing(l.tile.projectAll(ring), l.tile.clipBounds())
+		ring = geometry.Simplify(ring, simplifyTolerance)
+		if !e.encodeRing(ring, i == 0) && i == 0 {
+			return
+		}
+	}
+}
+
+func (l *Layer) add(id uint64, kind int, commands []uint32, properties map[string]interface{}) {
+	f := feature{id: id, kind: kind, geometry: commands}
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		v, ok := normalizeValue(properties[key])
+		if !ok {
+			continue
+		}
+		f.tags = append(f.tags, l.key(key), l.value(v))
+	}
+	l.features = append(l.features, f)
+}
+
+func (l *Layer) key(k string) uint32 {
+	if i, ok := l.keyIndex[k]; ok {
+		return i
+	}
+	i := uint32(len(l.keys))
+	l.keys = append(l.keys, k)
+	l.keyIndex[k] = i
+	return i
+}
+
+func (l *Layer) value(v interface{}) uint32 {
+	if i, ok := l.valueIndex[v]; ok {
+		return i
+	}
+	i := uint32(len(l.values))
+	l.values = append(l.values, v)
+	l.valueIndex[v] = i
+	return i
+}
+
+// normalizeValue converts a property value into a string, float64, int64, or bool.
+// Nil values are dropped and other values are encoded as JSON.
+func normalizeValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, false
+	case string, float64, int64, bool:
+		return v, true
+	case int:
+		return int64(v), true
+	case float32:
+		return float64(v), true
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, false
+		}
+		return string(b), true
+	}
+}
+
+// encoder writes geometry commands. Command parameters are relative to the cursor,
+// which carries across every part of a feature's geometry.
+type encoder struct {
+	cx, cy   int32
+	commands []uint32
+}
+
+func (e *encoder) moveTo(ps positions) {
+	e.commands = append(e.commands, command(commandMoveTo, len(ps)))
+	e.parameters(ps)
+}
+
+func (e *encoder) lineTo(ps positions) {
+	e.commands = append(e.commands, command(commandLineTo, len(ps)))
+	e.parameters(ps)
+}
+
+func (e *encoder) closePath() {
+	e.commands = append(e.commands, command(commandClosePath, 1))
+}
+
+func (e *encoder) parameters(ps positions) {
+	for _, p := range ps {
+		e.commands = append(e.commands, zigzag(p[0]-e.cx), zigzag(p[1]-e.cy))
+		e.cx, e.cy = p[0], p[1]
+	}
+}
+
+// encodePoints returns the commands for a point or multipoint geometry.
+func encodePoints(points []geometry.Point) []uint32 {
+	e := &encoder{}
+	ps := positions{}
+	for _, p := range points {
+		x, y := round(p)
+		ps = append(ps, [2]int32{x, y})
+	}
+	e.moveTo(ps)
+	return e.commands
+}
+
+// encodeLines returns the commands for a linestring or multilinestring geometry.
+// Lines with fewer than two distinct positions are skipped.
+func encodeLines(lines [][]geometry.Point) []uint32 {
+	e := &encoder{}
+	for _, line := range lines {
+		ps := distinct(line)
+		if len(ps) < 2 {
+			continue
+		}
+		e.moveTo(ps[:1])
+		e.lineTo(ps[1:])
+	}
+	return e.commands
+}
+
+// encodeRing writes the commands for a single polygon ring. Exterior rings are wound
+// so that their area is positive in tile coordinates and interior rings so that it is
+// negative, as the specification requires. False is returned if the ring has no area.
+func (e *encoder) encodeRing(ring []geometry.Point, exterior bool) bool {
+	ps := distinct(ring)
+	if len(ps) > 1 && ps[0] == ps[len(ps)-1] {
+		ps = ps[:len(ps)-1]
+	}
+	if len(ps) < 3 {
+		return false
+	}
+
+	area := int64(0)
+	for i := range ps {
+		p, q := ps[i], ps[(i+1)%len(ps)]
+		area += int64(p[0])*int64(q[1]) - int64(q[0])*int64(p[1])
+	}
+	if area == 0 {
+		return false
+	}
+	if (area > 0) != exterior {
+		for i, j := 1, len(ps)-1; i < j; i, j = i+1, j-1 {
+			ps[i], ps[j] = ps[j], ps[i]
+		}
+	}
+
+	e.moveTo(ps[:1])
+	e.lineTo(ps[1:])
+	e.closePath()
+	return true
+}
+
+// positions are points in integer tile coordinates.
+type positions [][2]int32
+
+// distinct rounds points to integer tile coordinates and removes consecutive duplicates.
+func distinct(points []geometry.Point) positions {
+	result := positions{}
+	for _, p := range points {
+		x, y := round(p)
+		if len(result) > 0 && result[len(result)-1] == [2]int32{x, y} {
+			continue
+		}
+		result = append(result, [2]int32{x, y})
+	}
+	return result
+}
+
+func round(p geometry.Point) (int32, int32) {
+	return int32(math.Floor(p[0] + 0.5)), int32(math.Floor(p[1] + 0.5))
+}
+
+func command(id, count int) uint32 {
+	return uint32(id&0x7) | uint32(count)<<3
+}
+
+func zigzag(v int32) uint32 {
+	return uint32((v << 1) ^ (v >> 31))
+}
@@ -0,0 +1,78 @@
+package mvt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jteppinette/peragrin-api/geometry"
+)
+
+// The expected commands are the examples from the vector tile specification.
+func TestEncodeGeometry(t *testing.T) {
+	if actual, expected := encodePoints([]geometry.Point{{25, 17}}), []uint32{9, 50, 34}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected point %v, got %v", expected, actual)
+	}
+
+	if actual, expected := encodeLines([][]geometry.Point{{{2, 2}, {2, 10}, {10, 10}}}), []uint32{9, 4, 4, 18, 0, 16, 16, 0}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected line %v, got %v", expected, actual)
+	}
+
+	expected := []uint32{9, 6, 12, 18, 10, 12, 24, 44, 15}
+	for _, ring := range [][]geometry.Point{
+		{{3, 6}, {8, 12}, {20, 34}, {3, 6}},
+		{{3, 6}, {20, 34}, {8, 12}, {3, 6}},
+	} {
+		e := &encoder{}
+		if !e.encodeRing(ring, true) || !reflect.DeepEqual(e.commands, expected) {
+			t.Errorf("expected polygon %v, got %v", expected, e.commands)
+		}
+	}
+}
+
+func TestTile(t *testing.T) {
+	if _, err := NewTile(1, 2, 0); err != errInvalidCoordinates {
+		t.Errorf("expected invalid coordinates, got %v", err)
+	}
+
+	tile, err := NewTile(1, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := tile.project(geometry.Point{0, 0}); p != (geometry.Point{0, Extent}) {
+		t.Errorf("expected the origin at the bottom left corner, got %v", p)
+	}
+	if b := tile.Bounds(); !b.Contains(geometry.Point{90, 45}) || b.Contains(geometry.Point{-90, 45}) {
+		t.Errorf("unexpected bounds: %+v", b)
+	}
+
+	tile.Layer("organizations").AddPoint(1, geometry.Point{90, 45}, map[string]interface{}{"open": true})
+	tile.Layer("organizations").AddPoint(2, geometry.Point{-90, 45}, nil)
+	if err := tile.Layer("overlays").AddGeoJSON([]byte(`{"type": "Feature", "properties": {"name": "park"}, "geometry": {
+		"type": "Polygon", "coordinates": [[[10, 10], [20, 10], [20, 20], [10, 20], [10, 10]]]
+	}}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	if l := tile.Layer("organizations"); len(l.features) != 1 || !reflect.DeepEqual(l.keys, []string{"open"}) {
+		t.Errorf("expected a single organization with the open property, got %+v", l.features)
+	}
+	if l := tile.Layer("overlays"); len(l.features) != 1 || l.features[0].kind != typePolygon {
+		t.Errorf("expected a single polygon overlay, got %+v", l.features)
+	}
+	if b := tile.Marshal(); len(b) == 0 || !bytes.Contains(b, []byte("organizations")) || !bytes.Contains(b, []byte("park")) {
+		t.Errorf("unexpected tile encoding: %x", b)
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache(1, time.Minute)
+	c.Set("a", []byte("a"))
+	c.Set("b", []byte("b"))
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to be evicted")
+	}
+	if data, ok := c.Get("b"); !ok || string(data) != "b" {
+		t.Errorf("expected b, got %q", data)
+	}
+}
@@ -0,0 +1,85 @@
+// Package mvt encodes Mapbox Vector Tiles as described by version 2.1 of the
+// vector tile specification (https://github.com/mapbox/vector-tile-spec).
+package mvt
+
+import (
+	"math"
+
+	"github.com/jteppinette/peragrin-api/geometry"
+)
+
+const (
+	// Extent is the number of units along each side of a tile.
+	Extent = 4096
+	// Buffer is the number of units outside of a tile that geometry is kept, so
+	// lines and polygons that cross tile edges are drawn without seams.
+	Buffer = 64
+	// MaxZoom is the deepest zoom level that tiles may be requested at.
+	MaxZoom = 22
+)
+
+// Tile is a single web mercator tile made up of named layers.
+type Tile struct {
+	Z, X, Y int
+
+	layers []*Layer
+}
+
+// NewTile returns an empty tile for the provided zoom level and coordinates.
+func NewTile(z, x, y int) (*Tile, error) {
+	if z < 0 || z > MaxZoom {
+		return nil, errInvalidZoom
+	}
+	if n := 1 << uint(z); x < 0 || y < 0 || x >= n || y >= n {
+		return nil, errInvalidCoordinates
+	}
+	return &Tile{Z: z, X: x, Y: y}, nil
+}
+
+// Bounds returns the longitudes and latitudes covered by the tile, including its buffer.
+func (t *Tile) Bounds() geometry.Bounds {
+	n := math.Pow(2, float64(t.Z))
+	buffer := float64(Buffer) / Extent
+	lon := func(x float64) float64 { return x/n*360 - 180 }
+	lat := func(y float64) float64 { return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi }
+	return geometry.Bounds{
+		MinLon: math.Max(-180, lon(float64(t.X)-buffer)),
+		MinLat: math.Max(-90, lat(float64(t.Y+1)+buffer)),
+		MaxLon: math.Min(180, lon(float64(t.X+1)+buffer)),
+		MaxLat: math.Min(90, lat(float64(t.Y)-buffer)),
+	}
+}
+
+// project converts a longitude and latitude into the tile's coordinate space, where
+// the origin is the tile's top left corner and y increases downward.
+func (t *Tile) project(p geometry.Point) geometry.Point {
+	x, y := p.Mercator(t.Z)
+	return geometry.Point{x*Extent/256 - float64(t.X*Extent), y*Extent/256 - float64(t.Y*Extent)}
+}
+
+// clipBounds are the bounds of the tile's coordinate space, including its buffer.
+func (t *Tile) clipBounds() geometry.Bounds {
+	return geometry.Bounds{MinLon: -Buffer, MinLat: -Buffer, MaxLon: Extent + Buffer, MaxLat: Extent + Buffer}
+}
+
+// Layer returns the tile's layer with the provided name, creating it if necessary.
+func (t *Tile) Layer(name string) *Layer {
+	for _, l := range t.layers {
+		if l.Name == name {
+			return l
+		}
+	}
+	l := &Layer{Name: name, tile: t, keyIndex: map[string]uint32{}, valueIndex: map[interface{}]uint32{}}
+	t.layers = append(t.layers, l)
+	return l
+}
+
+// IsEmpty reports whether none of the tile's layers contain any features.
+func (t *Tile) IsEmpty() bool {
+	for _, l := range t.layers {
+		if len(l.features) > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -2,23 +2,26 @@ package organizations
 
 import (
 	"github.com/jmoiron/sqlx"
-	minio "github.com/minio/minio-go"
 
+	"github.com/jteppinette/peragrin-api/geocoder"
 	"github.com/jteppinette/peragrin-api/mail"
+	"github.com/jteppinette/peragrin-api/store"
 )
 
 // Config represents the configuration objects necessary to
 // use the objects in this package.
 type Config struct {
 	DBClient    *sqlx.DB
-	StoreClient *minio.Client
+	StoreClient store.Store
 	MailClient  *mail.Config
 	TokenSecret string
 	AppDomain   string
+
+	GeocoderClient geocoder.Geocoder
 }
 
 // Init returns a configuration struct that can be used to initialize
 // the objects in this package.
-func Init(dbClient *sqlx.DB, storeClient *minio.Client, mailClient *mail.Config, tokenSecret, appDomain string) *Config {
-	return &Config{dbClient, storeClient, mailClient, tokenSecret, appDomain}
+func Init(dbClient *sqlx.DB, storeClient store.Store, mailClient *mail.Config, tokenSecret, appDomain string, geocoderClient geocoder.Geocoder) *Config {
+	return &Config{dbClient, storeClient, mailClient, tokenSecret, appDomain, geocoderClient}
 }
@@ -8,13 +8,28 @@ var (
 	errOrganizationIDRequired = errors.New("organization id required")
 	errAccountIDRequired      = errors.New("account id required")
 	errCommunityIDRequired    = errors.New("community id required")
+	errClaimIDRequired        = errors.New("claim id required")
 
-	errJoinCommunity = errors.New("join community")
+	errJoinCommunity             = errors.New("join community")
+	errJoinCommunityNotification = errors.New("join community notification")
 
 	errAccountCreation        = errors.New("account creation")
 	errAccountActivationEmail = errors.New("account activation email")
 
 	errAuthenticationRequired = errors.New("authentication required")
+	errAdministratorRequired  = errors.New("community administrator required")
+	errOperatorRequired       = errors.New("organization operator required")
+
+	errOrganizationNotVerified = errors.New("organization not verified")
+
+	errClaimNotFound     = errors.New("claim not found")
+	errCreateClaim       = errors.New("create claim")
+	errVerifyClaim       = errors.New("verify claim")
+	errClaimNotification = errors.New("claim notification")
+
+	errCredentialInvalid        = errors.New("membership credential invalid")
+	errCheckInCodeFormatInvalid = errors.New("check-in code format must be png or svg")
+	errTimezoneInvalid          = errors.New("timezone must be an IANA time zone, e.g. America/New_York")
 
 	errUploadLogo         = errors.New("upload logo")
 	errUpdateOrganization = errors.New("update organization")
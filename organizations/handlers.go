@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 
 	"github.com/jteppinette/peragrin-api/models"
@@ -28,7 +30,7 @@ func (c *Config) UpdateHandler(r *http.Request) *service.Response {
 	}
 	organization.ID = id
 
-	if err := organization.Update(c.DBClient); err != nil {
+	if err := organization.Update(c.GeocoderClient, c.DBClient); err != nil {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
 	return service.NewResponse(nil, http.StatusOK, organization)
@@ -68,7 +70,7 @@ func (c *Config) UploadLogoHandler(r *http.Request) *service.Response {
 		return service.NewResponse(errUploadLogo, http.StatusBadRequest, nil)
 	}
 
-	if err := organization.Update(c.DBClient); err != nil {
+	if err := organization.Update(c.GeocoderClient, c.DBClient); err != nil {
 		log.WithFields(log.Fields{
 			"logo":           organization.Logo,
 			"organizationID": organization.ID,
@@ -140,9 +142,18 @@ func (c *Config) CreatePromotionHandler(r *http.Request) *service.Response {
 		return service.NewResponse(errors.Wrap(err, errOrganizationIDRequired.Error()), http.StatusBadRequest, nil)
 	}
 
+	// Only verified organizations are allowed to offer promotions.
+	organization, err := models.GetOrganizationByID(organizationID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if !organization.Verified {
+		return service.NewResponse(errOrganizationNotVerified, http.StatusForbidden, map[string]string{"msg": errOrganizationNotVerified.Error()})
+	}
+
 	promotion.OrganizationID = organizationID
 	if err := promotion.Save(c.DBClient); err != nil {
-		return service.NewResponse(err, http.StatusBadRequest, nil)
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
 	}
 	return service.NewResponse(nil, http.StatusCreated, promotion)
 }
@@ -239,14 +250,27 @@ func (c *Config) RemoveAccountHandler(r *http.Request) *service.Response {
 	return service.NewResponse(nil, http.StatusNoContent, nil)
 }
 
-// JoinCommunityHandler creates a relationship between the given
-// organization and community.
+// JoinCommunityHandler requests to join the given community on behalf of the given
+// organization. This requires that the acting account operates the organization. The
+// request is approved immediately when the acting account also administers the community.
+// Otherwise, the request remains pending and the community's administrators are notified.
 func (c *Config) JoinCommunityHandler(r *http.Request) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
 	organizationID, err := strconv.Atoi(mux.Vars(r)["organizationID"])
 	if err != nil {
 		return service.NewResponse(errors.Wrap(err, errOrganizationIDRequired.Error()), http.StatusBadRequest, nil)
 	}
 
+	if ok, err := account.IsOrganizationOperator(organizationID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return service.NewResponse(errOperatorRequired, http.StatusForbidden, nil)
+	}
+
 	communityID, err := strconv.Atoi(mux.Vars(r)["communityID"])
 	if err != nil {
 		return service.NewResponse(errors.Wrap(err, errCommunityIDRequired.Error()), http.StatusBadRequest, nil)
@@ -263,18 +287,54 @@ func (c *Config) JoinCommunityHandler(r *http.Request) *service.Response {
 	co.OrganizationID = organizationID
 
 	// If the acting account is not a super administrator, then they cannot create an administrative relationship.
-	account, ok := context.Get(r, "account").(models.Account)
-	if !ok {
-		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
-	}
 	if !account.IsSuper {
 		co.IsAdministrator = false
 	}
 
-	if co.Create(c.DBClient); err != nil {
+	isAdministrator, err := account.IsCommunityAdministrator(communityID, c.DBClient)
+	if err != nil {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
-	return service.NewResponse(nil, http.StatusOK, nil)
+	if isAdministrator {
+		if err := co.Create(c.DBClient); err == models.ErrJoinRequestExists {
+			return service.NewResponse(err, http.StatusConflict, map[string]string{"msg": err.Error()})
+		} else if err != nil {
+			return service.NewResponse(err, http.StatusBadRequest, nil)
+		}
+		return service.NewResponse(nil, http.StatusOK, co)
+	}
+
+	if err := co.Request(c.DBClient); err == models.ErrJoinRequestExists {
+		return service.NewResponse(err, http.StatusConflict, map[string]string{"msg": err.Error()})
+	} else if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	organization, err := models.GetOrganizationByID(organizationID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	administrators, err := models.GetAdministratorsByCommunity(communityID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				log.WithFields(log.Fields{"error": err, "id": r.Header.Get("X-Request-ID")}).Info(errJoinCommunityNotification.Error())
+			}
+		}()
+		for _, administrator := range administrators {
+			if err := c.MailClient.Send([]string{administrator.Email}, fmt.Sprintf("%s Join Request", organization.Name), fmt.Sprintf("%s has requested to join your community. Review this request at %s/#/communities/%d/join-requests", organization.Name, c.AppDomain, communityID)); err != nil {
+				log.WithFields(log.Fields{
+					"email": administrator.Email, "error": err.Error(), "id": r.Header.Get("X-Request-ID"),
+				}).Info(errJoinCommunityNotification.Error())
+			}
+		}
+	}()
+
+	return service.NewResponse(nil, http.StatusAccepted, co)
 }
 
 // RemoveCommunityHandler deletes a relationship between the given
@@ -312,7 +372,7 @@ func (c *Config) CreateCommunityHandler(r *http.Request) *service.Response {
 	}
 
 	if err := community.CreateWithOrganization(organizationID, c.DBClient); err != nil {
-		return service.NewResponse(err, http.StatusBadRequest, nil)
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
 	}
 	return service.NewResponse(nil, http.StatusCreated, community)
 }
@@ -334,17 +394,199 @@ func (c *Config) ListHoursHandler(r *http.Request) *service.Response {
 }
 
 // ListPromotionsHandler generates a response with the promotions for
-// the requested organization.
+// the requested organization. The remaining redemptions of the authenticated
+// account are included when the request is authenticated, and activeNow=true
+// only returns the promotions that can be redeemed now.
 func (c *Config) ListPromotionsHandler(r *http.Request) *service.Response {
 	organizationID, err := strconv.Atoi(mux.Vars(r)["organizationID"])
 	if err != nil {
 		return service.NewResponse(errors.Wrap(err, errOrganizationIDRequired.Error()), http.StatusBadRequest, nil)
 	}
 
-	promotions, err := models.GetPromotionsByOrganization(organizationID, c.DBClient)
+	account, _ := context.Get(r, "account").(models.Account)
+	promotions, err := models.GetPromotionsByOrganization(organizationID, account.ID, time.Now(), c.DBClient)
 	if err != nil {
 		return service.NewResponse(err, http.StatusBadRequest, nil)
 	}
 
+	if activeNow, _ := strconv.ParseBool(r.URL.Query().Get("activeNow")); activeNow {
+		active := models.Promotions{}
+		for _, promotion := range promotions {
+			if *promotion.Available {
+				active = append(active, promotion)
+			}
+		}
+		promotions = active
+	}
+
 	return service.NewResponse(nil, http.StatusOK, promotions)
 }
+
+// ListClaimsHandler generates a response with all ownership claims for the requested
+// organization. This requires that the requesting account administers a community
+// that the organization is a member of.
+func (c *Config) ListClaimsHandler(r *http.Request) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	organizationID, err := strconv.Atoi(mux.Vars(r)["organizationID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errOrganizationIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	if ok, err := account.IsOrganizationAdministrator(organizationID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return service.NewResponse(errAdministratorRequired, http.StatusForbidden, nil)
+	}
+
+	claims, err := models.GetOrganizationClaimsByOrganization(organizationID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, claims)
+}
+
+// CreateClaimHandler allows the requesting account to request ownership of an organization.
+// Email claims will send a verification code to the organization's email address. Admin claims
+// will notify the administrators of the organization's communities, or the super users when the
+// organization is not a member of a community. Operators of unverified organizations, such as
+// the ones that they created, verify them in the same way.
+func (c *Config) CreateClaimHandler(r *http.Request) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	organizationID, err := strconv.Atoi(mux.Vars(r)["organizationID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errOrganizationIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	claim := models.OrganizationClaim{}
+	if err := json.NewDecoder(r.Body).Decode(&claim); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	claim.AccountID = account.ID
+
+	organization, err := models.GetOrganizationByID(organizationID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	if err := claim.Create(organization, c.DBClient, c.MailClient); err == models.ErrClaimRateLimited {
+		return service.NewResponse(err, http.StatusTooManyRequests, map[string]string{"msg": err.Error()})
+	} else if err != nil {
+		return service.NewResponse(errors.Wrap(err, errCreateClaim.Error()), http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+
+	if claim.Method == models.ClaimMethodAdmin {
+		administrators, err := models.GetAdministratorsByOrganization(organizationID, c.DBClient)
+		if err != nil {
+			return service.NewResponse(err, http.StatusBadRequest, nil)
+		}
+		if len(administrators) == 0 {
+			if administrators, err = models.GetSuperUsers(c.DBClient); err != nil {
+				return service.NewResponse(err, http.StatusBadRequest, nil)
+			}
+		}
+
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					log.WithFields(log.Fields{"error": err, "id": r.Header.Get("X-Request-ID")}).Info(errClaimNotification.Error())
+				}
+			}()
+			for _, administrator := range administrators {
+				if err := c.MailClient.Send([]string{administrator.Email}, fmt.Sprintf("%s Ownership Claim", organization.Name), fmt.Sprintf("%s has requested ownership of %s. Review this request at %s/#/organizations/%d/claims", account.Email, organization.Name, c.AppDomain, organization.ID)); err != nil {
+					log.WithFields(log.Fields{
+						"email": administrator.Email, "error": err.Error(), "id": r.Header.Get("X-Request-ID"),
+					}).Info(errClaimNotification.Error())
+				}
+			}
+		}()
+	}
+
+	return service.NewResponse(nil, http.StatusCreated, claim)
+}
+
+// VerifyClaimHandler approves an email claim if the provided code matches the code that
+// was sent to the organization's email address.
+func (c *Config) VerifyClaimHandler(r *http.Request) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	claim, err := c.getClaim(r)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if claim == nil || claim.AccountID != account.ID {
+		return service.NewResponse(errClaimNotFound, http.StatusNotFound, nil)
+	}
+
+	form := struct {
+		Code string `json:"code"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	if err := claim.Verify(form.Code, c.DBClient); err != nil {
+		return service.NewResponse(errors.Wrap(err, errVerifyClaim.Error()), http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusOK, claim)
+}
+
+// ApproveClaimHandler approves a pending claim. This requires that the requesting account
+// administers a community that the organization is a member of, or is a super user.
+func (c *Config) ApproveClaimHandler(r *http.Request) *service.Response {
+	return c.resolveClaim(r, (*models.OrganizationClaim).Approve)
+}
+
+// RejectClaimHandler rejects a pending claim. This requires that the requesting account
+// administers a community that the organization is a member of.
+func (c *Config) RejectClaimHandler(r *http.Request) *service.Response {
+	return c.resolveClaim(r, (*models.OrganizationClaim).Reject)
+}
+
+func (c *Config) resolveClaim(r *http.Request, resolve func(*models.OrganizationClaim, *sqlx.DB) error) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	claim, err := c.getClaim(r)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if claim == nil {
+		return service.NewResponse(errClaimNotFound, http.StatusNotFound, nil)
+	}
+
+	if ok, err := account.IsOrganizationAdministrator(claim.OrganizationID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return service.NewResponse(errAdministratorRequired, http.StatusForbidden, nil)
+	}
+
+	if err := resolve(claim, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusOK, claim)
+}
+
+func (c *Config) getClaim(r *http.Request) (*models.OrganizationClaim, error) {
+	organizationID, err := strconv.Atoi(mux.Vars(r)["organizationID"])
+	if err != nil {
+		return nil, errors.Wrap(err, errOrganizationIDRequired.Error())
+	}
+	claimID, err := strconv.Atoi(mux.Vars(r)["claimID"])
+	if err != nil {
+		return nil, errors.Wrap(err, errClaimIDRequired.Error())
+	}
+	return models.GetOrganizationClaimByID(organizationID, claimID, c.DBClient)
+}
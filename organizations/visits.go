@@ -0,0 +1,152 @@
+package organizations
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/jteppinette/peragrin-api/models"
+	"github.com/jteppinette/peragrin-api/qrcode"
+	"github.com/jteppinette/peragrin-api/service"
+)
+
+// checkInCodeScale is the size in pixels of the modules of png check-in codes, which are
+// large enough to be printed.
+const checkInCodeScale = 16
+
+// CheckInHandler records a visit of the member whose credential was scanned by an operator
+// of the organization. The response includes the credential so that the operator can see
+// who checked in.
+func (c *Config) CheckInHandler(r *http.Request) *service.Response {
+	account, organizationID, response := c.authorizeOperator(r, false)
+	if response != nil {
+		return response
+	}
+
+	body := struct {
+		Credential string `json:"credential"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	credential, err := models.VerifyMembershipCredential(body.Credential, c.TokenSecret, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if credential == nil {
+		return service.NewResponse(errCredentialInvalid, http.StatusBadRequest, map[string]string{"msg": errCredentialInvalid.Error()})
+	}
+
+	visit, err := models.CheckInCredential(organizationID, credential, account.ID, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusCreated, models.CheckIn{Visit: visit, Credential: credential})
+}
+
+// CheckInCodeHandler returns the png or svg QR code that members scan to check in at the
+// organization. The code links to the check-in page of the app.
+func (c *Config) CheckInCodeHandler(r *http.Request) *service.Response {
+	_, organizationID, response := c.authorizeOperator(r, false)
+	if response != nil {
+		return response
+	}
+
+	format := mux.Vars(r)["format"]
+	header := http.Header{}
+	switch format {
+	case "png":
+		header.Set("Content-Type", "image/png")
+	case "svg":
+		header.Set("Content-Type", "image/svg+xml")
+	default:
+		return service.NewResponse(errCheckInCodeFormatInvalid, http.StatusBadRequest, map[string]string{"msg": errCheckInCodeFormatInvalid.Error()})
+	}
+
+	link := c.AppDomain + "/#/check-in?" + url.Values{"code": {models.OrganizationCheckInCode(organizationID, c.TokenSecret)}}.Encode()
+	code, err := qrcode.Encode([]byte(link))
+	if err != nil {
+		return service.NewResponse(err, http.StatusInternalServerError, nil)
+	}
+	buf := &bytes.Buffer{}
+	if format == "svg" {
+		err = code.SVG(buf)
+	} else {
+		err = code.PNG(buf, checkInCodeScale)
+	}
+	if err != nil {
+		return service.NewResponse(err, http.StatusInternalServerError, nil)
+	}
+	return &service.Response{Code: http.StatusOK, Data: service.Raw(buf.Bytes()), Header: header}
+}
+
+// TrafficHandler returns a report of the organization's visits. The from, to and interval
+// query parameters select the period and how it is divided, and dates are in the time zone
+// of the organization's first community unless the timezone query parameter is provided.
+// Reports are available to the organization's operators and community administrators.
+func (c *Config) TrafficHandler(r *http.Request) *service.Response {
+	_, organizationID, response := c.authorizeOperator(r, true)
+	if response != nil {
+		return response
+	}
+
+	loc := time.UTC
+	if name := r.URL.Query().Get("timezone"); name != "" {
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return service.NewResponse(errTimezoneInvalid, http.StatusBadRequest, map[string]string{"msg": errTimezoneInvalid.Error()})
+		}
+	} else {
+		communities, err := models.GetCommunitiesByOrganization(organizationID, c.DBClient)
+		if err != nil {
+			return service.NewResponse(err, http.StatusBadRequest, nil)
+		}
+		if len(communities) > 0 {
+			loc = communities[0].Location()
+		}
+	}
+
+	query := r.URL.Query()
+	report, err := models.NewTrafficReport(query.Get("from"), query.Get("to"), query.Get("interval"), loc, time.Now())
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	if err := report.LoadOrganization(organizationID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	return service.NewResponse(nil, http.StatusOK, report)
+}
+
+// authorizeOperator returns the authenticated account and the requested organization if
+// the account operates the organization, or when administrators is true, if it administers
+// one of the organization's communities.
+func (c *Config) authorizeOperator(r *http.Request, administrators bool) (models.Account, int, *service.Response) {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return account, 0, service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	organizationID, err := strconv.Atoi(mux.Vars(r)["organizationID"])
+	if err != nil {
+		return account, 0, service.NewResponse(errors.Wrap(err, errOrganizationIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	ok, err = account.IsOrganizationOperator(organizationID, c.DBClient)
+	if err == nil && !ok && administrators {
+		ok, err = account.IsOrganizationAdministrator(organizationID, c.DBClient)
+	}
+	if err != nil {
+		return account, 0, service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return account, 0, service.NewResponse(errOperatorRequired, http.StatusForbidden, nil)
+	}
+	return account, organizationID, nil
+}
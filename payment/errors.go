@@ -0,0 +1,16 @@
+package payment
+
+import (
+	"errors"
+)
+
+var (
+	// ErrSignatureInvalid is returned when a webhook was not signed by the gateway.
+	ErrSignatureInvalid = errors.New("webhook signature invalid")
+	// ErrSignatureExpired is returned when a webhook signature is too old, which
+	// prevents signed events from being replayed.
+	ErrSignatureExpired = errors.New("webhook signature expired")
+
+	errSessionNotFound = errors.New("checkout session not found")
+	errPaymentNotFound = errors.New("payment not found")
+)
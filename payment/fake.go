@@ -0,0 +1,110 @@
+package payment
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Fake is a gateway for tests and local development. Checkout sessions are kept in
+// memory, and their URLs go straight to the success url. Events are the JSON encoding
+// of Event, signed with Secret in the same way as Stripe. When Err is set, it is
+// returned from every call that would reach a real gateway.
+type Fake struct {
+	Secret string
+	Err    error
+
+	mu       sync.Mutex
+	n        int
+	sessions map[string]CheckoutParams
+	payments map[string]Event
+	refunds  map[string]Refund
+}
+
+// NewFake returns a fake gateway that signs its events with the provided secret.
+func NewFake(secret string) *Fake {
+	return &Fake{Secret: secret, sessions: map[string]CheckoutParams{}, payments: map[string]Event{}, refunds: map[string]Refund{}}
+}
+
+// CreateCheckoutSession implements the Gateway interface.
+func (f *Fake) CreateCheckoutSession(params CheckoutParams) (Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return Session{}, f.Err
+	}
+	f.n++
+	id := fmt.Sprintf("cs_fake_%d", f.n)
+	f.sessions[id] = params
+	return Session{ID: id, URL: params.SuccessURL}, nil
+}
+
+// Refund implements the Gateway interface. Refunds with the same key are only made once.
+func (f *Fake) Refund(paymentID string, amount int64, key string) (Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return Refund{}, f.Err
+	}
+	if refund, ok := f.refunds[key]; ok && key != "" {
+		return refund, nil
+	}
+	if _, ok := f.payments[paymentID]; !ok {
+		return Refund{}, errPaymentNotFound
+	}
+	f.n++
+	refund := Refund{ID: fmt.Sprintf("re_fake_%d", f.n), Amount: amount}
+	f.refunds[key] = refund
+	return refund, nil
+}
+
+// ParseEvent implements the Gateway interface.
+func (f *Fake) ParseEvent(payload []byte, header http.Header) (Event, error) {
+	if err := verify(payload, header.Get(SignatureHeader), f.Secret, time.Now()); err != nil {
+		return Event{}, err
+	}
+	event := Event{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
+
+// Complete pays the checkout session and returns the signed webhook that a gateway would send.
+func (f *Fake) Complete(sessionID string) ([]byte, http.Header, error) {
+	f.mu.Lock()
+	params, ok := f.sessions[sessionID]
+	if !ok {
+		f.mu.Unlock()
+		return nil, nil, errSessionNotFound
+	}
+	f.n++
+	event := Event{
+		ID:        fmt.Sprintf("evt_fake_%d", f.n),
+		Type:      EventCheckoutCompleted,
+		SessionID: sessionID,
+		Reference: params.Reference,
+		PaymentID: fmt.Sprintf("pi_fake_%d", f.n),
+		Amount:    params.Amount,
+		Currency:  params.Currency,
+	}
+	f.payments[event.PaymentID] = event
+	f.mu.Unlock()
+
+	return f.webhook(event)
+}
+
+// webhook encodes and signs the event.
+func (f *Fake) webhook(event Event) ([]byte, http.Header, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(SignatureHeader, Sign(payload, f.Secret, time.Now()))
+	return payload, header, nil
+}
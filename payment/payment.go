@@ -0,0 +1,135 @@
+// Package payment charges members through interchangeable payment gateways.
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// EventCheckoutCompleted is sent when a checkout session has been paid.
+	EventCheckoutCompleted = "checkout.completed"
+	// EventCheckoutExpired is sent when a checkout session was abandoned.
+	EventCheckoutExpired = "checkout.expired"
+	// EventRefunded is sent when a payment has been refunded, including refunds
+	// that were made outside of this api.
+	EventRefunded = "payment.refunded"
+
+	// SignatureHeader is the webhook header that carries the event signature.
+	SignatureHeader = "Stripe-Signature"
+
+	// signatureTolerance is how old a webhook signature may be before it is rejected.
+	signatureTolerance = 5 * time.Minute
+)
+
+// CheckoutParams describes a single purchase. Amount is in the smallest unit of the
+// currency, e.g. cents. Reference identifies the purchase to this api, and it is returned
+// with the checkout's events.
+type CheckoutParams struct {
+	Reference   string
+	Amount      int64
+	Currency    string
+	Description string
+	Email       string
+	SuccessURL  string
+	CancelURL   string
+}
+
+// Session is a hosted checkout page that a member is redirected to.
+type Session struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// Refund is money returned to a member for a payment.
+type Refund struct {
+	ID     string `json:"id"`
+	Amount int64  `json:"amount"`
+}
+
+// Event is a webhook notification from a gateway. SessionID and Reference are set for
+// checkout events. PaymentID is the gateway's id of the payment, and Refunds are every
+// refund that the payment has had.
+type Event struct {
+	ID        string   `json:"id"`
+	Type      string   `json:"type"`
+	SessionID string   `json:"sessionID"`
+	Reference string   `json:"reference"`
+	PaymentID string   `json:"paymentID"`
+	Amount    int64    `json:"amount"`
+	Currency  string   `json:"currency"`
+	Refunds   []Refund `json:"refunds"`
+}
+
+// Gateway represents a payment provider that can charge and refund members.
+type Gateway interface {
+	// CreateCheckoutSession creates a hosted checkout page for the purchase.
+	CreateCheckoutSession(params CheckoutParams) (Session, error)
+
+	// Refund returns amount of the payment to the member. The key makes retries of the
+	// same refund safe.
+	Refund(paymentID string, amount int64, key string) (Refund, error)
+
+	// ParseEvent verifies the signature of a webhook and returns its event. Events of
+	// types that are not handled are returned with only their ID and Type.
+	ParseEvent(payload []byte, header http.Header) (Event, error)
+}
+
+// Sign returns a webhook signature header value for the payload at the provided time.
+func Sign(payload []byte, secret string, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, signature(payload, secret, timestamp))
+}
+
+func signature(payload []byte, secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks a webhook signature header. Any of the header's signatures may match,
+// which allows secrets to be rolled.
+func verify(payload []byte, header, secret string, now time.Time) error {
+	if secret == "" {
+		return ErrSignatureInvalid
+	}
+
+	var timestamp string
+	signatures := []string{}
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return ErrSignatureExpired
+	}
+
+	expected := signature(payload, secret, timestamp)
+	for _, s := range signatures {
+		if hmac.Equal([]byte(s), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrSignatureInvalid
+}
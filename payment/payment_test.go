@@ -0,0 +1,120 @@
+package payment
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"id": "evt_1"}`)
+	now := time.Unix(1509148800, 0)
+
+	tests := []struct {
+		header   string
+		expected error
+	}{
+		{Sign(payload, "secret", now), nil},
+		{"t=1509148800,v1=bad,v1=" + signature(payload, "secret", "1509148800"), nil},
+		{Sign(payload, "other", now), ErrSignatureInvalid},
+		{Sign(payload, "secret", now.Add(-10*time.Minute)), ErrSignatureExpired},
+		{"v1=abc", ErrSignatureInvalid},
+		{"", ErrSignatureInvalid},
+	}
+	for _, test := range tests {
+		if err := verify(payload, test.header, "secret", now); err != test.expected {
+			t.Errorf("expected %v for %q, got %v", test.expected, test.header, err)
+		}
+	}
+	if err := verify([]byte(`{"id": "evt_2"}`), Sign(payload, "secret", now), "secret", now); err != ErrSignatureInvalid {
+		t.Errorf("expected tampered payload to be invalid, got %v", err)
+	}
+}
+
+func TestStripe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, _, _ := r.BasicAuth(); key != "sk_test" {
+			t.Errorf("unexpected key: %q", key)
+		}
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/checkout/sessions":
+			if r.Form.Get("client_reference_id") != "7" || r.Form.Get("line_items[0][price_data][unit_amount]") != "2500" {
+				t.Errorf("unexpected checkout: %v", r.Form)
+			}
+			w.Write([]byte(`{"id": "cs_1", "url": "https://checkout.stripe.com/cs_1"}`))
+		case "/refunds":
+			if r.Header.Get("Idempotency-Key") != "refund-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": {"message": "no such payment_intent"}}`))
+				return
+			}
+			w.Write([]byte(`{"id": "re_1", "amount": 500}`))
+		}
+	}))
+	defer server.Close()
+
+	s := NewStripe("sk_test", "whsec", time.Second)
+	s.URL = server.URL
+
+	session, err := s.CreateCheckoutSession(CheckoutParams{Reference: "7", Amount: 2500, Currency: "usd", Description: "Annual"})
+	if err != nil || session != (Session{ID: "cs_1", URL: "https://checkout.stripe.com/cs_1"}) {
+		t.Errorf("unexpected session: %+v, %v", session, err)
+	}
+	if refund, err := s.Refund("pi_1", 500, "refund-1"); err != nil || refund != (Refund{ID: "re_1", Amount: 500}) {
+		t.Errorf("unexpected refund: %+v, %v", refund, err)
+	}
+	if _, err := s.Refund("pi_1", 500, ""); err == nil || err.Error() != "stripe: no such payment_intent" {
+		t.Errorf("expected stripe error, got %v", err)
+	}
+
+	payload := []byte(`{"id": "evt_1", "type": "checkout.session.completed", "data": {"object": {
+		"id": "cs_1", "client_reference_id": "7", "payment_intent": "pi_1", "payment_status": "paid", "amount_total": 2500, "currency": "usd"
+	}}}`)
+	header := http.Header{}
+	header.Set(SignatureHeader, Sign(payload, "whsec", time.Now()))
+	event, err := s.ParseEvent(payload, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != EventCheckoutCompleted || event.SessionID != "cs_1" || event.Reference != "7" || event.PaymentID != "pi_1" || event.Amount != 2500 {
+		t.Errorf("unexpected event: %+v", event)
+	}
+
+	header.Set(SignatureHeader, Sign(payload, "other", time.Now()))
+	if _, err := s.ParseEvent(payload, header); err != ErrSignatureInvalid {
+		t.Errorf("expected %v, got %v", ErrSignatureInvalid, err)
+	}
+}
+
+func TestFake(t *testing.T) {
+	f := NewFake("secret")
+	session, err := f.CreateCheckoutSession(CheckoutParams{Reference: "1", Amount: 1000, Currency: "usd", SuccessURL: "http://app/success"})
+	if err != nil || session.URL != "http://app/success" {
+		t.Fatalf("unexpected session: %+v, %v", session, err)
+	}
+
+	payload, header, err := f.Complete(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err := f.ParseEvent(payload, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != EventCheckoutCompleted || event.SessionID != session.ID || event.Reference != "1" || event.Amount != 1000 {
+		t.Errorf("unexpected event: %+v", event)
+	}
+
+	a, err := f.Refund(event.PaymentID, 400, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := f.Refund(event.PaymentID, 400, "key"); a != b {
+		t.Errorf("expected retried refund to be %+v, got %+v", a, b)
+	}
+	if _, err := f.Refund("unknown", 400, "other"); err != errPaymentNotFound {
+		t.Errorf("expected %v, got %v", errPaymentNotFound, err)
+	}
+}
@@ -0,0 +1,155 @@
+package payment
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StripeURL is the Stripe api endpoint.
+const StripeURL = "https://api.stripe.com/v1"
+
+// Stripe charges members with Stripe Checkout. WebhookSecret is the signing secret
+// of the webhook endpoint that Stripe sends events to.
+type Stripe struct {
+	URL           string
+	Key           string
+	WebhookSecret string
+	Client        *http.Client
+}
+
+// NewStripe returns a gateway that uses the Stripe api with the provided secret key.
+// Requests fail after the timeout.
+func NewStripe(key, webhookSecret string, timeout time.Duration) *Stripe {
+	return &Stripe{URL: StripeURL, Key: key, WebhookSecret: webhookSecret, Client: &http.Client{Timeout: timeout}}
+}
+
+// CreateCheckoutSession implements the Gateway interface.
+func (s *Stripe) CreateCheckoutSession(params CheckoutParams) (Session, error) {
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("success_url", params.SuccessURL)
+	form.Set("cancel_url", params.CancelURL)
+	form.Set("client_reference_id", params.Reference)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", params.Currency)
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(params.Amount, 10))
+	form.Set("line_items[0][price_data][product_data][name]", params.Description)
+	if params.Email != "" {
+		form.Set("customer_email", params.Email)
+	}
+
+	session := Session{}
+	if err := s.post("/checkout/sessions", form, "", &session); err != nil {
+		return Session{}, err
+	}
+	return session, nil
+}
+
+// Refund implements the Gateway interface. Payment ids are Stripe payment intents.
+func (s *Stripe) Refund(paymentID string, amount int64, key string) (Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", paymentID)
+	form.Set("amount", strconv.FormatInt(amount, 10))
+
+	refund := Refund{}
+	if err := s.post("/refunds", form, key, &refund); err != nil {
+		return Refund{}, err
+	}
+	return refund, nil
+}
+
+// stripeEvent is the subset of a Stripe event that is handled. The object is a checkout
+// session for checkout events and a charge for refund events.
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object struct {
+			ID                string `json:"id"`
+			ClientReferenceID string `json:"client_reference_id"`
+			PaymentIntent     string `json:"payment_intent"`
+			PaymentStatus     string `json:"payment_status"`
+			AmountTotal       int64  `json:"amount_total"`
+			Amount            int64  `json:"amount"`
+			Currency          string `json:"currency"`
+			Refunds           struct {
+				Data []Refund `json:"data"`
+			} `json:"refunds"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+// ParseEvent implements the Gateway interface. Checkout sessions that complete without
+// being paid, e.g. with a delayed payment method, are not treated as completed.
+func (s *Stripe) ParseEvent(payload []byte, header http.Header) (Event, error) {
+	if err := verify(payload, header.Get(SignatureHeader), s.WebhookSecret, time.Now()); err != nil {
+		return Event{}, err
+	}
+
+	e := stripeEvent{}
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return Event{}, err
+	}
+	o := e.Data.Object
+
+	event := Event{ID: e.ID}
+	switch e.Type {
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+		if o.PaymentStatus != "paid" {
+			event.Type = e.Type
+			return event, nil
+		}
+		event.Type, event.Amount = EventCheckoutCompleted, o.AmountTotal
+	case "checkout.session.expired", "checkout.session.async_payment_failed":
+		event.Type = EventCheckoutExpired
+	case "charge.refunded":
+		event.Type, event.Amount, event.Refunds = EventRefunded, o.Amount, o.Refunds.Data
+		event.PaymentID, event.Currency = o.PaymentIntent, o.Currency
+		return event, nil
+	default:
+		event.Type = e.Type
+		return event, nil
+	}
+	event.SessionID, event.Reference, event.PaymentID, event.Currency = o.ID, o.ClientReferenceID, o.PaymentIntent, o.Currency
+	return event, nil
+}
+
+// post sends the form to the path of the api and decodes the JSON response into v.
+func (s *Stripe) post(path string, form url.Values, key string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(s.URL, "/")+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.Key, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+
+	r, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		failure := struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&failure); err == nil && failure.Error.Message != "" {
+			return fmt.Errorf("stripe: %s", failure.Error.Message)
+		}
+		return fmt.Errorf("expected response to be HTTP 200, received %s", r.Status)
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response: %s", err.Error())
+	}
+	return nil
+}
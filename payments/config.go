@@ -0,0 +1,25 @@
+package payments
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jteppinette/peragrin-api/mail"
+	"github.com/jteppinette/peragrin-api/payment"
+)
+
+// Config represents the configuration objects necessary to
+// use the objects in this package.
+type Config struct {
+	DBClient    *sqlx.DB
+	MailClient  *mail.Config
+	TokenSecret string
+	AppDomain   string
+
+	PaymentGateway payment.Gateway
+}
+
+// Init returns a configuration struct that can be used to initialize
+// the objects in this package.
+func Init(dbClient *sqlx.DB, mailClient *mail.Config, tokenSecret, appDomain string, paymentGateway payment.Gateway) *Config {
+	return &Config{dbClient, mailClient, tokenSecret, appDomain, paymentGateway}
+}
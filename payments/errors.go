@@ -0,0 +1,16 @@
+package payments
+
+import (
+	"errors"
+)
+
+var (
+	errPaymentIDRequired = errors.New("payment id required")
+	errPaymentNotFound   = errors.New("payment not found")
+
+	errAuthenticationRequired = errors.New("authentication required")
+	errAdministratorRequired  = errors.New("community administrator required")
+
+	errWebhookTooLarge        = errors.New("webhook too large")
+	errAccountActivationEmail = errors.New("account activation email")
+)
@@ -0,0 +1,118 @@
+package payments
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/jteppinette/peragrin-api/models"
+	"github.com/jteppinette/peragrin-api/payment"
+	"github.com/jteppinette/peragrin-api/service"
+)
+
+// maxWebhookSize is the largest webhook body, in bytes, that is accepted.
+const maxWebhookSize = 1 << 16
+
+// WebhookHandler handles the signed events of the payment gateway. Members join their
+// membership when their checkout is completed, and refunds that were made through the
+// gateway are added to the ledger. Events are safe to deliver more than once. Failures
+// respond with a server error so that the gateway retries the event.
+func (c *Config) WebhookHandler(r *http.Request) *service.Response {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookSize+1))
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if len(body) > maxWebhookSize {
+		return service.NewResponse(errWebhookTooLarge, http.StatusRequestEntityTooLarge, nil)
+	}
+
+	event, err := c.PaymentGateway.ParseEvent(body, r.Header)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+
+	switch event.Type {
+	case payment.EventCheckoutCompleted:
+		session := models.CheckoutSession{}
+		account, created, err := session.Complete(event, c.DBClient)
+		if err != nil {
+			return service.NewResponse(err, http.StatusInternalServerError, nil)
+		}
+		if created {
+			c.sendActivationEmail(r, *account, session.CommunityID)
+		}
+	case payment.EventCheckoutExpired:
+		if err := models.ExpireCheckout(event, c.DBClient); err != nil {
+			return service.NewResponse(err, http.StatusInternalServerError, nil)
+		}
+	case payment.EventRefunded:
+		if err := models.RecordRefunds(event, c.DBClient); err != nil {
+			return service.NewResponse(err, http.StatusInternalServerError, nil)
+		}
+	}
+	return service.NewResponse(nil, http.StatusOK, nil)
+}
+
+// RefundHandler refunds a payment. The amount is in the smallest unit of the payment's
+// currency, and everything that has not been refunded yet is refunded when it is omitted.
+// The member's account membership is removed when revokeMembership is true.
+func (c *Config) RefundHandler(r *http.Request) *service.Response {
+	account, ok := context.Get(r, "account").(models.Account)
+	if !ok {
+		return service.NewResponse(errAuthenticationRequired, http.StatusUnauthorized, nil)
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["paymentID"])
+	if err != nil {
+		return service.NewResponse(errors.Wrap(err, errPaymentIDRequired.Error()), http.StatusBadRequest, nil)
+	}
+
+	body := struct {
+		Amount           int64 `json:"amount"`
+		RevokeMembership bool  `json:"revokeMembership"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+
+	p, err := models.GetPaymentByID(id, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	}
+	if p == nil {
+		return service.NewResponse(errPaymentNotFound, http.StatusNotFound, map[string]string{"msg": errPaymentNotFound.Error()})
+	}
+
+	if ok, err := account.IsCommunityAdministrator(p.CommunityID, c.DBClient); err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, nil)
+	} else if !ok {
+		return service.NewResponse(errAdministratorRequired, http.StatusForbidden, nil)
+	}
+
+	refund, err := p.Refund(body.Amount, body.RevokeMembership, c.PaymentGateway, c.DBClient)
+	if err != nil {
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
+	}
+	return service.NewResponse(nil, http.StatusCreated, refund)
+}
+
+// sendActivationEmail invites an account that was created by a checkout to set its password.
+func (c *Config) sendActivationEmail(r *http.Request, account models.Account, communityID int) {
+	community, err := models.GetCommunityByID(communityID, c.DBClient)
+	if err == nil {
+		err = account.SendActivationEmail(community.MapPath(), c.AppDomain, c.TokenSecret, fmt.Sprintf("%s Membership", community.Name), c.MailClient)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"email": account.Email, "error": err.Error(), "id": r.Header.Get("X-Request-ID"),
+		}).Info(errAccountActivationEmail.Error())
+	}
+}
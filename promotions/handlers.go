@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
@@ -27,7 +28,7 @@ func (c *Config) UpdateHandler(r *http.Request) *service.Response {
 	promotion.ID = id
 
 	if err := promotion.Save(c.Client); err != nil {
-		return service.NewResponse(err, http.StatusBadRequest, nil)
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
 	}
 	return service.NewResponse(nil, http.StatusOK, promotion)
 }
@@ -46,7 +47,8 @@ func (c *Config) DeleteHandler(r *http.Request) *service.Response {
 }
 
 // RedeemHandler creates a account promotion relationship. This represents
-// an account redeeming a promotion.
+// an account redeeming a promotion. Redemptions outside of the promotion's
+// schedule or that would exceed one of its limits are rejected with a conflict.
 func (c *Config) RedeemHandler(r *http.Request) *service.Response {
 	promotionID, err := strconv.Atoi(mux.Vars(r)["promotionID"])
 	if err != nil {
@@ -69,8 +71,13 @@ func (c *Config) RedeemHandler(r *http.Request) *service.Response {
 		return service.NewResponse(errPromotionMembershipRequirementNotMet, http.StatusForbidden, map[string]string{"msg": errPromotionMembershipRequirementNotMet.Error()})
 	}
 
-	if err := redemption.Create(c.Client); err != nil {
-		return service.NewResponse(err, http.StatusBadRequest, nil)
+	if err := redemption.Redeem(time.Now(), c.Client); err != nil {
+		switch err {
+		case models.ErrPromotionRedeemed, models.ErrPromotionAccountLimit, models.ErrPromotionSoldOut, models.ErrPromotionDailyLimit,
+			models.ErrPromotionNotStarted, models.ErrPromotionExpired, models.ErrPromotionBlackedOut, models.ErrPromotionOutsideWindow:
+			return service.NewResponse(err, http.StatusConflict, map[string]string{"msg": err.Error()})
+		}
+		return service.NewResponse(err, http.StatusBadRequest, map[string]string{"msg": err.Error()})
 	}
 
 	return service.NewResponse(nil, http.StatusOK, redemption)
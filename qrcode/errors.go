@@ -0,0 +1,10 @@
+package qrcode
+
+import (
+	"errors"
+)
+
+var (
+	// ErrDataTooLong is returned when the data does not fit in the largest QR code.
+	ErrDataTooLong = errors.New("data too long for a qr code")
+)
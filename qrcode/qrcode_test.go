@@ -0,0 +1,215 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// The data codewords of HELLO WORLD as a 1-M code.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expected := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if actual := reedSolomonRemainder(data, reedSolomonDivisor(10)); !bytes.Equal(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	if actual := formatBits(0); actual != 0x5412 {
+		t.Errorf("expected 101010000010010, got %015b", actual)
+	}
+	if actual := formatBits(5); actual != 0x40CE {
+		t.Errorf("expected 100000011001110, got %015b", actual)
+	}
+	if actual := versionBits(7); actual != 0x07C94 {
+		t.Errorf("expected 000111110010010100, got %018b", actual)
+	}
+}
+
+func TestAlignmentPatternPositions(t *testing.T) {
+	tests := map[int][]int{1: nil, 2: {6, 18}, 7: {6, 22, 38}, 32: {6, 34, 60, 86, 112, 138}, 40: {6, 30, 58, 86, 114, 142, 170}}
+	for version, expected := range tests {
+		actual := alignmentPatternPositions(version)
+		if len(actual) != len(expected) {
+			t.Errorf("expected %v for version %d, got %v", expected, version, actual)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != expected[i] {
+				t.Errorf("expected %v for version %d, got %v", expected, version, actual)
+				break
+			}
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		data    string
+		version int
+	}{
+		{"peragrin", 1},
+		{"0123456789abcdef0123456789abcdef.0123456789abcdef0123456789abcdef0123456789a", 5},
+		{strings.Repeat("membership", 30), 13},
+		{strings.Repeat("x", 2331), 40},
+	}
+	for _, test := range tests {
+		c, err := Encode([]byte(test.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Version != test.version || c.Size != test.version*4+17 {
+			t.Errorf("expected version %d, got %d with size %d", test.version, c.Version, c.Size)
+		}
+		if actual := decode(t, c); actual != test.data {
+			t.Errorf("expected %q, got %q", test.data, actual)
+		}
+	}
+
+	if _, err := Encode(make([]byte, 2332)); err != ErrDataTooLong {
+		t.Errorf("expected %v, got %v", ErrDataTooLong, err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	c, err := Encode([]byte("peragrin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := c.PNG(&b, 2); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := (21 + QuietZone*2) * 2; img.Bounds().Dx() != size || img.Bounds().Dy() != size {
+		t.Errorf("expected %dpx image, got %v", size, img.Bounds())
+	}
+	if r, _, _, _ := img.At(QuietZone*2, QuietZone*2).RGBA(); r != 0 {
+		t.Error("expected the finder pattern corner to be dark")
+	}
+
+	b.Reset()
+	if err := c.SVG(&b); err != nil {
+		t.Fatal(err)
+	}
+	if svg := b.String(); !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, `viewBox="0 0 29 29"`) || !strings.Contains(svg, "M4 4h1v1h-1z") {
+		t.Errorf("unexpected svg: %s", svg)
+	}
+}
+
+// decode reads the data of a code back from its modules, checking its format information
+// and error correction along the way.
+func decode(t *testing.T, c *Code) string {
+	var first, second int
+	for i := 0; i <= 5; i++ {
+		first |= bit(c.modules[i][8]) << uint(i)
+	}
+	first |= bit(c.modules[7][8])<<6 | bit(c.modules[8][8])<<7 | bit(c.modules[8][7])<<8
+	for i := 9; i < 15; i++ {
+		first |= bit(c.modules[8][14-i]) << uint(i)
+	}
+	for i := 0; i < 8; i++ {
+		second |= bit(c.modules[8][c.Size-1-i]) << uint(i)
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(c.modules[c.Size-15+i][8]) << uint(i)
+	}
+	if first != formatBits(c.Mask) || second != first {
+		t.Fatalf("expected format bits %015b, got %015b and %015b", formatBits(c.Mask), first, second)
+	}
+
+	c.applyMask(c.Mask)
+	defer c.applyMask(c.Mask)
+
+	raw := make([]byte, numRawDataModules(c.Version)/8)
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if !c.isFunction[y][x] && i < len(raw)*8 {
+					raw[i>>3] |= byte(bit(c.modules[y][x])) << uint(7-i&7)
+					i++
+				}
+			}
+		}
+	}
+
+	numBlocks, eccLen := numEccBlocks[c.Version], eccCodewordsPerBlock[c.Version]
+	numShortBlocks, shortBlockLen := numBlocks-len(raw)%numBlocks, len(raw)/numBlocks
+	blocks := make([][]byte, numBlocks)
+	for j := range blocks {
+		blocks[j] = make([]byte, shortBlockLen+1)
+	}
+	k := 0
+	for i := 0; i <= shortBlockLen; i++ {
+		for j := range blocks {
+			if i != shortBlockLen-eccLen || j >= numShortBlocks {
+				blocks[j][i] = raw[k]
+				k++
+			}
+		}
+	}
+
+	data := []byte{}
+	for j, block := range blocks {
+		n := shortBlockLen - eccLen
+		if j >= numShortBlocks {
+			n++
+		}
+		codeword := append(append([]byte{}, block[:n]...), block[shortBlockLen+1-eccLen:]...)
+		// Every root of the generator is a root of a valid codeword.
+		root := byte(1)
+		for r := 0; r < eccLen; r++ {
+			var value byte
+			for _, b := range codeword {
+				value = gfMultiply(value, root) ^ b
+			}
+			if value != 0 {
+				t.Fatalf("block %d has a non-zero syndrome", j)
+			}
+			root = gfMultiply(root, 0x02)
+		}
+		data = append(data, block[:n]...)
+	}
+
+	bits := bitBuffer{}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	read := func(n int) int {
+		v := 0
+		for _, b := range bits[:n] {
+			v = v<<1 | bit(b)
+		}
+		bits = bits[n:]
+		return v
+	}
+	if mode := read(4); mode != 0x4 {
+		t.Fatalf("expected byte mode, got %04b", mode)
+	}
+	result := make([]byte, read(characterCountBits(c.Version)))
+	for i := range result {
+		result[i] = byte(read(8))
+	}
+	return string(result)
+}
+
+func bit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
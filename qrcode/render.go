@@ -0,0 +1,51 @@
+package qrcode
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// QuietZone is the width, in modules, of the light border that readers need around a code.
+const QuietZone = 4
+
+// PNG writes the code as a black and white PNG image in which every module is scale pixels wide.
+func (c *Code) PNG(w io.Writer, scale int) error {
+	if scale < 1 {
+		scale = 1
+	}
+	size := (c.Size + QuietZone*2) * scale
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if c.Dark(x/scale-QuietZone, y/scale-QuietZone) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// SVG writes the code as an SVG image that scales to any size. Every module is one unit of
+// the view box, and the dark modules are a single path.
+func (c *Code) SVG(w io.Writer) error {
+	size := c.Size + QuietZone*2
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="`, size, size); err != nil {
+		return err
+	}
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Dark(x, y) {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "M%d %dh1v1h-1z", x+QuietZone, y+QuietZone); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.WriteString(w, `"/></svg>`)
+	return err
+}
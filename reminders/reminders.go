@@ -0,0 +1,84 @@
+// Package reminders emails members before and when their memberships expire.
+package reminders
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/jteppinette/peragrin-api/models"
+)
+
+// Run sends the reminders that are due every interval until stop is closed. Several
+// instances of the api may run reminders at the same time, because every reminder is
+// claimed before it is sent.
+func (c *Config) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if sent, err := c.Send(time.Now()); err != nil {
+			log.WithFields(log.Fields{"error": err.Error(), "sent": sent}).Error("membership reminders")
+		} else if sent > 0 {
+			log.WithFields(log.Fields{"sent": sent}).Info("membership reminders")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Send emails every reminder that is due at now, and it returns how many were sent.
+// Reminders that fail to send are released so that they are retried on the next run,
+// and the first failure is returned once every reminder has been attempted.
+func (c *Config) Send(now time.Time) (int, error) {
+	due, err := models.GetDueMembershipReminders(now, c.DBClient)
+	if err != nil {
+		return 0, err
+	}
+
+	var sent int
+	var failure error
+	for _, r := range due {
+		ok, err := c.send(r, now)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"email": r.Email, "error": err.Error(), "membershipID": r.MembershipID, "kind": r.Kind,
+			}).Info("membership reminder")
+			if failure == nil {
+				failure = err
+			}
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, failure
+}
+
+// send claims and emails a single reminder. It returns false without an error when the
+// reminder was claimed by another instance.
+func (c *Config) send(r models.MembershipReminder, now time.Time) (bool, error) {
+	t, err := models.GetReminderTemplate(r.CommunityID, r.Kind, c.DBClient)
+	if err != nil {
+		return false, err
+	}
+
+	claimed, err := r.Claim(c.DBClient)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	if err := c.MailClient.SendTemplate([]string{r.Email}, t.Template, r.Data(c.AppDomain, now)); err != nil {
+		if releaseErr := r.Release(c.DBClient); releaseErr != nil {
+			return false, fmt.Errorf("%s: %s", err.Error(), releaseErr.Error())
+		}
+		return false, err
+	}
+	return true, nil
+}
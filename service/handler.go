@@ -78,6 +78,12 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		log.WithFields(fields).Info("access log")
 	}
 
+	if response != nil {
+		for key, values := range response.Header {
+			w.Header()[key] = values
+		}
+	}
+
 	// If the response or response data is nil, then write the calculated code
 	// as a default empty text/html response.
 	if response == nil || response.Data == nil {
@@ -85,5 +91,11 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if raw, ok := response.Data.(Raw); ok {
+		w.WriteHeader(code)
+		w.Write(raw)
+		return
+	}
+
 	rend(w, code, response.Data)
 }
@@ -30,6 +30,13 @@ func TestHandler(t *testing.T) {
 			http.StatusOK,
 			nil,
 		},
+		{
+			func(r *http.Request) *Response {
+				return NewResponse(nil, http.StatusOK, Raw("<svg/>"))
+			},
+			http.StatusOK,
+			[]byte("<svg/>"),
+		},
 		{
 			func(r *http.Request) *Response {
 				return nil
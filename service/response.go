@@ -1,5 +1,9 @@
 package service
 
+import (
+	"net/http"
+)
+
 // Response represents the return value to the Handler type.
 // This struct encapsulates the information need to log and write
 // at the end of a request/response cycle.
@@ -7,9 +11,16 @@ type Response struct {
 	Error error
 	Code  int
 	Data  interface{}
+
+	// Header is written before the response data when it is not nil.
+	Header http.Header
 }
 
+// Raw is response data that is written as is instead of being encoded as JSON, e.g. an
+// image. The response's Content-Type header should be set.
+type Raw []byte
+
 // NewResponse returns an initialized response pointer.
 func NewResponse(err error, code int, data interface{}) *Response {
-	return &Response{err, code, data}
+	return &Response{Error: err, Code: code, Data: data}
 }
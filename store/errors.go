@@ -0,0 +1,12 @@
+package store
+
+import (
+	"errors"
+)
+
+var (
+	errNotFound         = errors.New("not found")
+	errInvalidKey       = errors.New("invalid key")
+	errInvalidSignature = errors.New("invalid signature")
+	errExpired          = errors.New("expired")
+)
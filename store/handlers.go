@@ -0,0 +1,44 @@
+package store
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+)
+
+// DownloadHandler serves the contents of the requested key to clients that
+// provide a valid signature generated by Presign. This is a raw http handler,
+// because the file contents should not be encoded as JSON.
+func (l *Local) DownloadHandler(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	values := r.URL.Query()
+
+	if err := l.verify(key, values.Get("expires"), values.Get("signature")); err != nil {
+		log.WithFields(log.Fields{"key": key, "error": err.Error(), "id": r.Header.Get("X-Request-ID")}).Info("store download")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	reader, err := l.Get(key)
+	if err == errNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.WithFields(log.Fields{"key": key, "error": err.Error(), "id": r.Header.Get("X-Request-ID")}).Error("store download")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer reader.Close()
+
+	// Files on disk are seekable, which allows content type detection and range requests.
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, path.Base(key), time.Time{}, seeker)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	io.Copy(w, reader)
+}
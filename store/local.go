@@ -0,0 +1,134 @@
+package store
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local is a Store that is backed by a directory on the local filesystem.
+// Presigned urls point back at the api, and they are served by the
+// DownloadHandler after their signature has been verified.
+type Local struct {
+	Directory string
+	URL       string
+	Secret    string
+}
+
+// NewLocal returns an initialized Local store. The provided directory will be
+// created if it does not already exist.
+func NewLocal(directory, url, secret string) (*Local, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	return &Local{directory, strings.TrimRight(url, "/"), secret}, nil
+}
+
+// path converts the provided key into a path inside of the store's directory.
+// Keys that attempt to escape the directory are rejected.
+func (l *Local) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || cleaned != "/"+key {
+		return "", errInvalidKey
+	}
+	return filepath.Join(l.Directory, filepath.FromSlash(cleaned)), nil
+}
+
+// Put satisfies the Store interface.
+func (l *Local) Put(key string, reader io.Reader, contentType string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that readers never observe a partial file.
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".upload-")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Get satisfies the Store interface.
+func (l *Local) Get(key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, errNotFound
+	}
+	return f, err
+}
+
+// Delete satisfies the Store interface.
+func (l *Local) Delete(key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Presign satisfies the Store interface.
+func (l *Local) Presign(key string, expiration time.Duration) (string, error) {
+	if _, err := l.path(key); err != nil {
+		return "", err
+	}
+	expires := time.Now().Add(expiration).Unix()
+	values := url.Values{}
+	values.Set("expires", strconv.FormatInt(expires, 10))
+	values.Set("signature", l.sign(key, expires))
+	return fmt.Sprintf("%s/%s?%s", l.URL, (&url.URL{Path: key}).EscapedPath(), values.Encode()), nil
+}
+
+// sign generates the hex encoded signature for the provided key and expiration.
+func (l *Local) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(l.Secret))
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks that the provided signature is valid for the key and that
+// it has not yet expired.
+func (l *Local) verify(key, expires, signature string) error {
+	e, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(l.sign(key, e))) {
+		return errInvalidSignature
+	}
+	if time.Now().Unix() > e {
+		return errExpired
+	}
+	return nil
+}
+
+var _ Store = &Local{}
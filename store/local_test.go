@@ -0,0 +1,98 @@
+package store
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewLocal(dir, "http://localhost/store", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Put("logos/1-logo.png", bytes.NewBufferString("logo"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := l.Get("logos/1-logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	if string(b) != "logo" {
+		t.Errorf("expected contents to be logo, got %s", b)
+	}
+
+	for _, key := range []string{"../escape", "logos/../../escape", "/absolute", ""} {
+		if err := l.Put(key, bytes.NewBufferString(""), ""); err != errInvalidKey {
+			t.Errorf("expected key %q to be rejected, got %v", key, err)
+		}
+	}
+
+	if err := l.Delete("logos/1-logo.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get("logos/1-logo.png"); err != errNotFound {
+		t.Errorf("expected deleted key to be not found, got %v", err)
+	}
+	if err := l.Delete("logos/1-logo.png"); err != nil {
+		t.Errorf("expected deleting a missing key to succeed, got %v", err)
+	}
+}
+
+func TestLocalDownloadHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewLocal(dir, "http://localhost/store", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Put("logos/1-logo.png", bytes.NewBufferString("logo"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/store/{key:.+}", l.DownloadHandler)
+
+	valid, _ := l.Presign("logos/1-logo.png", time.Hour)
+	expired, _ := l.Presign("logos/1-logo.png", -time.Hour)
+	missing, _ := l.Presign("logos/2-logo.png", time.Hour)
+
+	tests := []struct {
+		url  string
+		code int
+	}{
+		{valid, http.StatusOK},
+		{expired, http.StatusForbidden},
+		{missing, http.StatusNotFound},
+		{strings.Replace(valid, "1-logo", "2-logo", 1), http.StatusForbidden},
+		{"http://localhost/store/logos/1-logo.png", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+		if w.Code != test.code {
+			t.Errorf("expected code for %s to be %d, got %d", test.url, test.code, w.Code)
+		}
+	}
+}
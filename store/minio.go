@@ -0,0 +1,74 @@
+package store
+
+import (
+	"io"
+	"time"
+
+	minio "github.com/minio/minio-go"
+)
+
+// Minio is a Store that is backed by a minio (or any s3 compatible) server.
+type Minio struct {
+	Client *minio.Client
+	Bucket string
+}
+
+// NewMinio returns an initialized Minio store. The provided bucket will be
+// created if it does not already exist.
+func NewMinio(endpoint, accessKey, secretKey string, secure bool, bucket string) (*Minio, error) {
+	client, err := minio.New(endpoint, accessKey, secretKey, secure)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(bucket, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Minio{client, bucket}, nil
+}
+
+// Put satisfies the Store interface.
+func (m *Minio) Put(key string, reader io.Reader, contentType string) error {
+	_, err := m.Client.PutObject(m.Bucket, key, reader, contentType)
+	return err
+}
+
+// Get satisfies the Store interface.
+func (m *Minio) Get(key string) (io.ReadCloser, error) {
+	object, err := m.Client.GetObject(m.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	// The object is lazily requested, so stat it to surface missing keys now.
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, errNotFound
+		}
+		return nil, err
+	}
+	return object, nil
+}
+
+// Delete satisfies the Store interface.
+func (m *Minio) Delete(key string) error {
+	return m.Client.RemoveObject(m.Bucket, key)
+}
+
+// Presign satisfies the Store interface.
+func (m *Minio) Presign(key string, expiration time.Duration) (string, error) {
+	url, err := m.Client.PresignedGetObject(m.Bucket, key, expiration, nil)
+	if err != nil {
+		return "", err
+	}
+	return url.String(), nil
+}
+
+var _ Store = &Minio{}
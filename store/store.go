@@ -0,0 +1,25 @@
+package store
+
+import (
+	"io"
+	"time"
+)
+
+// Store represents a blob store that can be used to persist and retrieve
+// uploaded files such as organization logos.
+type Store interface {
+	// Put writes the contents of the provided reader to the given key.
+	Put(key string, reader io.Reader, contentType string) error
+
+	// Get returns a reader for the contents of the given key. The caller
+	// is responsible for closing the returned reader.
+	Get(key string) (io.ReadCloser, error)
+
+	// Delete removes the given key from the store. Deleting a key that
+	// does not exist is not considered an error.
+	Delete(key string) error
+
+	// Presign returns a url that can be used by an unauthenticated client
+	// to download the given key until the expiration has elapsed.
+	Presign(key string, expiration time.Duration) (string, error)
+}
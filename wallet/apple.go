@@ -0,0 +1,205 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"time"
+)
+
+// Apple signs membership cards as Apple Wallet passes. The certificate is the pass type ID
+// certificate of the pass type, and Intermediates are the Apple Worldwide Developer
+// Relations certificates that it was issued by.
+type Apple struct {
+	PassTypeID    string
+	TeamID        string
+	Certificate   *x509.Certificate
+	Key           *rsa.PrivateKey
+	Intermediates []*x509.Certificate
+}
+
+// applePass is the pass.json of an Apple Wallet pass.
+type applePass struct {
+	FormatVersion      int           `json:"formatVersion"`
+	PassTypeIdentifier string        `json:"passTypeIdentifier"`
+	TeamIdentifier     string        `json:"teamIdentifier"`
+	SerialNumber       string        `json:"serialNumber"`
+	OrganizationName   string        `json:"organizationName"`
+	Description        string        `json:"description"`
+	LogoText           string        `json:"logoText"`
+	BackgroundColor    string        `json:"backgroundColor"`
+	ForegroundColor    string        `json:"foregroundColor"`
+	LabelColor         string        `json:"labelColor"`
+	ExpirationDate     string        `json:"expirationDate,omitempty"`
+	Voided             bool          `json:"voided,omitempty"`
+	Barcodes           []appleCode   `json:"barcodes"`
+	Generic            appleFieldSet `json:"generic"`
+}
+
+type appleCode struct {
+	Format          string `json:"format"`
+	Message         string `json:"message"`
+	MessageEncoding string `json:"messageEncoding"`
+}
+
+type appleFieldSet struct {
+	PrimaryFields   []appleField `json:"primaryFields"`
+	SecondaryFields []appleField `json:"secondaryFields"`
+}
+
+type appleField struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// LoadApple reads the pass type ID certificate and its private key from a PEM file, and the
+// Apple Worldwide Developer Relations certificates from another, which is optional.
+func LoadApple(passTypeID, teamID, certificateFile, intermediatesFile string) (*Apple, error) {
+	a := &Apple{PassTypeID: passTypeID, TeamID: teamID}
+
+	b, err := ioutil.ReadFile(certificateFile)
+	if err != nil {
+		return nil, err
+	}
+	for block, rest := pem.Decode(b); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "CERTIFICATE":
+			if a.Certificate, err = x509.ParseCertificate(block.Bytes); err != nil {
+				return nil, err
+			}
+		case "RSA PRIVATE KEY":
+			if a.Key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+				return nil, err
+			}
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			var ok bool
+			if a.Key, ok = key.(*rsa.PrivateKey); !ok {
+				return nil, errPrivateKeyRequired
+			}
+		}
+	}
+	if a.Certificate == nil {
+		return nil, errCertificateRequired
+	}
+	if a.Key == nil {
+		return nil, errPrivateKeyRequired
+	}
+
+	if intermediatesFile == "" {
+		return a, nil
+	}
+	b, err = ioutil.ReadFile(intermediatesFile)
+	if err != nil {
+		return nil, err
+	}
+	for block, rest := pem.Decode(b); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		a.Intermediates = append(a.Intermediates, c)
+	}
+	return a, nil
+}
+
+// Pass returns the card as a signed Apple Wallet pass, i.e. a .pkpass file.
+func (a *Apple) Pass(p Pass) ([]byte, error) {
+	if a == nil || a.Certificate == nil || a.Key == nil {
+		return nil, ErrNotConfigured
+	}
+
+	background, foreground := p.background(), p.foreground()
+	pass := applePass{
+		FormatVersion:      1,
+		PassTypeIdentifier: a.PassTypeID,
+		TeamIdentifier:     a.TeamID,
+		SerialNumber:       p.SerialNumber,
+		OrganizationName:   p.Organization,
+		Description:        p.Organization + " " + p.Title + " membership",
+		LogoText:           p.Organization,
+		BackgroundColor:    rgb(background),
+		ForegroundColor:    rgb(foreground),
+		LabelColor:         rgb(foreground),
+		Voided:             p.Expired,
+		Barcodes:           []appleCode{{Format: "PKBarcodeFormatQR", Message: p.Barcode, MessageEncoding: "iso-8859-1"}},
+		Generic: appleFieldSet{
+			PrimaryFields: []appleField{{Key: "member", Label: "Member", Value: p.Member}},
+			SecondaryFields: []appleField{
+				{Key: "membership", Label: "Membership", Value: p.Title},
+				{Key: "expires", Label: "Expires", Value: p.expires()},
+			},
+		},
+	}
+	if !p.Expiration.IsZero() {
+		pass.ExpirationDate = p.Expiration.UTC().Format(time.RFC3339)
+	}
+
+	files := map[string][]byte{}
+	var err error
+	if files["pass.json"], err = json.Marshal(pass); err != nil {
+		return nil, err
+	}
+	// Passes must have an icon, which is shown on the lock screen and in notifications.
+	for name, size := range map[string]int{"icon.png": 29, "icon@2x.png": 58, "icon@3x.png": 87} {
+		if files[name], err = icon(background, size); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest := map[string]string{}
+	for name, b := range files {
+		sum := sha1.Sum(b)
+		manifest[name] = hex.EncodeToString(sum[:])
+	}
+	if files["manifest.json"], err = json.Marshal(manifest); err != nil {
+		return nil, err
+	}
+	if files["signature"], err = signDetached(files["manifest.json"], a.Certificate, a.Key, a.Intermediates, time.Now()); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	z := zip.NewWriter(buf)
+	for name, b := range files {
+		w, err := z.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// icon returns a square png of the color.
+func icon(c color.Color, size int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.ZP, draw.Src)
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
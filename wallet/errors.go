@@ -0,0 +1,13 @@
+package wallet
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNotConfigured is returned when a wallet has not been given its signing credentials.
+	ErrNotConfigured = errors.New("wallet not configured")
+
+	errCertificateRequired = errors.New("certificate required")
+	errPrivateKeyRequired  = errors.New("rsa private key required")
+)
@@ -0,0 +1,142 @@
+package wallet
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// googleSaveURL is where Google Wallet objects that are signed in a JWT are saved by members.
+const googleSaveURL = "https://pay.google.com/gp/v/save/"
+
+var googleIDInvalid = regexp.MustCompile(`[^\w.-]`)
+
+// Google produces membership cards as Google Wallet generic objects. Objects are signed
+// with the key of a service account of the issuer so that members can save them; without
+// one, objects can still be produced but not saved.
+type Google struct {
+	IssuerID       string
+	ServiceAccount string
+	Key            *rsa.PrivateKey
+}
+
+// GoogleObject is a Google Wallet generic object.
+type GoogleObject struct {
+	ID                 string              `json:"id"`
+	ClassID            string              `json:"classId"`
+	State              string              `json:"state"`
+	CardTitle          googleLocalized     `json:"cardTitle"`
+	Header             googleLocalized     `json:"header"`
+	Subheader          googleLocalized     `json:"subheader"`
+	HexBackgroundColor string              `json:"hexBackgroundColor"`
+	Barcode            googleBarcode       `json:"barcode"`
+	TextModulesData    []googleTextModule  `json:"textModulesData"`
+	ValidTimeInterval  *googleTimeInterval `json:"validTimeInterval,omitempty"`
+}
+
+type googleLocalized struct {
+	DefaultValue googleTranslated `json:"defaultValue"`
+}
+
+type googleTranslated struct {
+	Language string `json:"language"`
+	Value    string `json:"value"`
+}
+
+type googleBarcode struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type googleTextModule struct {
+	ID     string `json:"id"`
+	Header string `json:"header"`
+	Body   string `json:"body"`
+}
+
+type googleTimeInterval struct {
+	End googleDateTime `json:"end"`
+}
+
+type googleDateTime struct {
+	Date string `json:"date"`
+}
+
+// LoadGoogle reads the service account that signs objects from its JSON key file, as it
+// is downloaded from the Google Cloud console.
+func LoadGoogle(issuerID, credentialsFile string) (*Google, error) {
+	b, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	credentials := struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+	}{}
+	if err := json.Unmarshal(b, &credentials); err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(credentials.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	return &Google{IssuerID: issuerID, ServiceAccount: credentials.ClientEmail, Key: key}, nil
+}
+
+// Object returns the card as a Google Wallet generic object.
+func (g *Google) Object(p Pass) GoogleObject {
+	issuer := ""
+	if g != nil {
+		issuer = g.IssuerID
+	}
+	locale := p.Locale
+	if locale == "" {
+		locale = "en-US"
+	}
+	localized := func(value string) googleLocalized {
+		return googleLocalized{googleTranslated{Language: locale, Value: value}}
+	}
+
+	o := GoogleObject{
+		ID:                 issuer + "." + googleIDInvalid.ReplaceAllString(p.SerialNumber, "_"),
+		ClassID:            issuer + ".membership",
+		State:              "ACTIVE",
+		CardTitle:          localized(p.Organization),
+		Header:             localized(p.Member),
+		Subheader:          localized(p.Title),
+		HexBackgroundColor: "#" + hexRGB(p.background()),
+		Barcode:            googleBarcode{Type: "QR_CODE", Value: p.Barcode},
+		TextModulesData:    []googleTextModule{{ID: "expires", Header: "Expires", Body: p.expires()}},
+	}
+	if p.Expired {
+		o.State = "EXPIRED"
+	}
+	if !p.Expiration.IsZero() {
+		o.ValidTimeInterval = &googleTimeInterval{End: googleDateTime{Date: p.Expiration.UTC().Format(time.RFC3339)}}
+	}
+	return o
+}
+
+// SaveURL returns a link that saves the card to a member's Google Wallet.
+func (g *Google) SaveURL(p Pass) (string, error) {
+	if g == nil || g.Key == nil {
+		return "", ErrNotConfigured
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":     g.ServiceAccount,
+		"aud":     "google",
+		"typ":     "savetowallet",
+		"iat":     time.Now().Unix(),
+		"origins": []string{},
+		"payload": map[string][]GoogleObject{"genericObjects": {g.Object(p)}},
+	})
+	signed, err := token.SignedString(g.Key)
+	if err != nil {
+		return "", err
+	}
+	return googleSaveURL + signed, nil
+}
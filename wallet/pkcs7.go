@@ -0,0 +1,157 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"sort"
+	"time"
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+
+	asn1Null = asn1.RawValue{Tag: asn1.TagNull}
+)
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber asn1.RawValue
+}
+
+type attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           algorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue
+	DigestEncryptionAlgorithm algorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      asn1.RawValue
+}
+
+type signedContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+// set returns the DER encoding of a SET OF the encoded elements, which are sorted as DER requires.
+func set(elements ...[]byte) asn1.RawValue {
+	sort.Slice(elements, func(i, j int) bool { return bytes.Compare(elements[i], elements[j]) < 0 })
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: bytes.Join(elements, nil)}
+}
+
+// tagged returns a context specific constructed value with the encoded elements.
+func tagged(tag int, elements ...[]byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: tag, IsCompound: true, Bytes: bytes.Join(elements, nil)}
+}
+
+func marshalAttribute(t asn1.ObjectIdentifier, value interface{}) ([]byte, error) {
+	v, err := asn1.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(attribute{Type: t, Value: set(v)})
+}
+
+// signDetached returns a DER encoded PKCS #7 signature of content that does not include the
+// content itself. The certificates that chain cert to its root should be included so that
+// the signature can be verified.
+func signDetached(content []byte, cert *x509.Certificate, key *rsa.PrivateKey, chain []*x509.Certificate, signingTime time.Time) ([]byte, error) {
+	digest := sha256.Sum256(content)
+
+	var attributes [][]byte
+	for _, a := range []struct {
+		t     asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{oidContentType, oidData},
+		{oidSigningTime, signingTime.UTC()},
+		{oidMessageDigest, digest[:]},
+	} {
+		b, err := marshalAttribute(a.t, a.value)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, b)
+	}
+
+	// The signature is of the authenticated attributes encoded as a SET OF instead of with
+	// their implicit tag.
+	signed := set(attributes...)
+	encoded, err := asn1.Marshal(signed)
+	if err != nil {
+		return nil, err
+	}
+	attributesDigest := sha256.Sum256(encoded)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, attributesDigest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := asn1.Marshal(cert.SerialNumber)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := asn1.Marshal(signerInfo{
+		Version:                   1,
+		IssuerAndSerialNumber:     issuerAndSerialNumber{Issuer: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: asn1.RawValue{FullBytes: serial}},
+		DigestAlgorithm:           algorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1Null},
+		AuthenticatedAttributes:   tagged(0, signed.Bytes),
+		DigestEncryptionAlgorithm: algorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1Null},
+		EncryptedDigest:           signature,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, err := asn1.Marshal(algorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1Null})
+	if err != nil {
+		return nil, err
+	}
+	certificates := [][]byte{cert.Raw}
+	for _, c := range chain {
+		certificates = append(certificates, c.Raw)
+	}
+	sd, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: set(algorithm),
+		ContentInfo:      contentInfo{ContentType: oidData},
+		Certificates:     tagged(0, certificates...),
+		SignerInfos:      set(signer),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(signedContentInfo{ContentType: oidSignedData, Content: tagged(0, sd)})
+}
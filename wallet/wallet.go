@@ -0,0 +1,73 @@
+// Package wallet produces membership cards for Apple Wallet and Google Wallet.
+package wallet
+
+import (
+	"fmt"
+	"image/color"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// defaultColor is the background color of passes whose community has not set a primary color.
+var defaultColor = color.RGBA{0x33, 0x33, 0x33, 0xff}
+
+// Pass is a membership card. Barcode is the message of the card's QR code, Color is a hex
+// color such as #1e90ff, and Locale is a language tag such as en-US. Passes without an
+// expiration never expire.
+type Pass struct {
+	SerialNumber string
+	Organization string
+	Title        string
+	Member       string
+	Expiration   time.Time
+	Expired      bool
+	Barcode      string
+	Color        string
+	Locale       string
+	Location     *time.Location
+}
+
+// background returns the pass's color, or the default color when it does not have a valid one.
+func (p Pass) background() color.RGBA {
+	if !hexColor.MatchString(p.Color) {
+		return defaultColor
+	}
+	hex := p.Color[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, _ := strconv.ParseUint(hex, 16, 32)
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+}
+
+// foreground returns black or white, whichever is easier to read on the pass's color.
+func (p Pass) foreground() color.RGBA {
+	c := p.background()
+	if 299*int(c.R)+587*int(c.G)+114*int(c.B) > 128000 {
+		return color.RGBA{0, 0, 0, 0xff}
+	}
+	return color.RGBA{0xff, 0xff, 0xff, 0xff}
+}
+
+// expires returns the pass's expiration as a date in its location, or "Never".
+func (p Pass) expires() string {
+	if p.Expiration.IsZero() {
+		return "Never"
+	}
+	loc := p.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return p.Expiration.In(loc).Format("January 2, 2006")
+}
+
+func rgb(c color.RGBA) string {
+	return fmt.Sprintf("rgb(%d, %d, %d)", c.R, c.G, c.B)
+}
+
+func hexRGB(c color.RGBA) string {
+	return fmt.Sprintf("%02x%02x%02x", c.R, c.G, c.B)
+}
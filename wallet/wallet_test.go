@@ -0,0 +1,213 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func newCertificate(t *testing.T) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+var testPass = Pass{
+	SerialNumber: "abc.def",
+	Organization: "Downtown",
+	Title:        "Gold",
+	Member:       "Jane Doe",
+	Expiration:   time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC),
+	Barcode:      "abc.def",
+	Color:        "#fff",
+	Locale:       "fr-FR",
+}
+
+func TestPassColors(t *testing.T) {
+	for _, c := range []struct {
+		color, background, foreground string
+	}{
+		{"#fff", "rgb(255, 255, 255)", "rgb(0, 0, 0)"},
+		{"#1E90FF", "rgb(30, 144, 255)", "rgb(255, 255, 255)"},
+		{"#000080", "rgb(0, 0, 128)", "rgb(255, 255, 255)"},
+		{"blue", "rgb(51, 51, 51)", "rgb(255, 255, 255)"},
+	} {
+		p := Pass{Color: c.color}
+		if b, f := rgb(p.background()), rgb(p.foreground()); b != c.background || f != c.foreground {
+			t.Errorf("%s: got %s on %s, want %s on %s", c.color, f, b, c.foreground, c.background)
+		}
+	}
+}
+
+func TestSignDetached(t *testing.T) {
+	cert, key := newCertificate(t)
+	content := []byte(`{"pass.json":"0"}`)
+	signature, err := signDetached(content, cert, key, []*x509.Certificate{cert}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outer := signedContentInfo{}
+	if _, err := asn1.Unmarshal(signature, &outer); err != nil {
+		t.Fatal(err)
+	}
+	if !outer.ContentType.Equal(oidSignedData) {
+		t.Fatalf("got content type %v", outer.ContentType)
+	}
+	sd := signedData{}
+	if _, err := asn1.Unmarshal(outer.Content.Bytes, &sd); err != nil {
+		t.Fatal(err)
+	}
+	certificates, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil || len(certificates) != 2 {
+		t.Fatalf("got %d certificates, %v", len(certificates), err)
+	}
+	si := signerInfo{}
+	if _, err := asn1.Unmarshal(sd.SignerInfos.Bytes, &si); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signature is of the authenticated attributes with a SET OF tag.
+	attributes := si.AuthenticatedAttributes.Bytes
+	encoded, _ := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: attributes})
+	digest := sha256.Sum256(encoded)
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], si.EncryptedDigest); err != nil {
+		t.Fatal(err)
+	}
+
+	contentDigest := sha256.Sum256(content)
+	found := false
+	for rest := attributes; len(rest) > 0; {
+		a := attribute{}
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &a); err != nil {
+			t.Fatal(err)
+		}
+		if a.Type.Equal(oidMessageDigest) {
+			var d []byte
+			if _, err := asn1.Unmarshal(a.Value.Bytes, &d); err != nil {
+				t.Fatal(err)
+			}
+			found = bytes.Equal(d, contentDigest[:])
+		}
+	}
+	if !found {
+		t.Error("message digest of the content is not signed")
+	}
+}
+
+func TestApplePass(t *testing.T) {
+	if _, err := (&Apple{}).Pass(testPass); err != ErrNotConfigured {
+		t.Fatalf("got %v, want %v", err, ErrNotConfigured)
+	}
+
+	cert, key := newCertificate(t)
+	a := &Apple{PassTypeID: "pass.test", TeamID: "TEAM", Certificate: cert, Key: key}
+	b, err := a.Pass(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = ioutil.ReadAll(r)
+		r.Close()
+	}
+
+	manifest := map[string]string{}
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	for name, b := range files {
+		if name == "manifest.json" || name == "signature" {
+			continue
+		}
+		sum := sha1.Sum(b)
+		if manifest[name] != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s: manifest hash does not match", name)
+		}
+	}
+	if len(manifest) != 4 || len(files["signature"]) == 0 {
+		t.Errorf("got %d manifest entries and %d byte signature", len(manifest), len(files["signature"]))
+	}
+
+	pass := applePass{}
+	if err := json.Unmarshal(files["pass.json"], &pass); err != nil {
+		t.Fatal(err)
+	}
+	if pass.PassTypeIdentifier != "pass.test" || pass.Barcodes[0].Message != "abc.def" || pass.ExpirationDate != "2018-01-02T03:04:05Z" || pass.BackgroundColor != "rgb(255, 255, 255)" {
+		t.Errorf("got %+v", pass)
+	}
+}
+
+func TestGoogleObject(t *testing.T) {
+	g := &Google{IssuerID: "338"}
+	o := g.Object(testPass)
+	if o.ID != "338.abc.def" || o.ClassID != "338.membership" || o.State != "ACTIVE" || o.Header.DefaultValue.Value != "Jane Doe" || o.Header.DefaultValue.Language != "fr-FR" || o.HexBackgroundColor != "#ffffff" {
+		t.Errorf("got %+v", o)
+	}
+	if o.ValidTimeInterval == nil || o.ValidTimeInterval.End.Date != "2018-01-02T03:04:05Z" {
+		t.Errorf("got valid time interval %+v", o.ValidTimeInterval)
+	}
+
+	expired := testPass
+	expired.Expired, expired.Expiration = true, time.Time{}
+	if o := g.Object(expired); o.State != "EXPIRED" || o.ValidTimeInterval != nil {
+		t.Errorf("got %+v", o)
+	}
+
+	if _, err := g.SaveURL(testPass); err != ErrNotConfigured {
+		t.Fatalf("got %v, want %v", err, ErrNotConfigured)
+	}
+	_, g.Key = newCertificate(t)
+	u, err := g.SaveURL(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(strings.TrimPrefix(u, googleSaveURL), func(*jwt.Token) (interface{}, error) { return &g.Key.PublicKey, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims := token.Claims.(jwt.MapClaims); claims["typ"] != "savetowallet" || claims["aud"] != "google" {
+		t.Errorf("got %v", claims)
+	}
+}